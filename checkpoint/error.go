@@ -0,0 +1,20 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/25 10:05
+ */
+
+package checkpoint
+
+import "errors"
+
+var (
+	InvalidTableErr       = errors.New("invalid table")
+	ChecksumErr           = errors.New("checksum mismatch")
+	CompressedErr         = errors.New("compressed blocks are not supported")
+	UnsupportedDtypeErr   = errors.New("unsupported dtype")
+	OverflowErr           = errors.New("int64 value overflows int32")
+	PartitionedErr        = errors.New("partitioned variables are not supported")
+	VariableNotFoundErr   = errors.New("variable not found")
+	DuplicatedNameErr     = errors.New("variables mapped to the same name")
+	VocabularyMismatchErr = errors.New("vocabulary size mismatch")
+)
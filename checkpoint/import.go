@@ -0,0 +1,179 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/25 14:30
+ */
+
+package checkpoint
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/yinyajun/go-serving/params"
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+const (
+	// BiasName is the tensor name of the bias of linear models.
+	BiasName = "bias_weights"
+	// OOVFeature is the feature of the oov bucket appended to vocabulary files,
+	// the default feature of the column, like the python exporter.
+	OOVFeature = "__oov__"
+)
+
+// Options of Import.
+type Options struct {
+	ModelName string
+	Version   uint64
+	// optional, imports the variables under the scope only, e.g. linear/linear_model
+	Scope string
+	// optional, maps variables to tensor names, overriding ColumnName
+	Mapping map[string]string
+	// optional, vocabulary files of fields, e.g. the asset files of a SavedModel
+	Vocabularies map[string]string
+	// optional, looks up the vocabulary files named by fields, <field>.txt
+	AssetsDir string
+}
+
+// Imported describes an imported variable.
+type Imported struct {
+	Variable   string
+	Name       string
+	Shape      tensor.Shape
+	Vocabulary string // file of the vocabulary, empty for identity vocabularies
+}
+
+// ColumnName maps a variable of tensorflow feature columns to the tensor name
+// looked up by column.EmbeddingColumn and LinearModelLayer, the field of the
+// categorical column:
+//
+//	input_layer/F1_embedding/embedding_weights -> F1
+//	linear/linear_model/F1/weights             -> F1
+//	linear/linear_model/F2_bucketized/weights  -> F2
+//	linear/linear_model/bias_weights           -> bias_weights
+func ColumnName(variable string) (string, bool) {
+	parts := strings.Split(variable, "/")
+	n := len(parts)
+	var name string
+	switch {
+	case parts[n-1] == BiasName:
+		return BiasName, true
+	case n >= 2 && parts[n-1] == "embedding_weights":
+		name = strings.TrimSuffix(parts[n-2], "_embedding")
+	case n >= 2 && parts[n-1] == "weights":
+		name = parts[n-2]
+	default:
+		return "", false // e.g. global_step and slots of optimizers
+	}
+	return strings.TrimSuffix(name, "_bucketized"), name != ""
+}
+
+// Import maps the variables of the checkpoint to a model file. Embedding
+// tables get the vocabularies of their fields, or identity vocabularies of
+// their rows if there are no vocabulary files.
+func Import(r *Reader, opts Options) (*params.Writer, []Imported, error) {
+	w := params.NewWriter(opts.ModelName, opts.Version)
+	var res []Imported
+	variables := make(map[string]string) // of names
+	for _, variable := range r.Names() {
+		name, ok := opts.Mapping[variable]
+		if !ok {
+			if opts.Scope != "" && !strings.HasPrefix(variable, opts.Scope+"/") {
+				continue
+			}
+			if name, ok = ColumnName(variable); !ok {
+				continue
+			}
+		}
+		if other, ok := variables[name]; ok {
+			return nil, nil, fmt.Errorf("%w: %s and %s to %s", DuplicatedNameErr, other, variable, name)
+		}
+		variables[name] = variable
+
+		t, err := r.Tensor(variable)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := w.AddDense(name, t); err != nil {
+			return nil, nil, err
+		}
+		imported := Imported{Variable: variable, Name: name, Shape: t.Shape()}
+		if name != BiasName && t.Dims() == 2 && t.Dtype() == tensor.Float32 {
+			field, file, err := opts.vocabulary(name, t.Shape()[0], t.Shape()[1])
+			if err != nil {
+				return nil, nil, err
+			}
+			w.AddField(field)
+			imported.Vocabulary = file
+		}
+		res = append(res, imported)
+	}
+	for name := range opts.Mapping {
+		if _, ok := r.entries[name]; !ok {
+			return nil, nil, fmt.Errorf("%w: %s", VariableNotFoundErr, name)
+		}
+	}
+	return w, res, nil
+}
+
+// vocabulary returns the vocabulary of the embedding table of field and its file.
+func (o *Options) vocabulary(field string, rows, dim int) (*proto.Field, string, error) {
+	file, ok := o.Vocabularies[field]
+	if !ok && o.AssetsDir != "" {
+		file = filepath.Join(o.AssetsDir, field+params.VocabExt)
+		if _, err := os.Stat(file); err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				return nil, "", err
+			}
+			file = ""
+		}
+	}
+	f := &proto.Field{Name: field, Dim: int32(dim), Records: make(map[string]int64, rows)}
+	if file == "" {
+		for i := 0; i < rows; i++ {
+			f.Records[strconv.Itoa(i)] = int64(i)
+		}
+		return f, "", nil
+	}
+	features, err := ReadVocabulary(file)
+	if err != nil {
+		return nil, "", err
+	}
+	switch {
+	case len(features) >= rows: // truncated by vocabulary_size
+		features = features[:rows]
+	case len(features)+1 == rows: // one oov bucket
+		features = append(features, OOVFeature)
+	default:
+		return nil, "", fmt.Errorf("%w: %s has %d features, but %s has %d rows",
+			VocabularyMismatchErr, file, len(features), field, rows)
+	}
+	for i, feat := range features {
+		if _, ok := f.Records[feat]; !ok {
+			f.Records[feat] = int64(i)
+		}
+	}
+	return f, file, nil
+}
+
+// ReadVocabulary reads a vocabulary file of one feature per line, like
+// tensorflow's categorical_column_with_vocabulary_file.
+func ReadVocabulary(file string) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var features []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		features = append(features, strings.TrimRight(scanner.Text(), "\r"))
+	}
+	return features, scanner.Err()
+}
@@ -0,0 +1,76 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 16:35
+ */
+
+package checkpoint
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yinyajun/go-serving/params"
+)
+
+func TestColumnName(t *testing.T) {
+	tests := []struct {
+		variable string
+		name     string
+		ok       bool
+	}{
+		{"input_layer/F1_embedding/embedding_weights", "F1", true},
+		{"dnn/input_from_feature_columns/input_layer/F2_bucketized_embedding/embedding_weights", "F2", true},
+		{"linear/linear_model/F1/weights", "F1", true},
+		{"linear/linear_model/F2_bucketized/weights", "F2", true},
+		{"linear/linear_model/bias_weights", BiasName, true},
+		{"bias_weights", BiasName, true},
+		{"weights", "", false},
+		{"global_step", "", false},
+		{"linear/linear_model/F1/weights/part_ftrl/Ftrl", "", false},
+	}
+	for _, test := range tests {
+		name, ok := ColumnName(test.variable)
+		if name != test.name || ok != test.ok {
+			t.Errorf("%s: got %q %v, want %q %v", test.variable, name, ok, test.name, test.ok)
+		}
+	}
+}
+
+func TestImport(t *testing.T) {
+	r := open(t, fixture)
+	w, imported, err := Import(r, Options{ModelName: "test", Version: 1000, Scope: "linear/linear_model"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, i := range imported {
+		names = append(names, i.Name)
+	}
+	if want := []string{"F1", BiasName}; !reflect.DeepEqual(names, want) {
+		t.Fatalf("imported: got %v, want %v", names, want)
+	}
+
+	file := filepath.Join(t.TempDir(), "1000.pb")
+	if err := w.WriteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	m := params.New(file)
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+	if got := m.GetTensor("F1").Data(); !reflect.DeepEqual(got, []float32{0.1, 0.2, 0.3}) {
+		t.Errorf("F1: got %v", got)
+	}
+	if got := m.IndexLookup("F1", "0", "2"); !reflect.DeepEqual(got, params.Index{2}) {
+		t.Errorf("F1 vocabulary: got %v", got)
+	}
+
+	// the overflowed variable fails the import once it's mapped
+	_, _, err = Import(r, Options{Mapping: map[string]string{"int64_overflow": "steps"}})
+	if !errors.Is(err, OverflowErr) {
+		t.Errorf("got %v, want %v", err, OverflowErr)
+	}
+}
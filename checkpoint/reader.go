@@ -0,0 +1,187 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/25 11:10
+ */
+
+// Package checkpoint reads tensorflow checkpoints, the tensor bundle of a
+// prefix.index file and its prefix.data-* shards, and imports them as
+// go-serving model files.
+package checkpoint
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+// tensorflow.DataType
+const (
+	dtFloat  = 1
+	dtDouble = 2
+	dtInt32  = 3
+	dtInt64  = 9
+)
+
+// Reader reads the variables of a checkpoint.
+type Reader struct {
+	prefix  string
+	entries map[string]*proto.BundleEntryProto
+	shards  []*os.File
+}
+
+// Open opens the checkpoint of prefix, e.g. model.ckpt-1000 of
+// model.ckpt-1000.index and model.ckpt-1000.data-00000-of-00001.
+func Open(prefix string) (*Reader, error) {
+	f, err := os.Open(prefix + ".index")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reader{prefix: prefix, entries: make(map[string]*proto.BundleEntryProto)}
+	header := new(proto.BundleHeaderProto)
+	err = readTable(f, stat.Size(), func(key, value []byte) error {
+		if len(key) == 0 {
+			return header.Unmarshal(value)
+		}
+		entry := new(proto.BundleEntryProto)
+		if err := entry.Unmarshal(value); err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		r.entries[string(key)] = entry
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s.index: %w", prefix, err)
+	}
+	if header.Endianness != proto.BundleHeaderProto_LITTLE {
+		return nil, fmt.Errorf("%s.index: big endian checkpoints are not supported", prefix)
+	}
+	for i := 0; i < int(header.NumShards); i++ {
+		shard, err := os.Open(fmt.Sprintf("%s.data-%05d-of-%05d", prefix, i, header.NumShards))
+		if err != nil {
+			r.Close()
+			return nil, err
+		}
+		r.shards = append(r.shards, shard)
+	}
+	return r, nil
+}
+
+// Names returns the sorted names of the variables.
+func (r *Reader) Names() []string {
+	names := make([]string, 0, len(r.entries))
+	for k := range r.entries {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Shape returns the shape of a variable.
+func (r *Reader) Shape(name string) (tensor.Shape, bool) {
+	entry, ok := r.entries[name]
+	if !ok {
+		return nil, false
+	}
+	shape := make(tensor.Shape, 0, len(entry.Shape.GetDim()))
+	for _, d := range entry.Shape.GetDim() {
+		shape = append(shape, int(d.Size_))
+	}
+	return shape, true
+}
+
+// Tensor reads a variable, DT_DOUBLE is converted to float32 and DT_INT64 to
+// int32, it returns OverflowErr if an int64 value doesn't fit.
+func (r *Reader) Tensor(name string) (*tensor.Dense, error) {
+	entry, ok := r.entries[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", VariableNotFoundErr, name)
+	}
+	if len(entry.Slices) > 0 {
+		return nil, fmt.Errorf("%w: %s", PartitionedErr, name)
+	}
+	if int(entry.ShardId) >= len(r.shards) {
+		return nil, fmt.Errorf("%w: %s in shard %d", VariableNotFoundErr, name, entry.ShardId)
+	}
+	buf := make([]byte, entry.Size_)
+	if _, err := r.shards[entry.ShardId].ReadAt(buf, entry.Offset); err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	if crc32.Checksum(buf, castagnoli) != unmask(entry.Crc32C) {
+		return nil, fmt.Errorf("%w: %s", ChecksumErr, name)
+	}
+	shape, _ := r.Shape(name)
+	if len(shape) == 0 {
+		shape = tensor.Shape{1} // scalar
+	}
+	n := shape.TotalSize()
+
+	var back tensor.ConsOpt
+	switch entry.Dtype {
+	case dtFloat:
+		if len(buf) != 4*n {
+			return nil, fmt.Errorf("%w: %s", InvalidTableErr, name)
+		}
+		values := make([]float32, n)
+		for i := range values {
+			values[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[4*i:]))
+		}
+		back = tensor.WithBacking(values)
+	case dtDouble:
+		if len(buf) != 8*n {
+			return nil, fmt.Errorf("%w: %s", InvalidTableErr, name)
+		}
+		values := make([]float32, n)
+		for i := range values {
+			values[i] = float32(math.Float64frombits(binary.LittleEndian.Uint64(buf[8*i:])))
+		}
+		back = tensor.WithBacking(values)
+	case dtInt32:
+		if len(buf) != 4*n {
+			return nil, fmt.Errorf("%w: %s", InvalidTableErr, name)
+		}
+		values := make([]int32, n)
+		for i := range values {
+			values[i] = int32(binary.LittleEndian.Uint32(buf[4*i:]))
+		}
+		back = tensor.WithBacking(values)
+	case dtInt64:
+		if len(buf) != 8*n {
+			return nil, fmt.Errorf("%w: %s", InvalidTableErr, name)
+		}
+		values := make([]int32, n)
+		for i := range values {
+			v := int64(binary.LittleEndian.Uint64(buf[8*i:]))
+			if v < math.MinInt32 || v > math.MaxInt32 {
+				return nil, fmt.Errorf("%w: %s[%d] = %d", OverflowErr, name, i, v)
+			}
+			values[i] = int32(v)
+		}
+		back = tensor.WithBacking(values)
+	default:
+		return nil, fmt.Errorf("%w: %s of tensorflow dtype %d", UnsupportedDtypeErr, name, entry.Dtype)
+	}
+	return tensor.New(back, tensor.WithShape(shape...)), nil
+}
+
+func (r *Reader) Close() error {
+	var err error
+	for _, f := range r.shards {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	r.shards = nil
+	return err
+}
@@ -0,0 +1,133 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 15:40
+ */
+
+package checkpoint
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gorgonia.org/tensor"
+)
+
+// testdata/model.ckpt-1000 is written by testdata/gen.go.
+const fixture = "testdata/model.ckpt-1000"
+
+func open(t *testing.T, prefix string) *Reader {
+	t.Helper()
+	r, err := Open(prefix)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// copyFixture copies the fixture into a temp dir, and returns its prefix.
+func copyFixture(t *testing.T) string {
+	t.Helper()
+	prefix := filepath.Join(t.TempDir(), filepath.Base(fixture))
+	for _, ext := range []string{".index", ".data-00000-of-00001"} {
+		buf, err := os.ReadFile(fixture + ext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(prefix+ext, buf, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return prefix
+}
+
+// corrupt flips the byte at offset of file.
+func corrupt(t *testing.T, file string, offset int64) {
+	t.Helper()
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset < 0 {
+		offset += int64(len(buf))
+	}
+	buf[offset] ^= 0xff
+	if err := os.WriteFile(file, buf, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReaderNames(t *testing.T) {
+	r := open(t, fixture)
+	want := []string{
+		"dnn/input_from_feature_columns/input_layer/F1_embedding/embedding_weights",
+		"dnn/input_from_feature_columns/input_layer/F2_bucketized_embedding/embedding_weights",
+		"global_step",
+		"int32_counts",
+		"int64_overflow",
+		"linear/linear_model/F1/weights",
+		"linear/linear_model/F1/weights/part_ftrl/Ftrl",
+		"linear/linear_model/bias_weights",
+	}
+	if got := r.Names(); !reflect.DeepEqual(got, want) {
+		t.Errorf("names: got %v, want %v", got, want)
+	}
+}
+
+func TestReaderTensor(t *testing.T) {
+	r := open(t, fixture)
+	tests := []struct {
+		name  string
+		shape tensor.Shape
+		data  any
+	}{
+		{"global_step", tensor.Shape{1}, []int32{1000}},
+		{"int32_counts", tensor.Shape{2}, []int32{7, -7}},
+		{"linear/linear_model/F1/weights", tensor.Shape{3, 1}, []float32{0.1, 0.2, 0.3}},
+		{"linear/linear_model/bias_weights", tensor.Shape{1}, []float32{0.5}},
+		{"dnn/input_from_feature_columns/input_layer/F1_embedding/embedding_weights", tensor.Shape{4, 3},
+			[]float32{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12}},
+		{"dnn/input_from_feature_columns/input_layer/F2_bucketized_embedding/embedding_weights", tensor.Shape{2, 2},
+			[]float32{0.5, 1.5, 2.5, 3.5}}, // DT_DOUBLE
+	}
+	for _, test := range tests {
+		got, err := r.Tensor(test.name)
+		if err != nil {
+			t.Errorf("%s: %v", test.name, err)
+			continue
+		}
+		if !got.Shape().Eq(test.shape) || !reflect.DeepEqual(got.Data(), test.data) {
+			t.Errorf("%s: got %v %v, want %v %v", test.name, got.Shape(), got.Data(), test.shape, test.data)
+		}
+	}
+	if shape, ok := r.Shape("global_step"); !ok || len(shape) != 0 {
+		t.Errorf("shape of scalar: got %v %v", shape, ok)
+	}
+}
+
+func TestReaderTensorErrors(t *testing.T) {
+	prefix := copyFixture(t)
+	r := open(t, prefix)
+	// the data of F1/weights is corrupted, the other variables are intact
+	corrupt(t, prefix+".data-00000-of-00001", r.entries["linear/linear_model/F1/weights"].Offset)
+
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"int64_overflow", OverflowErr},
+		{"linear/linear_model/F1/weights", ChecksumErr},
+		{"missing", VariableNotFoundErr},
+	}
+	for _, test := range tests {
+		if _, err := r.Tensor(test.name); !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+	if _, err := r.Tensor("linear/linear_model/bias_weights"); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,133 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/25 10:20
+ */
+
+package checkpoint
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+// the leveldb table format of tensorflow's checkpoint index files:
+// data blocks | metaindex block | index block | footer(48)
+const (
+	footerSize    = 48
+	trailerSize   = 5 // compression type(1) | masked crc32c(4)
+	tableMagic    = 0xdb4775248b80fb57
+	noCompression = 0
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// unmask reverts the crc masking of leveldb and tensorflow.
+func unmask(crc uint32) uint32 {
+	rot := crc - 0xa282ead8
+	return rot>>17 | rot<<15
+}
+
+type blockHandle struct {
+	offset, size uint64
+}
+
+func decodeHandle(buf []byte) (blockHandle, int, error) {
+	offset, n := binary.Uvarint(buf)
+	if n <= 0 {
+		return blockHandle{}, 0, InvalidTableErr
+	}
+	size, m := binary.Uvarint(buf[n:])
+	if m <= 0 {
+		return blockHandle{}, 0, InvalidTableErr
+	}
+	return blockHandle{offset: offset, size: size}, n + m, nil
+}
+
+func readBlock(r io.ReaderAt, h blockHandle) ([]byte, error) {
+	buf := make([]byte, h.size+trailerSize)
+	if _, err := r.ReadAt(buf, int64(h.offset)); err != nil {
+		return nil, fmt.Errorf("%w: block at %d: %v", InvalidTableErr, h.offset, err)
+	}
+	data, trailer := buf[:h.size], buf[h.size:]
+	if crc32.Checksum(buf[:h.size+1], castagnoli) != unmask(binary.LittleEndian.Uint32(trailer[1:])) {
+		return nil, fmt.Errorf("%w: block at %d", ChecksumErr, h.offset)
+	}
+	if trailer[0] != noCompression {
+		return nil, CompressedErr
+	}
+	return data, nil
+}
+
+// iterateBlock calls f with the entries of a block in order.
+func iterateBlock(block []byte, f func(key, value []byte) error) error {
+	if len(block) < 4 {
+		return InvalidTableErr
+	}
+	restarts := int(binary.LittleEndian.Uint32(block[len(block)-4:]))
+	end := len(block) - 4*(restarts+1)
+	if end < 0 {
+		return InvalidTableErr
+	}
+	var key []byte
+	for off := 0; off < end; {
+		var lens [3]uint64
+		for i := range lens {
+			v, n := binary.Uvarint(block[off:end])
+			if n <= 0 {
+				return InvalidTableErr
+			}
+			lens[i] = v
+			off += n
+		}
+		shared, unshared, valueLen := int(lens[0]), int(lens[1]), int(lens[2])
+		if shared > len(key) || off+unshared+valueLen > end {
+			return InvalidTableErr
+		}
+		key = append(key[:shared], block[off:off+unshared]...)
+		off += unshared
+		if err := f(key, block[off:off+valueLen]); err != nil {
+			return err
+		}
+		off += valueLen
+	}
+	return nil
+}
+
+// readTable calls f with all entries of the table in key order.
+func readTable(r io.ReaderAt, size int64, f func(key, value []byte) error) error {
+	if size < footerSize {
+		return InvalidTableErr
+	}
+	footer := make([]byte, footerSize)
+	if _, err := r.ReadAt(footer, size-footerSize); err != nil {
+		return err
+	}
+	if binary.LittleEndian.Uint64(footer[footerSize-8:]) != tableMagic {
+		return fmt.Errorf("%w: bad magic number", InvalidTableErr)
+	}
+	_, n, err := decodeHandle(footer) // metaindex
+	if err != nil {
+		return err
+	}
+	indexHandle, _, err := decodeHandle(footer[n:])
+	if err != nil {
+		return err
+	}
+	index, err := readBlock(r, indexHandle)
+	if err != nil {
+		return err
+	}
+	return iterateBlock(index, func(_, value []byte) error {
+		h, _, err := decodeHandle(value)
+		if err != nil {
+			return err
+		}
+		block, err := readBlock(r, h)
+		if err != nil {
+			return err
+		}
+		return iterateBlock(block, f)
+	})
+}
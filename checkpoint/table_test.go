@@ -0,0 +1,93 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 16:10
+ */
+
+package checkpoint
+
+import (
+	"encoding/binary"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+)
+
+// block encodes entries of (shared, unshared key, value) with restarts.
+func block(restarts []uint32, entries ...[3]string) []byte {
+	var b []byte
+	for _, e := range entries {
+		b = binary.AppendUvarint(b, uint64(len(e[0])))
+		b = binary.AppendUvarint(b, uint64(len(e[1])))
+		b = binary.AppendUvarint(b, uint64(len(e[2])))
+		b = append(b, e[1]...)
+		b = append(b, e[2]...)
+	}
+	for _, r := range restarts {
+		b = binary.LittleEndian.AppendUint32(b, r)
+	}
+	return binary.LittleEndian.AppendUint32(b, uint32(len(restarts)))
+}
+
+func TestIterateBlock(t *testing.T) {
+	// the shared prefix is only a length, its content is ignored
+	b := block([]uint32{0}, [3]string{"", "apple", "1"}, [3]string{"app", "ly", "2"}, [3]string{"xx", "ricot", "3"})
+	var got [][2]string
+	err := iterateBlock(b, func(key, value []byte) error {
+		got = append(got, [2]string{string(key), string(value)})
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := [][2]string{{"apple", "1"}, {"apply", "2"}, {"apricot", "3"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	overflow := block([]uint32{0}, [3]string{"", "a", "1"})
+	overflow[2] = 10 // value length beyond the entries
+	invalid := map[string][]byte{
+		"empty":          nil,
+		"restarts":       binary.LittleEndian.AppendUint32(nil, 2),
+		"shared":         block([]uint32{0}, [3]string{"abc", "d", "1"}),
+		"value overflow": overflow,
+	}
+	for name, b := range invalid {
+		if err := iterateBlock(b, func(_, _ []byte) error { return nil }); !errors.Is(err, InvalidTableErr) {
+			t.Errorf("%s: got %v, want %v", name, err, InvalidTableErr)
+		}
+	}
+}
+
+func TestReadTableErrors(t *testing.T) {
+	index, err := os.ReadFile(fixture + ".index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		offset int64 // of the flipped byte, from the end if negative
+		size   int64 // truncated to if positive
+		err    error
+	}{
+		{"data block", 5, 0, ChecksumErr},
+		{"index block", -footerSize - 2, 0, ChecksumErr},
+		{"magic", -1, 0, InvalidTableErr},
+		{"truncated footer", 0, footerSize - 1, InvalidTableErr},
+		{"truncated", 0, int64(len(index)) - 1, InvalidTableErr},
+	}
+	for _, test := range tests {
+		prefix := copyFixture(t)
+		if test.size > 0 {
+			if err := os.Truncate(prefix+".index", test.size); err != nil {
+				t.Fatal(err)
+			}
+		} else {
+			corrupt(t, prefix+".index", test.offset)
+		}
+		if _, err := Open(prefix); !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+//go:build ignore
+
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 15:20
+ */
+
+// Writes the checkpoint read by the tests of package checkpoint, the tensor
+// bundle format of tensorflow's BundleWriter with small blocks and restart
+// intervals, so that the index spans several blocks.
+//
+//	cd checkpoint && go run testdata/gen.go testdata/model.ckpt-1000
+package main
+
+import (
+	"encoding/binary"
+	"hash/crc32"
+	"math"
+	"os"
+	"sort"
+
+	"github.com/yinyajun/go-serving/proto"
+)
+
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+func mask(crc uint32) uint32 { return (crc>>15 | crc<<17) + 0xa282ead8 }
+
+type variable struct {
+	name  string
+	dtype int32
+	shape []int64
+	data  []byte
+}
+
+func i64(vals ...int64) []byte {
+	var b []byte
+	for _, v := range vals {
+		b = binary.LittleEndian.AppendUint64(b, uint64(v))
+	}
+	return b
+}
+
+func f32(vals ...float32) []byte {
+	b := make([]byte, 4*len(vals))
+	for i, v := range vals {
+		binary.LittleEndian.PutUint32(b[4*i:], math.Float32bits(v))
+	}
+	return b
+}
+
+type blockBuilder struct {
+	buf      []byte
+	restarts []uint32
+	last     []byte
+	count    int
+}
+
+func (b *blockBuilder) add(key, value []byte) {
+	shared := 0
+	if b.count%2 == 0 { // restart interval 2 to exercise restarts
+		b.restarts = append(b.restarts, uint32(len(b.buf)))
+	} else {
+		for shared < len(key) && shared < len(b.last) && key[shared] == b.last[shared] {
+			shared++
+		}
+	}
+	b.buf = binary.AppendUvarint(b.buf, uint64(shared))
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(key)-shared))
+	b.buf = binary.AppendUvarint(b.buf, uint64(len(value)))
+	b.buf = append(b.buf, key[shared:]...)
+	b.buf = append(b.buf, value...)
+	b.last = append([]byte(nil), key...)
+	b.count++
+}
+
+func (b *blockBuilder) finish() []byte {
+	if len(b.restarts) == 0 {
+		b.restarts = []uint32{0}
+	}
+	for _, r := range b.restarts {
+		b.buf = binary.LittleEndian.AppendUint32(b.buf, r)
+	}
+	return binary.LittleEndian.AppendUint32(b.buf, uint32(len(b.restarts)))
+}
+
+func main() {
+	prefix := os.Args[1]
+	vars := []variable{
+		{"global_step", 9, nil, i64(1000)},
+		{"int32_counts", 3, []int64{2}, binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, 7), 0xfffffff9)},
+		{"int64_overflow", 9, []int64{2}, i64(1, 1<<40)},
+		{"linear/linear_model/F1/weights", 1, []int64{3, 1}, f32(0.1, 0.2, 0.3)},
+		{"linear/linear_model/F1/weights/part_ftrl/Ftrl", 1, []int64{3, 1}, f32(9, 9, 9)},
+		{"linear/linear_model/bias_weights", 1, []int64{1}, f32(0.5)},
+		{"dnn/input_from_feature_columns/input_layer/F1_embedding/embedding_weights", 1, []int64{4, 3},
+			f32(1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12)},
+		{"dnn/input_from_feature_columns/input_layer/F2_bucketized_embedding/embedding_weights", 2, []int64{2, 2},
+			func() []byte {
+				var b []byte
+				for _, v := range []float64{0.5, 1.5, 2.5, 3.5} {
+					b = binary.LittleEndian.AppendUint64(b, math.Float64bits(v))
+				}
+				return b
+			}()},
+	}
+	var data []byte
+	entries := map[string][]byte{}
+	for _, v := range vars {
+		e := &proto.BundleEntryProto{Dtype: v.dtype, Offset: int64(len(data)), Size_: int64(len(v.data)),
+			Crc32C: mask(crc32.Checksum(v.data, castagnoli)), Shape: &proto.TensorShapeProto{}}
+		for _, d := range v.shape {
+			e.Shape.Dim = append(e.Shape.Dim, &proto.TensorShapeProto_Dim{Size_: d})
+		}
+		data = append(data, v.data...)
+		entries[v.name], _ = e.Marshal()
+	}
+	h := &proto.BundleHeaderProto{NumShards: 1}
+	entries[""], _ = h.Marshal()
+	keys := make([]string, 0, len(entries))
+	for k := range entries {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var table []byte
+	writeBlock := func(block []byte) []byte {
+		handle := binary.AppendUvarint(nil, uint64(len(table)))
+		handle = binary.AppendUvarint(handle, uint64(len(block)))
+		table = append(table, block...)
+		trailer := []byte{0}
+		crc := crc32.Checksum(append(append([]byte(nil), block...), 0), castagnoli)
+		trailer = binary.LittleEndian.AppendUint32(trailer, mask(crc))
+		table = append(table, trailer...)
+		return handle
+	}
+	index := &blockBuilder{}
+	b := &blockBuilder{}
+	for i, k := range keys {
+		b.add([]byte(k), entries[k])
+		if b.count == 3 || i == len(keys)-1 {
+			index.add([]byte(k), writeBlock(b.finish()))
+			b = &blockBuilder{}
+		}
+	}
+	meta := writeBlock((&blockBuilder{}).finish())
+	idx := writeBlock(index.finish())
+	footer := append(append([]byte(nil), meta...), idx...)
+	footer = append(footer, make([]byte, 40-len(footer))...)
+	footer = binary.LittleEndian.AppendUint64(footer, 0xdb4775248b80fb57)
+	table = append(table, footer...)
+	if err := os.WriteFile(prefix+".index", table, 0644); err != nil {
+		panic(err)
+	}
+	if err := os.WriteFile(prefix+".data-00000-of-00001", data, 0644); err != nil {
+		panic(err)
+	}
+}
@@ -0,0 +1,45 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/6 15:02
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/yinyajun/go-serving/params"
+)
+
+// convert rewrites a model file in the current format version, optionally
+// renaming the model or changing its version.
+func convert(args []string) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	name := fs.String("name", "", "new model name")
+	version := fs.Uint64("version", 0, "new model version")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("convert: expected input and output files")
+	}
+	m, err := load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	w, err := params.FromParams(m)
+	if err != nil {
+		return err
+	}
+	if *name != "" {
+		w.SetModelName(*name)
+	}
+	if *version != 0 {
+		w.SetVersion(*version)
+	}
+	if err := w.WriteFile(fs.Arg(1)); err != nil {
+		return err
+	}
+	fmt.Printf("converted %s -> %s\n", fs.Arg(0), fs.Arg(1))
+	return nil
+}
@@ -0,0 +1,193 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/24 17:10
+ */
+
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/yinyajun/go-serving/params"
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+// delta writes the changed rows and new vocabulary records of NEW as a delta
+// file of BASE, other changed tensors are written as a whole.
+func delta(args []string) error {
+	fs := flag.NewFlagSet("delta", flag.ExitOnError)
+	tol := fs.Float64("tol", 0, "tolerance of float values")
+	fs.Parse(args)
+	if fs.NArg() != 3 {
+		return errors.New("delta: expected BASE NEW OUT")
+	}
+	base, err := load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	next, err := load(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+	if base.IsDelta() || next.IsDelta() {
+		return errors.New("delta: expected full model files")
+	}
+	if next.ModelName() != base.ModelName() || next.Version() <= base.Version() {
+		return fmt.Errorf("delta: expected a newer version of %s %d, but got %s %d",
+			base.ModelName(), base.Version(), next.ModelName(), next.Version())
+	}
+
+	w := params.NewDeltaWriter(next.ModelName(), next.Version(), base.Version())
+	for _, name := range next.TensorNames() {
+		if err := deltaTensor(w, name, base, next, *tol); err != nil {
+			return err
+		}
+	}
+	for _, name := range next.FieldNames() {
+		if err := deltaField(w, name, base, next); err != nil {
+			return err
+		}
+	}
+	return w.WriteFile(fs.Arg(2))
+}
+
+func deltaTensor(w *params.Writer, name string, base, next *params.Params, tol float64) error {
+	if qn, ok := next.Quantized(name); ok {
+		return deltaQuantized(w, name, base, next, qn)
+	}
+	tb, _ := base.GetTensor(name).(*tensor.Dense)
+	tn := next.GetTensor(name).(*tensor.Dense)
+	if next.StorageType(name) != proto.DataType_DT_FLOAT {
+		if tb == nil || !reflect.DeepEqual(tb.Data(), tn.Data()) || next.StorageType(name) != base.StorageType(name) {
+			fmt.Printf("~ tensor %s\n", name)
+			return w.AddDense(name, tn)
+		}
+		return nil
+	}
+	// a table grows by the records added to its vocabulary only
+	if tb == nil || base.StorageType(name) != proto.DataType_DT_FLOAT || tb.Dims() != 2 || tn.Dims() != 2 ||
+		tb.Shape()[1] != tn.Shape()[1] || tb.Shape()[0] > tn.Shape()[0] ||
+		tn.Shape()[0] > tb.Shape()[0]+addedRecords(name, base, next) {
+		if tb == nil || !reflect.DeepEqual(tb.Data(), tn.Data()) {
+			fmt.Printf("~ tensor %s\n", name)
+			return w.AddDense(name, tn)
+		}
+		return nil
+	}
+	// rows of the embedding table
+	vb, vn := tb.Data().([]float32), tn.Data().([]float32)
+	rows, dim := tb.Shape()[0], tb.Shape()[1]
+	var (
+		ids     []int
+		changed []float32
+	)
+	for i := 0; i < tn.Shape()[0]; i++ {
+		row := vn[i*dim : (i+1)*dim]
+		if i < rows && !rowChanged(vb[i*dim:(i+1)*dim], row, tol) {
+			continue
+		}
+		ids = append(ids, i)
+		changed = append(changed, row...)
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	fmt.Printf("~ tensor %s %d/%d rows\n", name, len(ids), tn.Shape()[0])
+	return w.AddRows(name, ids, tensor.New(tensor.WithBacking(changed), tensor.WithShape(len(ids), dim)))
+}
+
+// deltaQuantized writes the changed rows of a quantized table as they are
+// stored, rows are compared exactly.
+func deltaQuantized(w *params.Writer, name string, base, next *params.Params, qn *proto.Tensor) error {
+	qb, ok := base.Quantized(name)
+	if !ok || qb.Dtype != qn.Dtype || qb.TensorShape[1] != qn.TensorShape[1] || qb.TensorShape[0] > qn.TensorShape[0] ||
+		int(qn.TensorShape[0]) > int(qb.TensorShape[0])+addedRecords(name, base, next) {
+		fmt.Printf("~ tensor %s\n", name)
+		w.AddTensor(name, qn)
+		return nil
+	}
+	rows, dim := int(qn.TensorShape[0]), int(qn.TensorShape[1])
+	size := dim
+	if qn.Dtype == proto.DataType_DT_HALF {
+		size = 2 * dim
+	}
+	var ids []int
+	changed := &proto.Tensor{Dtype: qn.Dtype}
+	for i := 0; i < rows; i++ {
+		row := qn.ByteVal[i*size : (i+1)*size]
+		if i < int(qb.TensorShape[0]) && bytes.Equal(qb.ByteVal[i*size:(i+1)*size], row) &&
+			(qn.Scale == nil || qb.Scale[i] == qn.Scale[i]) {
+			continue
+		}
+		ids = append(ids, i)
+		changed.ByteVal = append(changed.ByteVal, row...)
+		if qn.Scale != nil {
+			changed.Scale = append(changed.Scale, qn.Scale[i])
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	changed.TensorShape = []int32{int32(len(ids)), int32(dim)}
+	fmt.Printf("~ tensor %s %d/%d rows\n", name, len(ids), rows)
+	return w.AddQuantizedRows(name, ids, changed)
+}
+
+func rowChanged(a, b []float32, tol float64) bool {
+	for i := range a {
+		if math.Abs(float64(a[i])-float64(b[i])) > tol {
+			return true
+		}
+	}
+	return false
+}
+
+// addedRecords returns the number of records of the vocabulary of name added by next.
+func addedRecords(name string, base, next *params.Params) int {
+	fn, _ := next.GetField(name)
+	fb, _ := base.GetField(name)
+	if fn == nil || fb == nil {
+		return 0
+	}
+	n := 0
+	for k := range fn.Records {
+		if _, ok := fb.Records[k]; !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// deltaField adds the new records of a vocabulary, moved or removed records
+// can't be expressed by a delta.
+func deltaField(w *params.Writer, name string, base, next *params.Params) error {
+	fn, _ := next.GetField(name)
+	fb, ok := base.GetField(name)
+	if !ok {
+		fmt.Printf("+ vocab %s\n", name)
+		w.AddField(fn)
+		return nil
+	}
+	added := &proto.Field{Name: fn.Name, Dim: fn.Dim, Records: make(map[string]int64)}
+	for k, i := range fb.Records {
+		if j, ok := fn.Records[k]; !ok || i != j {
+			return fmt.Errorf("delta: vocab %s record %q is moved or removed", name, k)
+		}
+	}
+	for k, i := range fn.Records {
+		if _, ok := fb.Records[k]; !ok {
+			added.Records[k] = i
+		}
+	}
+	if len(added.Records) > 0 {
+		fmt.Printf("+ vocab %s %d records\n", name, len(added.Records))
+		w.AddField(added)
+	}
+	return nil
+}
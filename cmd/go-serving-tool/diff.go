@@ -0,0 +1,136 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/6 14:05
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"reflect"
+
+	"github.com/yinyajun/go-serving/params"
+)
+
+func diff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	tol := fs.Float64("tol", 0, "tolerance of float values")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("diff: expected two files")
+	}
+	a, err := load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	b, err := load(fs.Arg(1))
+	if err != nil {
+		return err
+	}
+
+	if a.ModelName() != b.ModelName() {
+		fmt.Printf("model:   %s -> %s\n", a.ModelName(), b.ModelName())
+	}
+	if a.Version() != b.Version() {
+		fmt.Printf("version: %d -> %d\n", a.Version(), b.Version())
+	}
+	if a.FormatVersion() != b.FormatVersion() {
+		fmt.Printf("format:  %d -> %d\n", a.FormatVersion(), b.FormatVersion())
+	}
+	diffTensors(a, b, *tol)
+	diffFields(a, b)
+	return nil
+}
+
+func diffTensors(a, b *params.Params, tol float64) {
+	for _, name := range union(a.TensorNames(), b.TensorNames()) {
+		ta, tb := a.GetTensor(name), b.GetTensor(name)
+		switch {
+		case ta == nil:
+			fmt.Printf("+ tensor %s %v %v\n", name, tb.Dtype(), tb.Shape())
+		case tb == nil:
+			fmt.Printf("- tensor %s %v %v\n", name, ta.Dtype(), ta.Shape())
+		case ta.Dtype() != tb.Dtype() || !ta.Shape().Eq(tb.Shape()):
+			fmt.Printf("~ tensor %s %v %v -> %v %v\n", name, ta.Dtype(), ta.Shape(), tb.Dtype(), tb.Shape())
+		default:
+			fa, okA := ta.Data().([]float32)
+			fb, okB := tb.Data().([]float32)
+			if !okA || !okB {
+				if !reflect.DeepEqual(ta.Data(), tb.Data()) {
+					fmt.Printf("~ tensor %s values differ\n", name)
+				}
+				continue
+			}
+			var (
+				changed int
+				maxDiff float64
+			)
+			for i := range fa {
+				d := math.Abs(float64(fa[i]) - float64(fb[i]))
+				if d > tol {
+					changed++
+				}
+				maxDiff = math.Max(maxDiff, d)
+			}
+			if changed > 0 {
+				fmt.Printf("~ tensor %s %d/%d values differ, max abs diff %g\n", name, changed, len(fa), maxDiff)
+			}
+		}
+	}
+}
+
+func diffFields(a, b *params.Params) {
+	for _, name := range union(a.FieldNames(), b.FieldNames()) {
+		fa, okA := a.GetField(name)
+		fb, okB := b.GetField(name)
+		switch {
+		case !okA:
+			fmt.Printf("+ vocab %s dim=%d records=%d\n", name, fb.Dim, len(fb.Records))
+		case !okB:
+			fmt.Printf("- vocab %s dim=%d records=%d\n", name, fa.Dim, len(fa.Records))
+		default:
+			var added, removed, moved int
+			for k, i := range fb.Records {
+				j, ok := fa.Records[k]
+				if !ok {
+					added++
+				} else if i != j {
+					moved++
+				}
+			}
+			for k := range fa.Records {
+				if _, ok := fb.Records[k]; !ok {
+					removed++
+				}
+			}
+			if fa.Dim != fb.Dim || added+removed+moved > 0 {
+				fmt.Printf("~ vocab %s dim=%d -> %d, +%d -%d ~%d records\n",
+					name, fa.Dim, fb.Dim, added, removed, moved)
+			}
+		}
+	}
+}
+
+// union merges two sorted name lists.
+func union(a, b []string) []string {
+	res := make([]string, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			res = append(res, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			res = append(res, b[j])
+			j++
+		default:
+			res = append(res, a[i])
+			i++
+			j++
+		}
+	}
+	return res
+}
@@ -0,0 +1,65 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/6 11:32
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+)
+
+func dump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	tensorName := fs.String("tensor", "", "name of the tensor to dump")
+	vocabName := fs.String("vocab", "", "name of the vocabulary field to dump")
+	limit := fs.Int("limit", 0, "max number of vocabulary records to print, 0 means all")
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("dump: expected one file")
+	}
+	if (*tensorName == "") == (*vocabName == "") {
+		return errors.New("dump: exactly one of -tensor and -vocab is required")
+	}
+	m, err := load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if *tensorName != "" {
+		t := m.GetTensor(*tensorName)
+		if t == nil {
+			return fmt.Errorf("dump: tensor %s not found", *tensorName)
+		}
+		fmt.Printf("%s %v %v\n%v\n", *tensorName, t.Dtype(), t.Shape(), t)
+		return nil
+	}
+
+	f, ok := m.GetField(*vocabName)
+	if !ok {
+		return fmt.Errorf("dump: vocab %s not found", *vocabName)
+	}
+	keys := make([]string, 0, len(f.Records))
+	for k := range f.Records {
+		keys = append(keys, k)
+	}
+	// order by index, then by key
+	sort.Slice(keys, func(i, j int) bool {
+		ri, rj := f.Records[keys[i]], f.Records[keys[j]]
+		if ri != rj {
+			return ri < rj
+		}
+		return keys[i] < keys[j]
+	})
+	if *limit > 0 && *limit < len(keys) {
+		keys = keys[:*limit]
+	}
+	fmt.Printf("%s dim=%d records=%d\n", f.Name, f.Dim, len(f.Records))
+	for _, k := range keys {
+		fmt.Printf("%d\t%s\n", f.Records[k], k)
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/25 16:40
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yinyajun/go-serving/checkpoint"
+)
+
+// importCheckpoint converts a tensorflow checkpoint to a model file.
+func importCheckpoint(args []string) error {
+	fs := flag.NewFlagSet("import", flag.ExitOnError)
+	name := fs.String("name", "", "model name")
+	version := fs.Uint64("version", uint64(time.Now().Unix()), "model version")
+	scope := fs.String("scope", "", "imports the variables under the scope only, e.g. linear/linear_model")
+	mapping := fs.String("map", "", "comma separated VARIABLE=NAME, overriding the default names")
+	vocab := fs.String("vocab", "", "comma separated FIELD=FILE of vocabulary files")
+	assets := fs.String("assets", "", "dir of vocabulary files named by fields, e.g. the assets of a SavedModel")
+	list := fs.Bool("list", false, "lists the variables only")
+	fs.Parse(args)
+
+	if *list {
+		if fs.NArg() != 1 {
+			return errors.New("import: expected a checkpoint")
+		}
+		r, err := checkpoint.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		for _, v := range r.Names() {
+			shape, _ := r.Shape(v)
+			name, ok := checkpoint.ColumnName(v)
+			if !ok {
+				name = "-"
+			}
+			fmt.Printf("%s\t%v\t%s\n", v, shape, name)
+		}
+		return nil
+	}
+	if fs.NArg() != 2 || *name == "" {
+		return errors.New("import: expected -name, a checkpoint and an output file")
+	}
+	opts := checkpoint.Options{ModelName: *name, Version: *version, Scope: *scope, AssetsDir: *assets}
+	var err error
+	if opts.Mapping, err = parsePairs(*mapping); err != nil {
+		return fmt.Errorf("import: -map: %w", err)
+	}
+	if opts.Vocabularies, err = parsePairs(*vocab); err != nil {
+		return fmt.Errorf("import: -vocab: %w", err)
+	}
+
+	r, err := checkpoint.Open(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	w, imported, err := checkpoint.Import(r, opts)
+	if err != nil {
+		return err
+	}
+	for _, v := range imported {
+		vocab := v.Vocabulary
+		if vocab == "" && v.Name != checkpoint.BiasName && len(v.Shape) == 2 {
+			vocab = "identity"
+		}
+		fmt.Printf("%s %v -> %s %s\n", v.Variable, v.Shape, v.Name, vocab)
+	}
+	if err := w.WriteFile(fs.Arg(1)); err != nil {
+		return err
+	}
+	fmt.Printf("imported %s -> %s\n", fs.Arg(0), fs.Arg(1))
+	return nil
+}
+
+// parsePairs parses comma separated KEY=VALUE.
+func parsePairs(s string) (map[string]string, error) {
+	res := make(map[string]string)
+	if s == "" {
+		return res, nil
+	}
+	for _, pair := range strings.Split(s, ",") {
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" || v == "" {
+			return nil, fmt.Errorf("invalid pair %q", pair)
+		}
+		res[k] = v
+	}
+	return res, nil
+}
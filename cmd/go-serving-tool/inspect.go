@@ -0,0 +1,51 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/6 11:20
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+func inspect(args []string) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		return errors.New("inspect: expected one file")
+	}
+	m, err := load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	st := m.Stat()
+	fmt.Printf("file:           %s\n", fs.Arg(0))
+	fmt.Printf("model:          %s\n", st.ModelName)
+	fmt.Printf("version:        %d\n", st.Version)
+	if st.BaseVersion != 0 {
+		fmt.Printf("delta of:       %d\n", st.BaseVersion)
+	}
+	fmt.Printf("format version: %d\n", st.FormatVersion)
+	fmt.Printf("sizes:          header=%d data=%d index=%d footer=%d\n",
+		st.HeadSize, st.DataSize, st.IndexSize, st.FooterSize)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "\nTENSOR\tDTYPE\tSTORAGE\tSHAPE\tSIZE")
+	for _, name := range m.TensorNames() {
+		dtype, _ := m.Dtype(name)
+		shape, _ := m.Shape(name)
+		fmt.Fprintf(w, "%s\t%v\t%v\t%v\t%d\n", name, dtype, m.StorageType(name), shape, shape.TotalSize())
+	}
+	fmt.Fprintln(w, "\nFIELD\tDIM\tRECORDS\t\t")
+	for _, name := range m.FieldNames() {
+		f, _ := m.GetField(name)
+		fmt.Fprintf(w, "%s\t%d\t%d\t\t\n", name, f.Dim, len(f.Records))
+	}
+	return w.Flush()
+}
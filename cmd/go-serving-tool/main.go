@@ -0,0 +1,69 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/6 11:03
+ */
+
+// go-serving-tool inspects, verifies and converts go-serving model files, and
+// imports tensorflow checkpoints.
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/yinyajun/go-serving/params"
+)
+
+type command struct {
+	usage string
+	run   func(args []string) error
+}
+
+var commands = map[string]command{
+	"inspect":  {"inspect FILE", inspect},
+	"dump":     {"dump [-tensor NAME | -vocab NAME] [-limit N] FILE", dump},
+	"diff":     {"diff [-tol TOL] FILE1 FILE2", diff},
+	"delta":    {"delta [-tol TOL] BASE NEW OUT", delta},
+	"import":   {"import -name NAME [-version VERSION] [-scope SCOPE] [-map VAR=NAME,...] [-vocab FIELD=FILE,...] [-assets DIR] [-list] CHECKPOINT OUT", importCheckpoint},
+	"verify":   {"verify FILE...", verify},
+	"convert":  {"convert [-name NAME] [-version VERSION] IN OUT", convert},
+	"quantize": {"quantize [-type int8|fp16] [-tensors NAME,...] IN OUT", quantize},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: go-serving-tool <command> [arguments]")
+	fmt.Fprintln(os.Stderr, "Commands:")
+	names := make([]string, 0, len(commands))
+	for k := range commands {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	for _, k := range names {
+		fmt.Fprintln(os.Stderr, "  "+commands[k].usage)
+	}
+}
+
+func load(file string) (*params.Params, error) {
+	m := params.New(file)
+	if err := m.Load(); err != nil {
+		return nil, fmt.Errorf("load %s: %w", file, err)
+	}
+	return m, nil
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(2)
+	}
+	if err := cmd.run(os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
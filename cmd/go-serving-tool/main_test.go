@@ -0,0 +1,112 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 23:20
+ */
+
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yinyajun/go-serving/params"
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+// run runs the command and returns its stdout.
+func run(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	out := make(chan string)
+	go func() {
+		var buf bytes.Buffer
+		io.Copy(&buf, r)
+		out <- buf.String()
+	}()
+	err = commands[args[0]].run(args[1:])
+	w.Close()
+	return <-out, err
+}
+
+func writeModel(t *testing.T, file string, dim int) {
+	t.Helper()
+	w := params.NewWriter("test", 1)
+	table := tensor.New(tensor.WithBacking([]float32{1, -1, 0.5, 2, 0, 3}), tensor.WithShape(3, 2))
+	if err := w.AddDense("F1", table); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddQuantized("F2", table, proto.DataType_DT_INT8); err != nil {
+		t.Fatal(err)
+	}
+	w.AddField(&proto.Field{Name: "F1", Dim: int32(dim), Records: map[string]int64{"a": 0, "b": 2}})
+	w.AddField(&proto.Field{Name: "F2", Dim: 2, Records: map[string]int64{"c": 1}})
+	if err := w.WriteFile(file); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestConvertVerifyInspect(t *testing.T) {
+	dir := t.TempDir()
+	in, out := filepath.Join(dir, "in.pb"), filepath.Join(dir, "out.pb")
+	writeModel(t, in, 2)
+
+	if _, err := run(t, "convert", "-name", "renamed", "-version", "2", in, out); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := run(t, "verify", in, out); err != nil || strings.Count(got, "OK   ") != 2 {
+		t.Fatalf("verify: got %q, %v", got, err)
+	}
+	got, err := run(t, "inspect", out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := make(map[string]bool)
+	for _, line := range strings.Split(got, "\n") {
+		lines[strings.Join(strings.Fields(line), " ")] = true
+	}
+	for _, want := range []string{
+		"model: renamed",
+		"version: 2",
+		// the stored shape of the quantized table
+		"F2 float32 DT_INT8 (3, 2) 6",
+		"F1 2 2",
+		"F2 2 1",
+	} {
+		if !lines[want] {
+			t.Errorf("inspect: %q is not in\n%s", want, got)
+		}
+	}
+
+	m, err := load(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.FormatVersion() != params.FormatVersion || m.ModelName() != "renamed" || m.Version() != 2 {
+		t.Errorf("got %s %d of format version %d", m.ModelName(), m.Version(), m.FormatVersion())
+	}
+}
+
+func TestVerifyFails(t *testing.T) {
+	dir := t.TempDir()
+	valid, invalid := filepath.Join(dir, "valid.pb"), filepath.Join(dir, "invalid.pb")
+	writeModel(t, valid, 2)
+	writeModel(t, invalid, 3)
+	got, err := run(t, "verify", valid, invalid, filepath.Join(dir, "missing.pb"))
+	if err == nil || err.Error() != "verify: 2 of 3 files failed" {
+		t.Fatalf("got %v", err)
+	}
+	if !strings.Contains(got, "embedding F1 expected dim 3, but got 2") {
+		t.Errorf("got %s", got)
+	}
+}
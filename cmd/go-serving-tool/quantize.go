@@ -0,0 +1,90 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/8 17:35
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/yinyajun/go-serving/params"
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+var quantizeTypes = map[string]proto.DataType{
+	"int8": proto.DataType_DT_INT8,
+	"fp16": proto.DataType_DT_HALF,
+}
+
+// quantize rewrites the embedding tables of a model file as int8 or fp16,
+// by default every float table which has a vocabulary is quantized.
+func quantize(args []string) error {
+	fs := flag.NewFlagSet("quantize", flag.ExitOnError)
+	typ := fs.String("type", "int8", "quantized type, int8 or fp16")
+	names := fs.String("tensors", "", "comma separated tensors to quantize, default all embedding tables")
+	fs.Parse(args)
+	if fs.NArg() != 2 {
+		return errors.New("quantize: expected input and output files")
+	}
+	dtype, ok := quantizeTypes[*typ]
+	if !ok {
+		return fmt.Errorf("quantize: unknown type %q", *typ)
+	}
+	m, err := load(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	var tables []string
+	if *names != "" {
+		tables = strings.Split(*names, ",")
+	} else {
+		for _, name := range m.FieldNames() {
+			if m.StorageType(name) == proto.DataType_DT_FLOAT {
+				tables = append(tables, name)
+			}
+		}
+	}
+
+	w, err := params.FromParams(m)
+	if err != nil {
+		return err
+	}
+	for _, name := range tables {
+		if m.StorageType(name) != proto.DataType_DT_FLOAT {
+			return fmt.Errorf("quantize: %s is not a float tensor", name)
+		}
+		t := m.GetTensor(name).(*tensor.Dense)
+		pt, err := params.Quantize(t, dtype)
+		if err != nil {
+			return fmt.Errorf("quantize: %s: %w", name, err)
+		}
+		w.AddTensor(name, pt)
+		fmt.Printf("%s %v: %d -> %d bytes, max abs error %g\n",
+			name, t.Shape(), 4*t.Shape().TotalSize(), len(pt.ByteVal)+4*len(pt.Scale), maxError(t, pt))
+	}
+	if err := w.WriteFile(fs.Arg(1)); err != nil {
+		return err
+	}
+	fmt.Printf("quantized %s -> %s\n", fs.Arg(0), fs.Arg(1))
+	return nil
+}
+
+// maxError returns the max abs error between a table and its quantized form.
+func maxError(t *tensor.Dense, pt *proto.Tensor) float64 {
+	deq, err := params.Dequantize(pt)
+	if err != nil {
+		return math.NaN()
+	}
+	var res float64
+	for i, v := range deq.Data().([]float32) {
+		res = math.Max(res, math.Abs(float64(v)-float64(t.Data().([]float32)[i])))
+	}
+	return res
+}
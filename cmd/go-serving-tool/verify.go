@@ -0,0 +1,77 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/6 14:40
+ */
+
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+
+	"github.com/yinyajun/go-serving/params"
+)
+
+// verify loads the files, which checks the section checksums, and checks
+// every vocabulary field against its embedding table.
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	fs.Parse(args)
+	if fs.NArg() == 0 {
+		return errors.New("verify: expected at least one file")
+	}
+
+	failed := 0
+	for _, file := range fs.Args() {
+		m, err := load(file)
+		if err != nil {
+			fmt.Printf("FAIL %s: %s\n", file, err)
+			failed++
+			continue
+		}
+		if m.FormatVersion() < params.FormatVersion {
+			fmt.Printf("WARN %s: format version %d has no checksums, run convert to upgrade\n", file, m.FormatVersion())
+		}
+		problems := compat(m)
+		if len(problems) == 0 {
+			fmt.Printf("OK   %s (format version %d)\n", file, m.FormatVersion())
+			continue
+		}
+		failed++
+		fmt.Printf("FAIL %s\n", file)
+		for _, p := range problems {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("verify: %d of %d files failed", failed, fs.NArg())
+	}
+	return nil
+}
+
+func compat(m *params.Params) []string {
+	var problems []string
+	for _, name := range m.FieldNames() {
+		f, _ := m.GetField(name)
+		shape, ok := m.Shape(name)
+		if !ok {
+			problems = append(problems, fmt.Sprintf("vocab %s has no embedding table", name))
+			continue
+		}
+		if len(shape) != 2 {
+			problems = append(problems, fmt.Sprintf("embedding %s expected 2 dims, but got %v", name, shape))
+			continue
+		}
+		if int(f.Dim) != shape[1] {
+			problems = append(problems, fmt.Sprintf("embedding %s expected dim %d, but got %d", name, f.Dim, shape[1]))
+		}
+		for k, i := range f.Records {
+			if i < 0 || int(i) >= shape[0] {
+				problems = append(problems, fmt.Sprintf("vocab %s record %q index %d out of range [0, %d)", name, k, i, shape[0]))
+				break
+			}
+		}
+	}
+	return problems
+}
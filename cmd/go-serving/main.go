@@ -0,0 +1,13 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/17 15:40
+ */
+
+// go-serving serves the models listed in a config file, or a single model by flags.
+package main
+
+import "github.com/yinyajun/go-serving/server"
+
+func main() {
+	server.Main()
+}
@@ -6,6 +6,7 @@
 package column
 
 import (
+	"context"
 	"log"
 	"reflect"
 	"sort"
@@ -41,7 +42,7 @@ func (c *IdentityColumn) Name() string {
 	return c.Field
 }
 
-func (c *IdentityColumn) Transform(m params.Meta, inputs Inputs) (tensor.Tensor, error) {
+func (c *IdentityColumn) Transform(ctx context.Context, m params.Meta, inputs Inputs) (tensor.Tensor, error) {
 	// already transformed
 	if t, ok := inputs.Get(c); ok {
 		return t, nil
@@ -97,7 +98,7 @@ func (c *BucketizedColumn) bucketized(target float32) int {
 
 func (c *BucketizedColumn) NumBuckets() int { return len(c.Boundaries) + 1 }
 
-func (c *BucketizedColumn) Transform(m params.Meta, inputs Inputs) (tensor.Tensor, error) {
+func (c *BucketizedColumn) Transform(ctx context.Context, m params.Meta, inputs Inputs) (tensor.Tensor, error) {
 	// already transformed
 	if t, ok := inputs.Get(c); ok {
 		return t, nil
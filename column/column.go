@@ -6,11 +6,13 @@
 package column
 
 import (
+	"context"
+
 	"github.com/yinyajun/go-serving/params"
 	"gorgonia.org/tensor"
 )
 
 type FeatureColumn interface {
 	Name() string
-	Transform(m params.Meta, inputs Inputs) (tensor.Tensor, error)
+	Transform(ctx context.Context, m params.Meta, inputs Inputs) (tensor.Tensor, error)
 }
@@ -6,6 +6,7 @@
 package column
 
 import (
+	"context"
 	"math"
 
 	math2 "github.com/yinyajun/go-serving/math"
@@ -55,7 +56,7 @@ func (c *EmbeddingColumn) Dimension() int { return c.dim }
 
 func (c *EmbeddingColumn) Name() string { return c.column.Name() + "_embedding" }
 
-func (c *EmbeddingColumn) Transform(m params.Meta, inputs Inputs) (tensor.Tensor, error) {
+func (c *EmbeddingColumn) Transform(ctx context.Context, m params.Meta, inputs Inputs) (tensor.Tensor, error) {
 	// already transformed
 	if t, ok := inputs.Get(c); ok {
 		return t, nil
@@ -64,13 +65,17 @@ func (c *EmbeddingColumn) Transform(m params.Meta, inputs Inputs) (tensor.Tensor
 	index, ok := inputs.Get(c.column)
 	var err error
 	if !ok {
-		index, err = c.column.Transform(m, inputs)
+		index, err = c.column.Transform(ctx, m, inputs)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	embeddings := m.EmbeddingLookup(c.column.Name(), index.Data().([]int)).(*tensor.Dense) // todo: can omit check?
+	lookup, err := m.EmbeddingLookup(c.column.Name(), index.Data().([]int))
+	if err != nil {
+		return nil, err
+	}
+	embeddings := lookup.(*tensor.Dense)
 	shape := append(index.Shape(), c.dim)
 	err = embeddings.Reshape(shape...)
 	if err != nil {
@@ -6,11 +6,17 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"github.com/yinyajun/go-serving/column"
+	"github.com/yinyajun/go-serving/metrics"
 	"github.com/yinyajun/go-serving/model"
 	"github.com/yinyajun/go-serving/serving"
 	"gorgonia.org/tensor"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
@@ -21,7 +27,8 @@ func LRModel() model.Model {
 }
 
 func main() {
-	s := serving.New()
+	m := metrics.NewPrometheus("go_serving")
+	s := serving.New(serving.WithMetrics(m))
 	s.Register(&serving.ModelConfig{
 		Name:  "wide_deep",
 		Path:  "/tmp/data/wide_deep",
@@ -30,6 +37,19 @@ func main() {
 	s.Launch()
 	go s.Watch()
 
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", m.Handler())
+	mux.Handle("/healthz", s.HealthHandler())
+	mux.Handle("/readyz", s.HealthHandler())
+	mux.Handle("/v1/models/", s.PredictHandler())
+	go s.ListenAndServe(":8080", mux)
+
+	// the admin api has no auth, it's only served on loopback
+	admin := http.NewServeMux()
+	admin.Handle("/v1/models/", s.AdminHandler())
+	admin.Handle("/v1/models", s.AdminHandler())
+	go s.ListenAndServe("127.0.0.1:8082", admin)
+
 	go func() {
 		for {
 			batch := 2
@@ -44,5 +64,13 @@ func main() {
 		}
 
 	}()
-	select {}
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	<-sig
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := s.Shutdown(ctx); err != nil {
+		fmt.Println(err)
+	}
 }
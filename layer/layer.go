@@ -6,15 +6,45 @@
 package layer
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"sort"
 
 	"github.com/yinyajun/go-serving/column"
 	"github.com/yinyajun/go-serving/params"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorgonia.org/tensor"
 )
 
+var tracer = otel.Tracer("github.com/yinyajun/go-serving/layer")
+
+// transform transforms the columns one by one, each in its own span.
+func transform(ctx context.Context, m params.Meta, inputs column.Inputs, cols column.DenseColumns) ([]tensor.Tensor, error) {
+	tt := make([]tensor.Tensor, len(cols))
+	for i, col := range cols {
+		// check cancellation between columns
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		cctx, span := tracer.Start(ctx, "Column.Transform",
+			trace.WithAttributes(attribute.String("column.name", col.Name())))
+		t, err := col.Transform(cctx, m, inputs)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			span.End()
+			return nil, err
+		}
+		span.End()
+		tt[i] = t
+	}
+	return tt, nil
+}
+
 type inputLayer struct {
 	columns column.DenseColumns
 }
@@ -24,14 +54,13 @@ func NewInputLayer(cols column.DenseColumns) *inputLayer {
 	return &inputLayer{columns: cols}
 }
 
-func (l *inputLayer) Call(m params.Meta, inputs column.Inputs) (tensor.Tensor, error) {
-	tt := make([]tensor.Tensor, len(l.columns))
-	for i, col := range l.columns {
-		t, err := col.Transform(m, inputs)
-		if err != nil {
-			return nil, err
-		}
-		tt[i] = t
+func (l *inputLayer) Call(ctx context.Context, m params.Meta, inputs column.Inputs) (tensor.Tensor, error) {
+	ctx, span := tracer.Start(ctx, "InputLayer.Call")
+	defer span.End()
+
+	tt, err := transform(ctx, m, inputs, l.columns)
+	if err != nil {
+		return nil, err
 	}
 	if len(tt) == 0 {
 		return nil, errors.New("output tensor is empty")
@@ -60,14 +89,14 @@ func NewLinearModelLayer(units int, cols column.DenseColumns) *LinearModelLayer
 	return &LinearModelLayer{columns: cols, units: units}
 }
 
-func (l *LinearModelLayer) Call(m params.Meta, inputs column.Inputs) (tensor.Tensor, error) {
-	tt := make([]tensor.Tensor, len(l.columns))
-	for i, col := range l.columns {
-		t, err := col.Transform(m, inputs)
-		if err != nil {
-			return nil, err
-		}
-		tt[i] = t
+func (l *LinearModelLayer) Call(ctx context.Context, m params.Meta, inputs column.Inputs) (tensor.Tensor, error) {
+	ctx, span := tracer.Start(ctx, "LinearModelLayer.Call",
+		trace.WithAttributes(attribute.Int("layer.units", l.units)))
+	defer span.End()
+
+	tt, err := transform(ctx, m, inputs, l.columns)
+	if err != nil {
+		return nil, err
 	}
 	if len(tt) == 0 {
 		return nil, errors.New("output tensor is empty")
@@ -0,0 +1,191 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/11 11:30
+ */
+
+// Package metrics implements serving.Metrics by prometheus.
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yinyajun/go-serving/serving"
+)
+
+var _ serving.Metrics = (*Prometheus)(nil)
+
+type Prometheus struct {
+	registry     *prometheus.Registry
+	requests     *prometheus.CounterVec
+	errors       *prometheus.CounterVec
+	latency      *prometheus.HistogramVec
+	batchSize    *prometheus.HistogramVec
+	version      *prometheus.GaugeVec
+	loadDuration *prometheus.HistogramVec
+	loadFailures *prometheus.CounterVec
+	rollbacks    *prometheus.CounterVec
+	paramsBytes  *prometheus.GaugeVec
+	shadowDiff   *prometheus.HistogramVec
+	shadowErrors *prometheus.CounterVec
+	cacheHits    *prometheus.CounterVec
+	cacheMisses  *prometheus.CounterVec
+	embedHits    *prometheus.CounterVec
+	embedMisses  *prometheus.CounterVec
+}
+
+// NewPrometheus creates the serving metrics in its own registry, metric
+// names are prefixed by namespace, e.g. go_serving_requests_total.
+func NewPrometheus(namespace string) *Prometheus {
+	p := &Prometheus{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Number of requests.",
+		}, []string{"model"}),
+		errors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "request_errors_total",
+			Help:      "Number of failed requests by error type.",
+		}, []string{"model", "type"}),
+		latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "request_latency_seconds",
+			Help:      "Latency of requests.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 2, 16),
+		}, []string{"model"}),
+		batchSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "batch_size",
+			Help:      "Rows of predicted batches.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 11),
+		}, []string{"model"}),
+		version: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "model_version",
+			Help:      "Currently loaded version of the model.",
+		}, []string{"model"}),
+		loadDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "load_duration_seconds",
+			Help:      "Duration of loading model versions.",
+			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 14),
+		}, []string{"model"}),
+		loadFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "load_failures_total",
+			Help:      "Number of failed model version loads.",
+		}, []string{"model"}),
+		rollbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rollbacks_total",
+			Help:      "Number of model versions rolled back.",
+		}, []string{"model"}),
+		paramsBytes: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "params_bytes",
+			Help:      "Memory size of the loaded params.",
+		}, []string{"model"}),
+		shadowDiff: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "shadow_score_diff",
+			Help:      "Mean absolute difference between the outputs of shadow and served models.",
+			Buckets:   prometheus.ExponentialBuckets(0.0001, 4, 10),
+		}, []string{"route", "shadow"}),
+		shadowErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "shadow_errors_total",
+			Help:      "Number of failed or incomparable shadow requests.",
+		}, []string{"route", "shadow"}),
+		cacheHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_hits_total",
+			Help:      "Number of rows hit by the output cache.",
+		}, []string{"model"}),
+		cacheMisses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_misses_total",
+			Help:      "Number of rows missed by the output cache.",
+		}, []string{"model"}),
+		embedHits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "embedding_cache_hits_total",
+			Help:      "Number of embedding rows hit by the row cache of tiered tables.",
+		}, []string{"model", "table"}),
+		embedMisses: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "embedding_cache_misses_total",
+			Help:      "Number of embedding rows read from the store of tiered tables.",
+		}, []string{"model", "table"}),
+	}
+	p.registry.MustRegister(p.requests, p.errors, p.latency, p.batchSize,
+		p.version, p.loadDuration, p.loadFailures, p.rollbacks, p.paramsBytes, p.shadowDiff, p.shadowErrors,
+		p.cacheHits, p.cacheMisses, p.embedHits, p.embedMisses)
+	return p
+}
+
+// Registry returns the registry of the metrics, which can be used to register more collectors.
+func (p *Prometheus) Registry() *prometheus.Registry {
+	return p.registry
+}
+
+// Handler serves the metrics in prometheus text format, usually on /metrics.
+func (p *Prometheus) Handler() http.Handler {
+	return promhttp.HandlerFor(p.registry, promhttp.HandlerOpts{})
+}
+
+func (p *Prometheus) ObserveRequest(model string, latency time.Duration, err error) {
+	p.requests.WithLabelValues(model).Inc()
+	p.latency.WithLabelValues(model).Observe(latency.Seconds())
+	if err != nil {
+		p.errors.WithLabelValues(model, serving.ErrorType(err)).Inc()
+	}
+}
+
+func (p *Prometheus) ObserveBatch(model string, size int) {
+	p.batchSize.WithLabelValues(model).Observe(float64(size))
+}
+
+func (p *Prometheus) ObserveLoad(model string, version uint64, duration time.Duration, size int64, err error) {
+	p.loadDuration.WithLabelValues(model).Observe(duration.Seconds())
+	if err != nil {
+		p.loadFailures.WithLabelValues(model).Inc()
+		return
+	}
+	p.version.WithLabelValues(model).Set(float64(version))
+	p.paramsBytes.WithLabelValues(model).Set(float64(size))
+}
+
+func (p *Prometheus) ObserveRollback(model string, from, to uint64, size int64) {
+	p.rollbacks.WithLabelValues(model).Inc()
+	p.version.WithLabelValues(model).Set(float64(to))
+	p.paramsBytes.WithLabelValues(model).Set(float64(size))
+}
+
+func (p *Prometheus) ObserveShadow(route, shadow string, diff float64, err error) {
+	if err != nil {
+		p.shadowErrors.WithLabelValues(route, shadow).Inc()
+		return
+	}
+	p.shadowDiff.WithLabelValues(route, shadow).Observe(diff)
+}
+
+func (p *Prometheus) ObserveCache(model string, hits, misses int) {
+	p.cacheHits.WithLabelValues(model).Add(float64(hits))
+	p.cacheMisses.WithLabelValues(model).Add(float64(misses))
+}
+
+func (p *Prometheus) ObserveEmbeddingCache(model, table string, hits, misses int) {
+	p.embedHits.WithLabelValues(model, table).Add(float64(hits))
+	p.embedMisses.WithLabelValues(model, table).Add(float64(misses))
+}
+
+// ObserveRemoval deletes the gauges of the model, the counters are kept, so
+// that their last increments are still scraped.
+func (p *Prometheus) ObserveRemoval(model string) {
+	p.version.DeleteLabelValues(model)
+	p.paramsBytes.DeleteLabelValues(model)
+}
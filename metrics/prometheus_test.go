@@ -0,0 +1,58 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 22:30
+ */
+
+package metrics
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// series returns the sorted model labels of the metric family of name.
+func series(t *testing.T, p *Prometheus, name string) []string {
+	t.Helper()
+	families, err := p.Registry().Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var res []string
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "model" {
+					res = append(res, l.GetValue())
+				}
+			}
+		}
+	}
+	sort.Strings(res)
+	return res
+}
+
+func TestObserveRemoval(t *testing.T) {
+	p := NewPrometheus("test")
+	for _, model := range []string{"a", "b"} {
+		p.ObserveLoad(model, 1, time.Second, 100, nil)
+		p.ObserveRequest(model, time.Millisecond, nil)
+	}
+	p.ObserveRemoval("a")
+
+	for _, name := range []string{"test_model_version", "test_params_bytes"} {
+		if got := series(t, p, name); !reflect.DeepEqual(got, []string{"b"}) {
+			t.Errorf("%s: got %v, want [b]", name, got)
+		}
+	}
+	if got := series(t, p, "test_requests_total"); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("requests: got %v, want [a b]", got)
+	}
+	// removing twice or an unknown model is fine
+	p.ObserveRemoval("a")
+	p.ObserveRemoval("c")
+}
@@ -6,6 +6,8 @@
 package model
 
 import (
+	"context"
+
 	"github.com/yinyajun/go-serving/column"
 	"github.com/yinyajun/go-serving/layer"
 	"github.com/yinyajun/go-serving/math"
@@ -16,7 +18,7 @@ import (
 type Features map[string]tensor.Tensor
 
 type Model interface {
-	Predict(params.Meta, Features) (tensor.Tensor, error)
+	Predict(context.Context, params.Meta, Features) (tensor.Tensor, error)
 }
 
 type LogisticRegression struct {
@@ -30,12 +32,12 @@ func NewLR(name string, units int, columns column.DenseColumns) *LogisticRegress
 		layer: layer.NewLinearModelLayer(units, columns)}
 }
 
-func (m *LogisticRegression) Predict(meta params.Meta, feats Features) (tensor.Tensor, error) {
-	inputs, err := NewInputs(feats)
+func (m *LogisticRegression) Predict(ctx context.Context, meta params.Meta, feats Features) (tensor.Tensor, error) {
+	inputs, err := NewInputs(ctx, feats)
 	if err != nil {
 		return nil, err
 	}
-	logit, err := m.layer.Call(meta, inputs)
+	logit, err := m.layer.Call(ctx, meta, inputs)
 	if err != nil {
 		return nil, err
 	}
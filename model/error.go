@@ -0,0 +1,32 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/7 10:31
+ */
+
+package model
+
+import "fmt"
+
+type SpecError struct {
+	msg string
+}
+
+func (e SpecError) Error() string {
+	return fmt.Sprintf("Invalid spec: %s", e.msg)
+}
+
+type NotRegisteredError struct {
+	name string
+}
+
+func (e NotRegisteredError) Error() string {
+	return fmt.Sprintf("Model %s is not registered", e.name)
+}
+
+type RegisteredError struct {
+	name string
+}
+
+func (e RegisteredError) Error() string {
+	return fmt.Sprintf("Model %s is registered twice", e.name)
+}
@@ -6,10 +6,28 @@
 package model
 
 import (
+	"context"
 	"errors"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
 	"gorgonia.org/tensor"
 )
 
+var tracer = otel.Tracer("github.com/yinyajun/go-serving/model")
+
+// BatchSize returns dimension 0 of the features, -1 if it's empty or inconsistent.
+func (f Features) BatchSize() int {
+	batch := -1
+	for _, val := range f {
+		if val.Dims() == 0 || (batch != -1 && val.Shape()[0] != batch) {
+			return -1
+		}
+		batch = val.Shape()[0]
+	}
+	return batch
+}
+
 type InputCache map[interface{}]tensor.Tensor
 
 func (b InputCache) Get(key interface{}) (tensor.Tensor, bool) {
@@ -21,7 +39,11 @@ func (b InputCache) Set(key interface{}, t tensor.Tensor) {
 	b[key] = t
 }
 
-func NewInputs(features Features) (InputCache, error) {
+func NewInputs(ctx context.Context, features Features) (InputCache, error) {
+	_, span := tracer.Start(ctx, "NewInputs")
+	defer span.End()
+	span.SetAttributes(attribute.Int("features", len(features)))
+
 	var (
 		in    = make(InputCache)
 		batch = -1
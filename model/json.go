@@ -0,0 +1,79 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/15 11:20
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gorgonia.org/tensor"
+)
+
+// jsonTensor is the json form of a feature, exactly one of the values is set.
+type jsonTensor struct {
+	Shape     []int     `json:"shape"`
+	FloatVal  []float32 `json:"float_val,omitempty"`
+	IntVal    []int     `json:"int_val,omitempty"`
+	StringVal []string  `json:"string_val,omitempty"`
+}
+
+func (j jsonTensor) tensor() (tensor.Tensor, error) {
+	var backing interface{}
+	size := 0
+	switch {
+	case j.FloatVal != nil:
+		backing, size = j.FloatVal, len(j.FloatVal)
+	case j.IntVal != nil:
+		backing, size = j.IntVal, len(j.IntVal)
+	case j.StringVal != nil:
+		backing, size = j.StringVal, len(j.StringVal)
+	default:
+		return nil, fmt.Errorf("no values")
+	}
+	if tensor.Shape(j.Shape).TotalSize() != size {
+		return nil, fmt.Errorf("shape %v mismatches %d values", j.Shape, size)
+	}
+	return tensor.New(tensor.WithBacking(backing), tensor.WithShape(j.Shape...)), nil
+}
+
+func (f Features) MarshalJSON() ([]byte, error) {
+	m := make(map[string]jsonTensor, len(f))
+	for name, t := range f {
+		j := jsonTensor{Shape: t.Shape().Clone()}
+		if v, ok := t.(tensor.View); ok && v.IsMaterializable() {
+			t = v.Materialize()
+		}
+		switch v := t.Data().(type) {
+		case []float32:
+			j.FloatVal = v
+		case []int:
+			j.IntVal = v
+		case []string:
+			j.StringVal = v
+		default:
+			return nil, fmt.Errorf("feature %s: unsupported dtype %v", name, t.Dtype())
+		}
+		m[name] = j
+	}
+	return json.Marshal(m)
+}
+
+func (f *Features) UnmarshalJSON(data []byte) error {
+	var m map[string]jsonTensor
+	if err := json.Unmarshal(data, &m); err != nil {
+		return err
+	}
+	feats := make(Features, len(m))
+	for name, j := range m {
+		t, err := j.tensor()
+		if err != nil {
+			return fmt.Errorf("feature %s: %w", name, err)
+		}
+		feats[name] = t
+	}
+	*f = feats
+	return nil
+}
@@ -0,0 +1,36 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/18 10:05
+ */
+
+package model
+
+import "sync"
+
+var registry = struct {
+	sync.RWMutex
+	factories map[string]func() Model
+}{factories: make(map[string]func() Model)}
+
+// Register makes a Go-defined model available by name, e.g. to the model config file.
+// It returns a RegisteredError if the name is registered twice.
+func Register(name string, factory func() Model) error {
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := registry.factories[name]; ok {
+		return RegisteredError{name: name}
+	}
+	registry.factories[name] = factory
+	return nil
+}
+
+// Registered builds the model registered by name.
+func Registered(name string) (Model, error) {
+	registry.RLock()
+	factory, ok := registry.factories[name]
+	registry.RUnlock()
+	if !ok {
+		return nil, NotRegisteredError{name: name}
+	}
+	return factory(), nil
+}
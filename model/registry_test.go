@@ -0,0 +1,27 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 23:00
+ */
+
+package model
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	factory := func() Model { return nil }
+	if err := Register("test_register", factory); err != nil {
+		t.Fatal(err)
+	}
+	if err := Register("test_register", factory); !errors.As(err, &RegisteredError{}) {
+		t.Fatalf("got %v, want RegisteredError", err)
+	}
+	if _, err := Registered("test_register"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Registered("test_missing"); !errors.As(err, &NotRegisteredError{}) {
+		t.Fatalf("got %v, want NotRegisteredError", err)
+	}
+}
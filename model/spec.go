@@ -0,0 +1,154 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/7 10:26
+ */
+
+package model
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/yinyajun/go-serving/column"
+)
+
+// Spec describes a model by its feature columns, so that a model exported
+// outside of Go can be served without writing Go code.
+//
+//	{
+//	  "name": "wide_deep",
+//	  "type": "lr",
+//	  "units": 3,
+//	  "columns": [
+//	    {"type": "embedding", "dimension": 3, "combiner": "sum",
+//	     "categorical": {"type": "identity", "field": "F1", "default": "124", "buckets": 2}}
+//	  ]
+//	}
+type Spec struct {
+	Name    string        `json:"name"`
+	Type    string        `json:"type"`
+	Units   int           `json:"units"`
+	Columns []*ColumnSpec `json:"columns"`
+}
+
+type ColumnSpec struct {
+	Type string `json:"type"`
+
+	// identity and bucketized column
+	Field string `json:"field,omitempty"`
+	// identity column
+	Default string `json:"default,omitempty"`
+	Buckets int    `json:"buckets,omitempty"`
+	// bucketized column
+	DefaultValue float32   `json:"default_value,omitempty"`
+	OmittedValue float32   `json:"omitted_value,omitempty"`
+	Boundaries   []float32 `json:"boundaries,omitempty"`
+
+	// embedding column
+	Categorical *ColumnSpec `json:"categorical,omitempty"`
+	Dimension   int         `json:"dimension,omitempty"`
+	Combiner    string      `json:"combiner,omitempty"`
+	Weight      string      `json:"weight,omitempty"`
+}
+
+const (
+	LRModelType = "lr"
+
+	IdentityColumnType   = "identity"
+	BucketizedColumnType = "bucketized"
+	EmbeddingColumnType  = "embedding"
+)
+
+var combiners = map[string]column.Combiner{
+	"":      column.Sum,
+	"sum":   column.Sum,
+	"mean":  column.Mean,
+	"sqrtn": column.SqrtN,
+}
+
+func LoadSpec(file string) (*Spec, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	spec := new(Spec)
+	if err := json.Unmarshal(buf, spec); err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return spec, nil
+}
+
+// Build creates the model described by the spec.
+func (s *Spec) Build() (Model, error) {
+	if s.Name == "" {
+		return nil, SpecError{"name is required"}
+	}
+	cols := make(column.DenseColumns, len(s.Columns))
+	for i, c := range s.Columns {
+		col, err := c.dense()
+		if err != nil {
+			return nil, err
+		}
+		cols[i] = col
+	}
+
+	switch s.Type {
+	case LRModelType:
+		for _, col := range cols {
+			if col.Dimension() != s.Units {
+				return nil, SpecError{fmt.Sprintf("column %s expected dimension %d, but got %d",
+					col.Name(), s.Units, col.Dimension())}
+			}
+		}
+		return NewLR(s.Name, s.Units, cols), nil
+	default:
+		return nil, SpecError{fmt.Sprintf("unknown model type %q", s.Type)}
+	}
+}
+
+func (c *ColumnSpec) dense() (column.DenseColumn, error) {
+	if c.Type != EmbeddingColumnType {
+		return nil, SpecError{fmt.Sprintf("expected dense column, but got %q", c.Type)}
+	}
+	if c.Categorical == nil {
+		return nil, SpecError{"embedding column requires a categorical column"}
+	}
+	if c.Dimension <= 0 {
+		return nil, SpecError{"embedding column requires a positive dimension"}
+	}
+	comb, ok := combiners[c.Combiner]
+	if !ok {
+		return nil, SpecError{fmt.Sprintf("unknown combiner %q", c.Combiner)}
+	}
+	cat, err := c.Categorical.categorical()
+	if err != nil {
+		return nil, err
+	}
+	return column.NewEmbeddingColumn(cat, c.Weight, c.Dimension, comb), nil
+}
+
+func (c *ColumnSpec) categorical() (column.CategoricalColumn, error) {
+	if c.Field == "" {
+		return nil, SpecError{fmt.Sprintf("%s column requires a field", c.Type)}
+	}
+	switch c.Type {
+	case IdentityColumnType:
+		if c.Default == "" || c.Buckets <= 0 {
+			return nil, SpecError{fmt.Sprintf("identity column %s requires default and buckets", c.Field)}
+		}
+		return column.NewIdentityColumn(c.Field, c.Default, c.Buckets), nil
+	case BucketizedColumnType:
+		if c.DefaultValue == c.OmittedValue {
+			return nil, SpecError{fmt.Sprintf("bucketized column %s requires default_value != omitted_value", c.Field)}
+		}
+		for i := 1; i < len(c.Boundaries); i++ {
+			if c.Boundaries[i-1] > c.Boundaries[i] {
+				return nil, SpecError{fmt.Sprintf("bucketized column %s requires sorted boundaries", c.Field)}
+			}
+		}
+		return column.NewBucketizedColumn(c.Field, c.DefaultValue, c.OmittedValue, c.Boundaries), nil
+	default:
+		return nil, SpecError{fmt.Sprintf("expected categorical column, but got %q", c.Type)}
+	}
+}
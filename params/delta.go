@@ -0,0 +1,345 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/24 15:20
+ */
+
+package params
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+const (
+	// DeltaExt is the extension of delta files, which are named by their versions
+	// like model files, e.g. 1649235600.delta.
+	DeltaExt = ".delta"
+	// DeltaIDsSuffix names the int32 row ids of the changed rows of a table in
+	// delta files, e.g. F1:ids for the rows in F1. Tensors without ids are
+	// replaced as a whole.
+	DeltaIDsSuffix = ":ids"
+)
+
+// chunkRows is the number of rows of a chunk of the tables updated by deltas.
+const chunkRows = 1024
+
+// ApplyDelta fills m with base updated by delta. Only the updated chunks of
+// tables and the updated vocabularies are copied, base is unchanged, so
+// requests in flight on base still see a consistent version.
+func (m *Params) ApplyDelta(base, delta *Params) error {
+	if !delta.IsDelta() {
+		return NotDeltaErr
+	}
+	if delta.base != base.BaseVersion() {
+		return fmt.Errorf("%w: %d, params of %d", DeltaBaseErr, delta.base, base.BaseVersion())
+	}
+	if delta.Version() <= base.Version() {
+		return fmt.Errorf("%w: %d, params of %d", DeltaVersionErr, delta.Version(), base.Version())
+	}
+
+	m.tensors = make(map[string]*tensor.Dense, len(base.tensors))
+	for k, v := range base.tensors {
+		m.tensors[k] = v
+	}
+	m.quantized = make(map[string]*quantizedTable, len(base.quantized))
+	for k, v := range base.quantized {
+		m.quantized[k] = v
+	}
+	m.chunked = make(map[string]*chunkedTable, len(base.chunked))
+	for k, v := range base.chunked {
+		m.chunked[k] = v
+	}
+	m.index = make(map[string]*proto.Field, len(base.index))
+	for k, v := range base.index {
+		m.index[k] = v
+	}
+	m.overlays = make(map[string][]map[string]int64, len(base.overlays))
+	for k, v := range base.overlays {
+		m.overlays[k] = v
+	}
+	for name, t := range delta.tensors {
+		if strings.HasSuffix(name, DeltaIDsSuffix) {
+			continue
+		}
+		if err := m.applyTensor(name, delta.tensors[name+DeltaIDsSuffix], t, nil, m.added(delta, name)); err != nil {
+			return err
+		}
+	}
+	for name, q := range delta.quantized {
+		if err := m.applyTensor(name, delta.tensors[name+DeltaIDsSuffix], nil, q, m.added(delta, name)); err != nil {
+			return err
+		}
+	}
+	// the records are layered on base instead of copying the vocabulary
+	for name, f := range delta.index {
+		if _, ok := m.index[name]; !ok {
+			m.index[name] = f
+			continue
+		}
+		overlays := m.overlays[name]
+		m.overlays[name] = append(overlays[:len(overlays):len(overlays)], f.Records)
+	}
+
+	m.file = delta.file
+	m.header = delta.header
+	m.footer = delta.footer
+	m.base = base.BaseVersion()
+	m.deltas = append(base.deltas[:len(base.deltas):len(base.deltas)], delta.Version())
+	m.stat = base.stat
+	m.stat.Version = delta.Version()
+	return nil
+}
+
+// added returns the number of records the delta adds to the vocabulary of name.
+func (m *Params) added(delta *Params, name string) int {
+	f, ok := delta.index[name]
+	if !ok {
+		return 0
+	}
+	base, ok := m.index[name]
+	if !ok {
+		return len(f.Records)
+	}
+	n := 0
+	for k := range f.Records {
+		if _, ok := m.record(name, base, k); !ok {
+			n++
+		}
+	}
+	return n
+}
+
+// applyTensor replaces the tensor of name by t or q of the delta, or only the
+// rows of ids if present, which keep the data type of the table. The table
+// grows by the added records of its vocabulary at most.
+func (m *Params) applyTensor(name string, ids, t *tensor.Dense, q *quantizedTable, added int) error {
+	if ids == nil {
+		delete(m.tensors, name)
+		delete(m.quantized, name)
+		delete(m.chunked, name)
+		if q != nil {
+			m.quantized[name] = q
+		} else {
+			m.tensors[name] = t
+		}
+		return nil
+	}
+	table, err := m.chunkedTable(name)
+	if err != nil {
+		return err
+	}
+	index, ok := ids.Data().([]int32)
+	if !ok || ids.Dims() != 1 {
+		return fmt.Errorf("%w: ids of %s are %v%v, expected 1-D int32", InvalidDeltaErr, name, ids.Dtype(), ids.Shape())
+	}
+	rows, n, err := table.convert(name, t, q)
+	if err != nil {
+		return err
+	}
+	if n != len(index) {
+		return fmt.Errorf("%w: %d ids of %d rows of %s", InvalidDeltaErr, len(index), n, name)
+	}
+	updated, err := table.update(name, index, rows, table.rows+added)
+	if err != nil {
+		return err
+	}
+	delete(m.tensors, name)
+	delete(m.quantized, name)
+	m.chunked[name] = updated
+	return nil
+}
+
+// chunkedTable returns the table of name split into chunks, without copying rows.
+func (m *Params) chunkedTable(name string) (*chunkedTable, error) {
+	if c, ok := m.chunked[name]; ok {
+		return c, nil
+	}
+	if q, ok := m.quantized[name]; ok {
+		c := &chunkedTable{dtype: q.dtype, rows: q.rows, dim: q.dim}
+		n := rowSize(q.dtype, q.dim)
+		for i := 0; i < q.rows; i += chunkRows {
+			end := min(i+chunkRows, q.rows)
+			ch := tableChunk{data: q.data[i*n : end*n : end*n]}
+			if q.dtype == proto.DataType_DT_INT8 {
+				ch.scales = q.scales[i:end:end]
+			}
+			c.chunks = append(c.chunks, ch)
+		}
+		return c, nil
+	}
+	t, ok := m.tensors[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", TensorNotFoundErr, name)
+	}
+	values, ok := t.Data().([]float32)
+	if !ok || t.Dims() != 2 {
+		return nil, fmt.Errorf("%w: %s is %v%v, expected 2-D float32", UnsupportedDtypeErr, name, t.Dtype(), t.Shape())
+	}
+	c := &chunkedTable{dtype: proto.DataType_DT_FLOAT, rows: t.Shape()[0], dim: t.Shape()[1]}
+	for i := 0; i < c.rows; i += chunkRows {
+		end := min(i+chunkRows, c.rows)
+		c.chunks = append(c.chunks, tableChunk{values: values[i*c.dim : end*c.dim : end*c.dim]})
+	}
+	return c, nil
+}
+
+// tableChunk holds consecutive rows of a chunkedTable, float32 values or the
+// bytes and scales of quantized rows.
+type tableChunk struct {
+	values []float32
+	data   []byte
+	scales []float32
+}
+
+// chunkedTable is an embedding table updated by delta files. The chunks are
+// shared with the table it's updated from, only the chunks of updated rows
+// are copied.
+type chunkedTable struct {
+	dtype  proto.DataType // DT_FLOAT, DT_INT8 or DT_HALF
+	rows   int
+	dim    int
+	chunks []tableChunk // chunkRows rows each, except the last one
+}
+
+func (c *chunkedTable) newChunk(rows int) tableChunk {
+	switch c.dtype {
+	case proto.DataType_DT_FLOAT:
+		return tableChunk{values: make([]float32, rows*c.dim)}
+	case proto.DataType_DT_INT8:
+		return tableChunk{data: make([]byte, rows*c.dim), scales: make([]float32, rows)}
+	default:
+		return tableChunk{data: make([]byte, rows*rowSize(c.dtype, c.dim))}
+	}
+}
+
+// chunkLen returns the rows of the i-th chunk of a table of rows.
+func chunkLen(rows, i int) int {
+	return max(min(rows-i*chunkRows, chunkRows), 0)
+}
+
+// row copies the i-th row into dst, which has length dim.
+func (c *chunkedTable) row(i int, dst []float32) {
+	ch, j := c.chunks[i/chunkRows], i%chunkRows
+	if c.dtype == proto.DataType_DT_FLOAT {
+		copy(dst, ch.values[j*c.dim:(j+1)*c.dim])
+		return
+	}
+	var scale float32
+	if ch.scales != nil {
+		scale = ch.scales[j]
+	}
+	n := rowSize(c.dtype, c.dim)
+	dequantizeRow(c.dtype, ch.data[j*n:(j+1)*n], scale, dst)
+}
+
+// setRow copies the k-th row of src into the j-th row of dst.
+func (c *chunkedTable) setRow(dst tableChunk, j int, src tableChunk, k int) {
+	if c.dtype == proto.DataType_DT_FLOAT {
+		copy(dst.values[j*c.dim:(j+1)*c.dim], src.values[k*c.dim:(k+1)*c.dim])
+		return
+	}
+	n := rowSize(c.dtype, c.dim)
+	copy(dst.data[j*n:(j+1)*n], src.data[k*n:(k+1)*n])
+	if dst.scales != nil {
+		dst.scales[j] = src.scales[k]
+	}
+}
+
+// convert returns the rows of a delta, dense t or quantized q, in the data
+// type of c and the number of them.
+func (c *chunkedTable) convert(name string, t *tensor.Dense, q *quantizedTable) (tableChunk, int, error) {
+	if q != nil {
+		if q.dtype == c.dtype && q.dim == c.dim {
+			return tableChunk{data: q.data, scales: q.scales}, q.rows, nil
+		}
+		t = q.dense()
+	}
+	values, ok := t.Data().([]float32)
+	if !ok || t.Dims() != 2 || t.Shape()[1] != c.dim {
+		return tableChunk{}, 0, fmt.Errorf("%w: rows of %s are %v%v, expected [n, %d] float32",
+			InvalidDeltaErr, name, t.Dtype(), t.Shape(), c.dim)
+	}
+	if c.dtype == proto.DataType_DT_FLOAT {
+		return tableChunk{values: values}, t.Shape()[0], nil
+	}
+	pt, err := Quantize(t, c.dtype)
+	if err != nil {
+		return tableChunk{}, 0, fmt.Errorf("%s: %w", name, err)
+	}
+	return tableChunk{data: pt.ByteVal, scales: pt.Scale}, t.Shape()[0], nil
+}
+
+// update returns a copy of c whose rows of ids are replaced by rows, the
+// table grows if ids are beyond its rows, up to limit rows. Only the chunks
+// of ids and the grown last chunk are copied.
+func (c *chunkedTable) update(name string, ids []int32, rows tableChunk, limit int) (*chunkedTable, error) {
+	n := c.rows
+	for _, id := range ids {
+		if id < 0 || int(id) >= limit {
+			return nil, fmt.Errorf("%w: %s[%d] beyond %d rows", InvalidDeltaErr, name, id, limit)
+		}
+		n = max(n, int(id)+1)
+	}
+	u := &chunkedTable{dtype: c.dtype, rows: n, dim: c.dim, chunks: make([]tableChunk, (n+chunkRows-1)/chunkRows)}
+	copied := make([]bool, len(u.chunks))
+	for i := range u.chunks {
+		if i < len(c.chunks) && chunkLen(n, i) == chunkLen(c.rows, i) {
+			u.chunks[i] = c.chunks[i]
+			continue
+		}
+		u.chunks[i], copied[i] = u.clone(c, i), true
+	}
+	for k, id := range ids {
+		i := int(id) / chunkRows
+		if !copied[i] {
+			u.chunks[i], copied[i] = u.clone(c, i), true
+		}
+		u.setRow(u.chunks[i], int(id)%chunkRows, rows, k)
+	}
+	return u, nil
+}
+
+// clone returns a copy of the i-th chunk of from, sized for the rows of c.
+func (c *chunkedTable) clone(from *chunkedTable, i int) tableChunk {
+	ch := c.newChunk(chunkLen(c.rows, i))
+	if i < len(from.chunks) {
+		copy(ch.values, from.chunks[i].values)
+		copy(ch.data, from.chunks[i].data)
+		copy(ch.scales, from.chunks[i].scales)
+	}
+	return ch
+}
+
+// dense copies the whole table into a float32 tensor.
+func (c *chunkedTable) dense() *tensor.Dense {
+	backing := make([]float32, c.rows*c.dim)
+	for i := 0; i < c.rows; i++ {
+		c.row(i, backing[i*c.dim:(i+1)*c.dim])
+	}
+	return tensor.New(tensor.WithBacking(backing), tensor.WithShape(c.rows, c.dim))
+}
+
+// proto returns the table as a tensor of its data type.
+func (c *chunkedTable) proto() (*proto.Tensor, error) {
+	if c.dtype == proto.DataType_DT_FLOAT {
+		return Encode(c.dense())
+	}
+	q := &quantizedTable{dtype: c.dtype, rows: c.rows, dim: c.dim}
+	for _, ch := range c.chunks {
+		q.data = append(q.data, ch.data...)
+		q.scales = append(q.scales, ch.scales...)
+	}
+	return q.proto(), nil
+}
+
+// size returns the bytes of the rows.
+func (c *chunkedTable) size() int64 {
+	var size int64
+	for _, ch := range c.chunks {
+		size += int64(4*len(ch.values) + len(ch.data) + 4*len(ch.scales))
+	}
+	return size
+}
@@ -0,0 +1,258 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/26 16:20
+ */
+
+package params
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+func writeParams(t *testing.T, w *Writer) *Params {
+	t.Helper()
+	file := filepath.Join(t.TempDir(), "params")
+	if err := w.WriteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	m := New(file)
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func newBase(t *testing.T, rows int) *Params {
+	t.Helper()
+	w := NewWriter("m", 100)
+	if err := w.AddDense("F1", newTable(rows, 2)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.AddQuantized("F2", newTable(rows, 2), proto.DataType_DT_INT8); err != nil {
+		t.Fatal(err)
+	}
+	w.AddField(&proto.Field{Name: "F1", Dim: 2, Records: map[string]int64{"a": 0, "b": 1}})
+	return writeParams(t, w)
+}
+
+func rowsOf(values ...float32) *tensor.Dense {
+	return tensor.New(tensor.WithBacking(values), tensor.WithShape(len(values)/2, 2))
+}
+
+func lookup(t *testing.T, m *Params, name string, index ...int) []float32 {
+	t.Helper()
+	out, err := m.EmbeddingLookup(name, index)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out.Data().([]float32)
+}
+
+func TestApplyDeltaErrors(t *testing.T) {
+	base := newBase(t, 4)
+	tests := []struct {
+		name  string
+		delta *Writer
+		err   error
+	}{
+		{"full file", NewWriter("m", 200), NotDeltaErr},
+		{"base mismatch", NewDeltaWriter("m", 200, 99), DeltaBaseErr},
+		{"older version", NewDeltaWriter("m", 100, 100), DeltaVersionErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := New("").ApplyDelta(base, writeParams(t, tt.delta)); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestApplyDeltaVocabulary(t *testing.T) {
+	base := newBase(t, 4)
+	w := NewDeltaWriter("m", 200, 100)
+	w.AddField(&proto.Field{Name: "F1", Dim: 2, Records: map[string]int64{"c": 2}})
+	w.AddField(&proto.Field{Name: "F3", Dim: 2, Records: map[string]int64{"x": 0}})
+	m := New("")
+	if err := m.ApplyDelta(base, writeParams(t, w)); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.IndexLookup("F1", "a", "a", "b", "c"); !reflect.DeepEqual(got, Index{0, 1, 2}) {
+		t.Errorf("F1: got %v", got)
+	}
+	if got := m.IndexLookup("F3", "x", "x"); !reflect.DeepEqual(got, Index{0}) {
+		t.Errorf("F3: got %v", got)
+	}
+	if f, _ := base.GetField("F1"); len(f.Records) != 2 {
+		t.Errorf("base F1 is changed: %v", f.Records)
+	}
+	if _, ok := base.GetField("F3"); ok {
+		t.Error("base F3 is added")
+	}
+	if m.Version() != 200 || m.BaseVersion() != 100 || !reflect.DeepEqual(m.Deltas(), []uint64{200}) {
+		t.Errorf("versions: got %d base %d deltas %v", m.Version(), m.BaseVersion(), m.Deltas())
+	}
+}
+
+func TestApplyDeltaRows(t *testing.T) {
+	const rows = 3*chunkRows - 10
+	base := newBase(t, rows)
+	w := NewDeltaWriter("m", 200, 100)
+	if err := w.AddRows("F1", []int{5, rows + 1}, rowsOf(-1, -2, -3, -4)); err != nil {
+		t.Fatal(err)
+	}
+	w.AddField(&proto.Field{Name: "F1", Dim: 2, Records: map[string]int64{"x": rows, "y": rows + 1}})
+	first := New("")
+	if err := first.ApplyDelta(base, writeParams(t, w)); err != nil {
+		t.Fatal(err)
+	}
+	w = NewDeltaWriter("m", 300, 100)
+	if err := w.AddRows("F1", []int{6}, rowsOf(-5, -6)); err != nil {
+		t.Fatal(err)
+	}
+	second := New("")
+	if err := second.ApplyDelta(first, writeParams(t, w)); err != nil {
+		t.Fatal(err)
+	}
+
+	// readers of the old snapshots still see their versions
+	if got := lookup(t, base, "F1", 5, 6); !reflect.DeepEqual(got, []float32{10, 11, 12, 13}) {
+		t.Errorf("base: got %v", got)
+	}
+	if got := base.GetTensor("F1").Shape(); !got.Eq(tensor.Shape{rows, 2}) {
+		t.Errorf("base: got shape %v", got)
+	}
+	if got := lookup(t, first, "F1", 5, 6, rows, rows+1); !reflect.DeepEqual(got, []float32{-1, -2, 12, 13, 0, 0, -3, -4}) {
+		t.Errorf("first: got %v", got)
+	}
+	if got := lookup(t, second, "F1", 5, 6, rows+1); !reflect.DeepEqual(got, []float32{-1, -2, -5, -6, -3, -4}) {
+		t.Errorf("second: got %v", got)
+	}
+	if got := second.GetTensor("F1").Shape(); !got.Eq(tensor.Shape{rows + 2, 2}) {
+		t.Errorf("second: got shape %v", got)
+	}
+	if got, _ := second.Shape("F1"); !got.Eq(tensor.Shape{rows + 2, 2}) {
+		t.Errorf("second: got stored shape %v", got)
+	}
+	if _, err := second.EmbeddingLookup("F1", Index{rows + 2}); !errors.Is(err, IndexOutOfRangeErr) {
+		t.Errorf("second: got %v, want %v", err, IndexOutOfRangeErr)
+	}
+
+	// only the updated chunks are copied
+	c1, c2 := first.chunked["F1"], second.chunked["F1"]
+	if &c1.chunks[1].values[0] != &base.tensors["F1"].Data().([]float32)[chunkRows*2] {
+		t.Error("first: untouched chunk is copied")
+	}
+	if &c2.chunks[1].values[0] != &c1.chunks[1].values[0] || &c2.chunks[2].values[0] != &c1.chunks[2].values[0] {
+		t.Error("second: untouched chunks are copied")
+	}
+	if &c2.chunks[0].values[0] == &c1.chunks[0].values[0] {
+		t.Error("second: updated chunk is shared")
+	}
+}
+
+func TestApplyDeltaRowsBeyondVocabulary(t *testing.T) {
+	const rows = 4
+	base := newBase(t, rows)
+	tests := []struct {
+		name    string
+		ids     []int
+		records map[string]int64
+		err     error
+	}{
+		{"new records", []int{0, rows + 1}, map[string]int64{"c": rows, "d": rows + 1}, nil},
+		{"no records", []int{rows}, nil, InvalidDeltaErr},
+		{"beyond records", []int{rows + 1}, map[string]int64{"c": rows}, InvalidDeltaErr},
+		{"existing records", []int{rows}, map[string]int64{"a": 0, "b": 1}, InvalidDeltaErr},
+		{"negative", []int{-1}, nil, InvalidDeltaErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := NewDeltaWriter("m", 200, 100)
+			values := make([]float32, 2*len(tt.ids))
+			if err := w.AddRows("F1", tt.ids, rowsOf(values...)); err != nil {
+				t.Fatal(err)
+			}
+			if tt.records != nil {
+				w.AddField(&proto.Field{Name: "F1", Dim: 2, Records: tt.records})
+			}
+			if err := New("").ApplyDelta(base, writeParams(t, w)); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestApplyDeltaLayersVocabulary(t *testing.T) {
+	base := newBase(t, 4)
+	m := base
+	for i, records := range []map[string]int64{{"c": 2}, {"d": 3, "a": 3}} {
+		w := NewDeltaWriter("m", uint64(200+i), 100)
+		w.AddField(&proto.Field{Name: "F1", Dim: 2, Records: records})
+		next := New("")
+		if err := next.ApplyDelta(m, writeParams(t, w)); err != nil {
+			t.Fatal(err)
+		}
+		m = next
+	}
+	// the latest delta overrides the former ones and base
+	if got := m.IndexLookup("F1", "b", "a", "b", "c", "d", "z"); !reflect.DeepEqual(got, Index{3, 1, 2, 3, 1}) {
+		t.Errorf("got %v", got)
+	}
+	want := map[string]int64{"a": 3, "b": 1, "c": 2, "d": 3}
+	if f, _ := m.GetField("F1"); !reflect.DeepEqual(f.Records, want) {
+		t.Errorf("got records %v, want %v", f.Records, want)
+	}
+	// base is shared, not copied
+	if m.index["F1"] != base.index["F1"] || len(base.index["F1"].Records) != 2 {
+		t.Error("base F1 is copied or changed")
+	}
+}
+
+func TestApplyDeltaQuantized(t *testing.T) {
+	base := newBase(t, 4)
+	q, err := Quantize(rowsOf(1, -1), proto.DataType_DT_INT8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := NewDeltaWriter("m", 200, 100)
+	if err := w.AddQuantizedRows("F2", []int{1}, q); err != nil {
+		t.Fatal(err)
+	}
+	m := New("")
+	if err := m.ApplyDelta(base, writeParams(t, w)); err != nil {
+		t.Fatal(err)
+	}
+	if m.StorageType("F2") != proto.DataType_DT_INT8 {
+		t.Fatalf("got %v, want DT_INT8", m.StorageType("F2"))
+	}
+	got := lookup(t, m, "F2", 1)
+	if want := []float32{1, -1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if stored, ok := m.Quantized("F2"); !ok || stored.Dtype != proto.DataType_DT_INT8 || len(stored.ByteVal) != 8 {
+		t.Errorf("stored: got %v", stored)
+	}
+
+	// float rows of a quantized table are quantized to it
+	w = NewDeltaWriter("m", 300, 100)
+	if err := w.AddRows("F2", []int{0}, rowsOf(4, -4)); err != nil {
+		t.Fatal(err)
+	}
+	next := New("")
+	if err := next.ApplyDelta(m, writeParams(t, w)); err != nil {
+		t.Fatal(err)
+	}
+	if next.StorageType("F2") != proto.DataType_DT_INT8 {
+		t.Fatalf("got %v, want DT_INT8", next.StorageType("F2"))
+	}
+	if got := lookup(t, next, "F2", 0, 1); !reflect.DeepEqual(got, []float32{4, -4, 1, -1}) {
+		t.Errorf("got %v", got)
+	}
+}
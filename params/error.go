@@ -13,4 +13,18 @@ var (
 	DataInvalidLengthErr   = errors.New("invalid data length")
 	IndexInvalidLengthErr  = errors.New("invalid index length")
 	InvalidMagicErr        = errors.New("invalid magic number")
+	UnsupportedFormatErr   = errors.New("unsupported format version")
+	HeaderChecksumErr      = errors.New("header checksum mismatch")
+	DataChecksumErr        = errors.New("data checksum mismatch")
+	IndexChecksumErr       = errors.New("index checksum mismatch")
+	UnsupportedDtypeErr    = errors.New("unsupported dtype")
+	InvalidTensorErr       = errors.New("shape mismatches the values")
+	InvalidQuantizedErr    = errors.New("invalid quantized table")
+	TensorNotFoundErr      = errors.New("tensor not found")
+	IndexOutOfRangeErr     = errors.New("index out of range")
+	EmptyIndexErr          = errors.New("empty index")
+	NotDeltaErr            = errors.New("not a delta file")
+	DeltaBaseErr           = errors.New("delta of another base version")
+	DeltaVersionErr        = errors.New("delta not newer than the params")
+	InvalidDeltaErr        = errors.New("invalid delta")
 )
@@ -0,0 +1,71 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/26 15:02
+ */
+
+package params
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+// testdata/1650000000.pb is written by testdata/golden.py with the python exporter.
+func TestLoadPythonGolden(t *testing.T) {
+	m := New("testdata/1650000000.pb")
+	if err := m.Load(); err != nil {
+		t.Fatal(err)
+	}
+	defer m.Close()
+
+	if m.ModelName() != "golden" || m.Version() != 1650000000 || m.FormatVersion() != FormatVersion {
+		t.Fatalf("header: got %s %d format %d", m.ModelName(), m.Version(), m.FormatVersion())
+	}
+
+	tensors := map[string]struct {
+		shape tensor.Shape
+		data  []float32
+	}{
+		"age":          {tensor.Shape{3, 2}, []float32{0.5, 1, -1.25, 0, 2, -2}},
+		"city":         {tensor.Shape{3, 2}, []float32{1, 2, 3, 4, 0, -0.5}},
+		"bias_weights": {tensor.Shape{1}, []float32{0.25}},
+	}
+	if names := m.TensorNames(); len(names) != len(tensors) {
+		t.Fatalf("tensors: got %v", names)
+	}
+	for name, want := range tensors {
+		got := m.GetTensor(name)
+		if got == nil {
+			t.Fatalf("%s: not found", name)
+		}
+		if m.StorageType(name) != proto.DataType_DT_FLOAT {
+			t.Errorf("%s: got dtype %v", name, m.StorageType(name))
+		}
+		if !got.Shape().Eq(want.shape) || !reflect.DeepEqual(got.Data(), want.data) {
+			t.Errorf("%s: got %v %v, want %v %v", name, got.Shape(), got.Data(), want.shape, want.data)
+		}
+	}
+
+	vocabularies := map[string]map[string]int64{
+		"age":  {"0": 0, "1": 1, "2": 2},
+		"city": {"beijing": 0, "shanghai": 1, "__oov__": 2},
+	}
+	if names := m.FieldNames(); len(names) != len(vocabularies) {
+		t.Fatalf("fields: got %v", names)
+	}
+	for name, want := range vocabularies {
+		field, ok := m.GetField(name)
+		if !ok {
+			t.Fatalf("%s: field not found", name)
+		}
+		if field.Dim != 2 || !reflect.DeepEqual(field.Records, want) {
+			t.Errorf("%s: got dim %d %v, want %v", name, field.Dim, field.Records, want)
+		}
+	}
+	if got := m.IndexLookup("city", "__oov__", "shanghai", "hangzhou"); !reflect.DeepEqual(got, Index{1, 2}) {
+		t.Errorf("city lookup: got %v", got)
+	}
+}
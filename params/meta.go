@@ -8,33 +8,53 @@ package params
 import (
 	"encoding/binary"
 	"fmt"
-	"log"
+	"hash/crc32"
+	"io"
 	"os"
+	"sort"
 
 	"github.com/yinyajun/go-serving/proto"
 	"gorgonia.org/tensor"
 )
 
 const (
-	FooterSize = 30
-	Magic      = "go_serving"
+	// LegacyFooterSize is the footer size of format version 0, which only
+	// holds the two offsets and the magic number.
+	LegacyFooterSize = 30
+	// FooterSize is the footer size of the current format version:
+	// dataOffset(10) | indexOffset(10) | headerCRC(4) | dataCRC(4) | indexCRC(4) | version(1) | magic(10)
+	FooterSize    = 43
+	FormatVersion = 1
+	Magic         = "go_serving"
 )
 
+// castagnoli is the CRC32C table used for section checksums.
+var castagnoli = crc32.MakeTable(crc32.Castagnoli)
+
+// Checksum returns the CRC32C checksum of a section.
+func Checksum(buf []byte) uint32 {
+	return crc32.Checksum(buf, castagnoli)
+}
+
 type Index []int
 
+// Meta is the params looked up by models, see Composite to assemble it from
+// several stores.
 type Meta interface {
-	IndexLookup(fieldName, defaultFeat string, featNames ...string) Index
-	EmbeddingLookup(fieldName string, index Index) tensor.Tensor
-	GetTensor(fieldName string) tensor.Tensor
+	VocabularyStore
+	EmbeddingStore
+	TensorStore
 }
 
-type stat struct {
-	modelName  string
-	version    uint64
-	headSize   int64
-	dataSize   int64
-	indexSize  int64
-	footerSize int64
+type Stat struct {
+	ModelName     string
+	Version       uint64
+	BaseVersion   uint64 // of the full file a delta file updates, 0 for full files
+	FormatVersion uint8
+	HeadSize      int64
+	DataSize      int64
+	IndexSize     int64
+	FooterSize    int64
 }
 
 type header struct {
@@ -43,55 +63,101 @@ type header struct {
 }
 
 type footer struct {
-	dataOffset  uint64 `validate:"required,len=10"`
-	indexOffset uint64 `validate:"required,len=10"`
-	magicNumber []byte `validate:"required,len=10"`
+	dataOffset    uint64 `validate:"required,len=10"`
+	indexOffset   uint64 `validate:"required,len=10"`
+	headerCRC     uint32
+	dataCRC       uint32
+	indexCRC      uint32
+	formatVersion uint8
+	magicNumber   []byte `validate:"required,len=10"`
 }
 
 type Params struct {
-	file    string
-	header  header
-	footer  footer
-	tensors map[string]*tensor.Dense
-	index   map[string]*proto.Field
-	stat    stat
+	file      string
+	header    header
+	footer    footer
+	tensors   map[string]*tensor.Dense
+	quantized map[string]*quantizedTable
+	chunked   map[string]*chunkedTable // tables updated by deltas
+	index     map[string]*proto.Field
+	overlays  map[string][]map[string]int64 // records added to index by deltas, the latest last
+	stat      Stat
+	base      uint64   // version of the full file, 0 if it's the loaded one
+	deltas    []uint64 // versions of the applied delta files
 }
 
 func New(file string) *Params {
 	m := &Params{
-		file:    file,
-		tensors: make(map[string]*tensor.Dense),
-		index:   make(map[string]*proto.Field),
-		stat:    stat{},
+		file:      file,
+		tensors:   make(map[string]*tensor.Dense),
+		quantized: make(map[string]*quantizedTable),
+		chunked:   make(map[string]*chunkedTable),
+		index:     make(map[string]*proto.Field),
+		stat:      Stat{},
 	}
 	return m
 }
 
+// footerSize returns the footer size of the given format version. The byte
+// before the magic number is the format version; in legacy files it is the
+// zero padding of indexOffset.
+func footerSize(formatVersion uint8) (int64, error) {
+	switch formatVersion {
+	case 0:
+		return LegacyFooterSize, nil
+	case FormatVersion:
+		return FooterSize, nil
+	default:
+		return 0, UnsupportedFormatErr
+	}
+}
+
 func (m *Params) parseFooter(buf []byte) error {
-	if len(buf) != FooterSize {
+	if len(buf) != LegacyFooterSize && len(buf) != FooterSize {
 		return FooterInvalidLengthErr
 	}
 
 	footer := footer{}
 	footer.dataOffset, _ = binary.Uvarint(buf[:10])
 	footer.indexOffset, _ = binary.Uvarint(buf[10:20])
-	footer.magicNumber = buf[20:30]
+	footer.magicNumber = buf[len(buf)-10:]
 	if string(footer.magicNumber) != Magic {
 		return InvalidMagicErr
 	}
+	if len(buf) == FooterSize {
+		footer.headerCRC = binary.LittleEndian.Uint32(buf[20:24])
+		footer.dataCRC = binary.LittleEndian.Uint32(buf[24:28])
+		footer.indexCRC = binary.LittleEndian.Uint32(buf[28:32])
+		footer.formatVersion = buf[32]
+	}
 	m.footer = footer
-	m.stat.footerSize = int64(len(buf))
+	m.stat.FooterSize = int64(len(buf))
+	m.stat.FormatVersion = footer.formatVersion
+	return nil
+}
+
+// verify checks the checksum of a section, legacy files have no checksums.
+func (m *Params) verify(buf []byte, crc uint32, err error) error {
+	if m.footer.formatVersion == 0 {
+		return nil
+	}
+	if Checksum(buf) != crc {
+		return err
+	}
 	return nil
 }
 
 func (m *Params) parseHeader(buf []byte) error {
+	if len(buf) < 10 {
+		return HeaderInvalidLengthErr
+	}
 	header := header{}
 	header.modelName = string(buf[:len(buf)-10])
 	header.version, _ = binary.Uvarint(buf[len(buf)-10 : len(buf)])
 	m.header = header
-	m.stat.headSize = int64(len(buf))
-	m.stat.modelName = header.modelName
-	m.stat.version = header.version
+	m.stat.HeadSize = int64(len(buf))
+	m.stat.ModelName = header.modelName
+	m.stat.Version = header.version
 	return nil
 }
 
@@ -102,9 +168,24 @@ func (m *Params) parseData(buf []byte) error {
 	}
 
 	for k, v := range data.Data {
-		m.tensors[k] = decode(v)
+		switch v.GetDtype() {
+		case proto.DataType_DT_INT8, proto.DataType_DT_HALF:
+			q, err := newQuantizedTable(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			m.quantized[k] = q
+		default:
+			t, err := DecodeChecked(v)
+			if err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			m.tensors[k] = t
+		}
 	}
-	m.stat.dataSize = int64(len(buf))
+	m.base = data.BaseVersion
+	m.stat.BaseVersion = data.BaseVersion
+	m.stat.DataSize = int64(len(buf))
 	return nil
 }
 
@@ -114,7 +195,7 @@ func (m *Params) parseIndex(buf []byte) error {
 		return err
 	}
 	m.index = index.Embeddings
-	m.stat.indexSize = int64(len(buf))
+	m.stat.IndexSize = int64(len(buf))
 	return nil
 }
 
@@ -129,59 +210,92 @@ func (m *Params) Load() (err error) {
 	if err != nil {
 		return err
 	}
-	// footer
-	footerOffset := stat.Size() - FooterSize
-	buf := make([]byte, FooterSize)
-	n, err := f.ReadAt(buf, footerOffset)
+	return m.LoadFrom(f, stat.Size())
+}
+
+// LoadFrom loads the model file of fileSize bytes from r, e.g. a remote object.
+func (m *Params) LoadFrom(r io.ReaderAt, fileSize int64) error {
+	// format version
+	if fileSize < LegacyFooterSize {
+		return FooterInvalidLengthErr
+	}
+	buf := make([]byte, 1)
+	if err := readAt(r, buf, fileSize-int64(len(Magic))-1, FooterInvalidLengthErr); err != nil {
+		return err
+	}
+	size, err := footerSize(buf[0])
 	if err != nil {
 		return err
 	}
-	if n != FooterSize {
+	if fileSize < size {
 		return FooterInvalidLengthErr
 	}
+	// footer
+	footerOffset := fileSize - size
+	buf = make([]byte, size)
+	if err := readAt(r, buf, footerOffset, FooterInvalidLengthErr); err != nil {
+		return err
+	}
 	if err := m.parseFooter(buf); err != nil {
 		return err
 	}
+	if m.footer.dataOffset > m.footer.indexOffset || int64(m.footer.indexOffset) > footerOffset {
+		return IndexInvalidLengthErr
+	}
 
 	// header
 	buf = make([]byte, m.footer.dataOffset)
-	n, err = f.ReadAt(buf, 0)
-	if err != nil {
+	if err := readAt(r, buf, 0, HeaderInvalidLengthErr); err != nil {
 		return err
 	}
-	if n != int(m.footer.dataOffset) {
-		return HeaderInvalidLengthErr
+	if err := m.verify(buf, m.footer.headerCRC, HeaderChecksumErr); err != nil {
+		return err
 	}
 	if err := m.parseHeader(buf); err != nil {
 		return err
 	}
 	// data
 	buf = make([]byte, m.footer.indexOffset-m.footer.dataOffset)
-	// todo: int64 overflow?
-	n, err = f.ReadAt(buf, int64(m.footer.dataOffset))
-	if err != nil {
+	if err := readAt(r, buf, int64(m.footer.dataOffset), DataInvalidLengthErr); err != nil {
 		return err
 	}
-	if n != int(m.footer.indexOffset-m.footer.dataOffset) {
-		return DataInvalidLengthErr
+	if err := m.verify(buf, m.footer.dataCRC, DataChecksumErr); err != nil {
+		return err
 	}
 	if err := m.parseData(buf); err != nil {
 		return err
 	}
 	// index
 	buf = make([]byte, footerOffset-int64(m.footer.indexOffset))
-	n, err = f.ReadAt(buf, int64(m.footer.indexOffset))
-	if err != nil {
+	if err := readAt(r, buf, int64(m.footer.indexOffset), IndexInvalidLengthErr); err != nil {
 		return err
 	}
-	if n != int(footerOffset-int64(m.footer.indexOffset)) {
-		return IndexInvalidLengthErr
+	if err := m.verify(buf, m.footer.indexCRC, IndexChecksumErr); err != nil {
+		return err
+	}
+	return m.parseIndex(buf)
+}
+
+// readAt reads len(buf) bytes at off, it returns short if r has less.
+func readAt(r io.ReaderAt, buf []byte, off int64, short error) error {
+	n, err := r.ReadAt(buf, off)
+	if n == len(buf) {
+		return nil
 	}
-	if err := m.parseIndex(buf); err != nil {
+	if err != nil && err != io.EOF {
 		return err
 	}
-	log.Printf("Load %s (%v) OK!", m.file, m.stat)
-	return
+	return short
+}
+
+// Close releases the tensors, the params can't be looked up any more.
+func (m *Params) Close() error {
+	m.tensors = make(map[string]*tensor.Dense)
+	m.quantized = make(map[string]*quantizedTable)
+	m.chunked = make(map[string]*chunkedTable)
+	m.index = make(map[string]*proto.Field)
+	m.overlays = nil
+	return nil
 }
 
 func (m *Params) ModelName() string {
@@ -192,16 +306,111 @@ func (m *Params) Version() uint64 {
 	return m.header.version
 }
 
-func (m *Params) EmbeddingLookup(fieldName string, index Index) tensor.Tensor {
-	tt := m.tensors[fieldName]
-	// lookup embedding depends on indices
-	tensors := make([]tensor.Tensor, len(index))
-	for i := 0; i < len(index); i++ {
-		view, _ := tt.Slice(tensor.S(index[i]))
-		tensors[i] = view.Materialize().(*tensor.Dense)
+// BaseVersion returns the version of the full file, which differs from Version
+// after delta files are applied.
+func (m *Params) BaseVersion() uint64 {
+	if m.base == 0 {
+		return m.Version()
+	}
+	return m.base
+}
+
+// Deltas returns the versions of the applied delta files.
+func (m *Params) Deltas() []uint64 {
+	return m.deltas
+}
+
+// IsDelta reports whether the params are loaded from a delta file, which can
+// only be applied to its base by ApplyDelta.
+func (m *Params) IsDelta() bool {
+	return m.base != 0 && len(m.deltas) == 0
+}
+
+func (m *Params) FormatVersion() uint8 {
+	return m.footer.formatVersion
+}
+
+func (m *Params) Stat() Stat {
+	return m.stat
+}
+
+// MemorySize estimates the bytes held by tensors and vocabularies.
+func (m *Params) MemorySize() int64 {
+	var size int64
+	for _, t := range m.tensors {
+		switch data := t.Data().(type) {
+		case []string:
+			for _, s := range data {
+				size += int64(len(s))
+			}
+		default:
+			size += int64(t.MemSize())
+		}
+	}
+	for _, q := range m.quantized {
+		size += int64(len(q.data) + 4*len(q.scales))
+	}
+	for _, c := range m.chunked {
+		size += c.size()
+	}
+	for _, f := range m.index {
+		for k := range f.Records {
+			size += int64(len(k)) + 8
+		}
+	}
+	for _, overlays := range m.overlays {
+		for _, records := range overlays {
+			for k := range records {
+				size += int64(len(k)) + 8
+			}
+		}
+	}
+	return size
+}
+
+// EmbeddingLookup gathers the rows of index from the embedding table of fieldName
+// into one [len(index), dim] tensor.
+func (m *Params) EmbeddingLookup(fieldName string, index Index) (tensor.Tensor, error) {
+	if len(index) == 0 {
+		return nil, EmptyIndexErr
+	}
+	if q, ok := m.quantized[fieldName]; ok {
+		// dequantize the looked-up rows only
+		return gatherRows(fieldName, index, q.rows, q.dim, q.row)
+	}
+	if c, ok := m.chunked[fieldName]; ok {
+		return gatherRows(fieldName, index, c.rows, c.dim, c.row)
+	}
+
+	tt, ok := m.tensors[fieldName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", TensorNotFoundErr, fieldName)
+	}
+	table, ok := tt.Data().([]float32)
+	if !ok || tt.Dims() != 2 {
+		return nil, fmt.Errorf("%w: %s is %v%v, expected 2-D float32", UnsupportedDtypeErr, fieldName, tt.Dtype(), tt.Shape())
+	}
+	rows, dim := tt.Shape()[0], tt.Shape()[1]
+	backing := make([]float32, len(index)*dim)
+	for i, idx := range index {
+		if idx < 0 || idx >= rows {
+			return nil, fmt.Errorf("%w: %s[%d], rows %d", IndexOutOfRangeErr, fieldName, idx, rows)
+		}
+		copy(backing[i*dim:(i+1)*dim], table[idx*dim:(idx+1)*dim])
+	}
+	return tensor.New(tensor.WithBacking(backing), tensor.WithShape(len(index), dim)), nil
+}
+
+// gatherRows gathers the rows of index by row, which copies a row into dst.
+func gatherRows(fieldName string, index Index, rows, dim int, row func(i int, dst []float32)) (tensor.Tensor, error) {
+	backing := make([]float32, len(index)*dim)
+	for i, idx := range index {
+		if idx < 0 || idx >= rows {
+			return nil, fmt.Errorf("%w: %s[%d], rows %d", IndexOutOfRangeErr, fieldName, idx, rows)
+		}
+		row(idx, backing[i*dim:(i+1)*dim])
 	}
-	res, _ := tensor.Stack(0, tensors[0], tensors[1:]...)
-	return res
+	return tensor.New(tensor.WithBacking(backing), tensor.WithShape(len(index), dim)), nil
 }
 
 func (m *Params) IndexLookup(fieldName, defaultFeat string, featNames ...string) Index {
@@ -214,22 +423,155 @@ func (m *Params) IndexLookup(fieldName, defaultFeat string, featNames ...string)
 		ok             bool
 	)
 	for i := 0; i < len(indices); i++ {
-		featIdx, ok = ii.Records[featNames[i]]
+		featIdx, ok = m.record(fieldName, ii, featNames[i])
 		if ok {
 			indices[i] = int(featIdx)
 			continue
 		}
 		// find default featName in Field
 		if defaultFeatIdx == -1 {
-			defaultFeatIdx, ok = ii.Records[defaultFeat] // after check, defaultFeatName is definitely exists
+			defaultFeatIdx, ok = m.record(fieldName, ii, defaultFeat) // after check, defaultFeatName is definitely exists
 		}
 		indices[i] = int(defaultFeatIdx)
 	}
 	return indices
 }
 
+// record looks key up in the records added by deltas, the latest first, then
+// in f, the field of name.
+func (m *Params) record(name string, f *proto.Field, key string) (int64, bool) {
+	overlays := m.overlays[name]
+	for i := len(overlays) - 1; i >= 0; i-- {
+		if v, ok := overlays[i][key]; ok {
+			return v, true
+		}
+	}
+	v, ok := f.Records[key]
+	return v, ok
+}
+
+// GetTensor returns the tensor of fieldName, quantized tables are dequantized as a whole.
 func (m *Params) GetTensor(fieldName string) tensor.Tensor {
-	return m.tensors[fieldName]
+	if t, ok := m.tensors[fieldName]; ok {
+		return t
+	}
+	if q, ok := m.quantized[fieldName]; ok {
+		return q.dense()
+	}
+	if c, ok := m.chunked[fieldName]; ok {
+		return c.dense()
+	}
+	return nil
+}
+
+// Shape returns the shape of the tensor of fieldName, quantized tables are
+// not dequantized for it.
+func (m *Params) Shape(fieldName string) (tensor.Shape, bool) {
+	if t, ok := m.tensors[fieldName]; ok {
+		return t.Shape().Clone(), true
+	}
+	if q, ok := m.quantized[fieldName]; ok {
+		return tensor.Shape{q.rows, q.dim}, true
+	}
+	if c, ok := m.chunked[fieldName]; ok {
+		return tensor.Shape{c.rows, c.dim}, true
+	}
+	return nil, false
+}
+
+// Dtype returns the data type of GetTensor of fieldName, which is float32 for
+// quantized tables.
+func (m *Params) Dtype(fieldName string) (tensor.Dtype, bool) {
+	if t, ok := m.tensors[fieldName]; ok {
+		return t.Dtype(), true
+	}
+	_, quantized := m.quantized[fieldName]
+	_, chunked := m.chunked[fieldName]
+	return tensor.Float32, quantized || chunked
+}
+
+// Quantized returns the stored DT_INT8 or DT_HALF tensor of fieldName, false
+// if it isn't quantized.
+func (m *Params) Quantized(fieldName string) (*proto.Tensor, bool) {
+	if q, ok := m.quantized[fieldName]; ok {
+		return q.proto(), true
+	}
+	if c, ok := m.chunked[fieldName]; ok && c.dtype != proto.DataType_DT_FLOAT {
+		t, err := c.proto()
+		return t, err == nil
+	}
+	return nil, false
+}
+
+// StorageType returns the stored data type of a tensor, which differs from
+// the type of GetTensor for quantized tables.
+func (m *Params) StorageType(fieldName string) proto.DataType {
+	if q, ok := m.quantized[fieldName]; ok {
+		return q.dtype
+	}
+	if c, ok := m.chunked[fieldName]; ok {
+		return c.dtype
+	}
+	t, ok := m.tensors[fieldName]
+	if !ok {
+		return proto.DataType_DT_INVALID
+	}
+	switch t.Dtype() {
+	case tensor.Float32:
+		return proto.DataType_DT_FLOAT
+	case tensor.Int32:
+		return proto.DataType_DT_INT32
+	case tensor.String:
+		return proto.DataType_DT_STRING
+	default:
+		return proto.DataType_DT_INVALID
+	}
+}
+
+// TensorNames returns the sorted names of all tensors.
+func (m *Params) TensorNames() []string {
+	names := make([]string, 0, len(m.tensors)+len(m.quantized)+len(m.chunked))
+	for k := range m.tensors {
+		names = append(names, k)
+	}
+	for k := range m.quantized {
+		names = append(names, k)
+	}
+	for k := range m.chunked {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FieldNames returns the sorted names of all vocabulary fields.
+func (m *Params) FieldNames() []string {
+	names := make([]string, 0, len(m.index))
+	for k := range m.index {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GetField returns the vocabulary of fieldName, the records added by deltas
+// are copied into it.
+func (m *Params) GetField(fieldName string) (*proto.Field, bool) {
+	f, ok := m.index[fieldName]
+	overlays := m.overlays[fieldName]
+	if !ok || len(overlays) == 0 {
+		return f, ok
+	}
+	merged := &proto.Field{Name: f.Name, Dim: f.Dim, Records: make(map[string]int64, len(f.Records))}
+	for k, v := range f.Records {
+		merged.Records[k] = v
+	}
+	for _, records := range overlays {
+		for k, v := range records {
+			merged.Records[k] = v
+		}
+	}
+	return merged, true
 }
 
 func (m *Params) ShowTensors() {
@@ -240,15 +582,18 @@ func (m *Params) ShowTensors() {
 }
 
 func (m *Params) ShowIndex() {
-	for k, v := range m.index {
+	for k := range m.index {
 		fmt.Println(k)
+		v, _ := m.GetField(k)
 		for i, j := range v.Records {
 			fmt.Println("  ", i, j)
 		}
 	}
 }
 
-func decode(t *proto.Tensor) *tensor.Dense {
+// Decode converts a DT_FLOAT, DT_INT32 or DT_STRING tensor, it panics if the
+// tensor is invalid, see DecodeChecked for untrusted tensors.
+func Decode(t *proto.Tensor) *tensor.Dense {
 	var back tensor.ConsOpt
 
 	switch t.GetDtype() {
@@ -266,3 +611,34 @@ func decode(t *proto.Tensor) *tensor.Dense {
 	}
 	return tensor.New(back, tensor.WithShape(shapes...))
 }
+
+// DecodeChecked is Decode which returns an error if the dtype is unsupported,
+// or the shape doesn't match the number of values.
+func DecodeChecked(t *proto.Tensor) (*tensor.Dense, error) {
+	var n int
+	switch t.GetDtype() {
+	case proto.DataType_DT_FLOAT:
+		n = len(t.GetFloatVal())
+	case proto.DataType_DT_INT32:
+		n = len(t.GetIntVal())
+	case proto.DataType_DT_STRING:
+		n = len(t.GetStringVal())
+	default:
+		return nil, fmt.Errorf("%w: %v", UnsupportedDtypeErr, t.GetDtype())
+	}
+	size := 1
+	for _, d := range t.GetTensorShape() {
+		switch {
+		case d < 0:
+			return nil, fmt.Errorf("%w: shape %v", InvalidTensorErr, t.GetTensorShape())
+		case d == 0:
+			size = 0
+		case size <= n: // stops growing once mismatched, so it can't overflow
+			size *= int(d)
+		}
+	}
+	if size != n {
+		return nil, fmt.Errorf("%w: shape %v of %d values", InvalidTensorErr, t.GetTensorShape(), n)
+	}
+	return Decode(t), nil
+}
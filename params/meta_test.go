@@ -0,0 +1,395 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/26 14:10
+ */
+
+package params
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+func newTable(rows, dim int) *tensor.Dense {
+	backing := make([]float32, rows*dim)
+	for i := range backing {
+		backing[i] = float32(i)
+	}
+	return tensor.New(tensor.WithBacking(backing), tensor.WithShape(rows, dim))
+}
+
+func newLookupParams(t testing.TB, rows, dim int) *Params {
+	t.Helper()
+	m := New("")
+	m.tensors["dense"] = newTable(rows, dim)
+	q, err := Quantize(newTable(rows, dim), proto.DataType_DT_INT8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.quantized["int8"], err = newQuantizedTable(q); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestEmbeddingLookup(t *testing.T) {
+	m := newLookupParams(t, 10, 3)
+	out, err := m.EmbeddingLookup("dense", Index{2, 0, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !out.Shape().Eq(tensor.Shape{3, 3}) {
+		t.Fatalf("shape: got %v, want (3, 3)", out.Shape())
+	}
+	want := []float32{6, 7, 8, 0, 1, 2, 6, 7, 8}
+	for i, v := range out.Data().([]float32) {
+		if v != want[i] {
+			t.Fatalf("rows: got %v, want %v", out.Data(), want)
+		}
+	}
+}
+
+func TestShape(t *testing.T) {
+	m := newLookupParams(t, 10, 3)
+	for _, name := range []string{"dense", "int8"} {
+		shape, ok := m.Shape(name)
+		if !ok || !shape.Eq(tensor.Shape{10, 3}) {
+			t.Errorf("%s: got shape %v %v", name, shape, ok)
+		}
+		if dtype, ok := m.Dtype(name); !ok || dtype != tensor.Float32 {
+			t.Errorf("%s: got dtype %v %v", name, dtype, ok)
+		}
+	}
+	if _, ok := m.Shape("missing"); ok {
+		t.Error("missing: got a shape")
+	}
+	if _, ok := m.Dtype("missing"); ok {
+		t.Error("missing: got a dtype")
+	}
+}
+
+func TestEmbeddingLookupErrors(t *testing.T) {
+	m := newLookupParams(t, 10, 3)
+	tests := []struct {
+		name  string
+		table string
+		index Index
+		err   error
+	}{
+		{"negative", "dense", Index{1, -1}, IndexOutOfRangeErr},
+		{"beyond rows", "dense", Index{10}, IndexOutOfRangeErr},
+		{"quantized beyond rows", "int8", Index{0, 10}, IndexOutOfRangeErr},
+		{"quantized negative", "int8", Index{-1}, IndexOutOfRangeErr},
+		{"empty", "dense", Index{}, EmptyIndexErr},
+		{"not found", "missing", Index{0}, TensorNotFoundErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := m.EmbeddingLookup(tt.table, tt.index); !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestQuantizeInt8(t *testing.T) {
+	const rows, dim = 50, 16
+	r := rand.New(rand.NewSource(1))
+	values := make([]float32, rows*dim)
+	for i := range values {
+		// rows of different magnitudes
+		values[i] = float32(r.NormFloat64() * math.Pow(10, float64(i/dim%7-3)))
+	}
+	// all-zero rows are scaled by 0
+	for j := 0; j < dim; j++ {
+		values[3*dim+j] = 0
+	}
+	pt, err := Quantize(tensor.New(tensor.WithBacking(values), tensor.WithShape(rows, dim)), proto.DataType_DT_INT8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Dequantize(pt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := got.Data().([]float32)
+	for i := 0; i < rows; i++ {
+		var maxAbs float64
+		for _, v := range values[i*dim : (i+1)*dim] {
+			maxAbs = math.Max(maxAbs, math.Abs(float64(v)))
+		}
+		if want := float32(maxAbs / math.MaxInt8); pt.Scale[i] != want {
+			t.Errorf("row %d: got scale %v, want %v", i, pt.Scale[i], want)
+		}
+		// rounded to the nearest step of the row
+		bound := maxAbs/math.MaxInt8/2 + 1e-6*maxAbs
+		for j := i * dim; j < (i+1)*dim; j++ {
+			if diff := math.Abs(float64(data[j] - values[j])); diff > bound {
+				t.Fatalf("row %d: got %v, want %v within %v", i, data[j], values[j], bound)
+			}
+		}
+	}
+	for j := 3 * dim; j < 4*dim; j++ {
+		if data[j] != 0 || math.Signbit(float64(data[j])) {
+			t.Fatalf("zero row: got %v", data[3*dim:4*dim])
+		}
+	}
+}
+
+func TestQuantizeInt8NonFinite(t *testing.T) {
+	for _, v := range []float32{float32(math.Inf(1)), float32(math.Inf(-1)), float32(math.NaN())} {
+		table := tensor.New(tensor.WithBacking([]float32{1, 2, 3, v}), tensor.WithShape(2, 2))
+		if _, err := Quantize(table, proto.DataType_DT_INT8); !errors.Is(err, InvalidQuantizedErr) {
+			t.Errorf("%v: got %v, want %v", v, err, InvalidQuantizedErr)
+		}
+	}
+}
+
+func TestQuantizeHalf(t *testing.T) {
+	inf, nan := float32(math.Inf(1)), float32(math.NaN())
+	values := []float32{
+		0, 0.5, -2, 65504, // exact in fp16
+		inf, -inf, nan, 1e6, // 1e6 overflows to +Inf
+		0.1, -3.14159, 1e-3, 12345.678,
+	}
+	pt, err := Quantize(tensor.New(tensor.WithBacking(values), tensor.WithShape(3, 4)), proto.DataType_DT_HALF)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pt.Scale != nil {
+		t.Errorf("got scales %v", pt.Scale)
+	}
+	got, err := Dequantize(pt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data := got.Data().([]float32)
+	for i, want := range []float32{0, 0.5, -2, 65504, inf, -inf} {
+		if data[i] != want {
+			t.Errorf("%d: got %v, want %v", i, data[i], want)
+		}
+	}
+	if !math.IsNaN(float64(data[6])) || !math.IsInf(float64(data[7]), 1) {
+		t.Errorf("got %v, want NaN +Inf", data[6:8])
+	}
+	// 10 bits of mantissa
+	for i := 8; i < len(values); i++ {
+		if diff := math.Abs(float64(data[i]-values[i])) / math.Abs(float64(values[i])); diff > 1.0/2048 {
+			t.Errorf("%d: got %v, want %v within relative 2^-11", i, data[i], values[i])
+		}
+	}
+}
+
+// sliceStackLookup is the lookup before gathering: a materialized slice per id, then stacked.
+func sliceStackLookup(t *tensor.Dense, index Index) tensor.Tensor {
+	tensors := make([]tensor.Tensor, len(index))
+	for i := 0; i < len(index); i++ {
+		view, _ := t.Slice(tensor.S(index[i]))
+		tensors[i] = view.Materialize().(*tensor.Dense)
+	}
+	res, _ := tensor.Stack(0, tensors[0], tensors[1:]...)
+	return res
+}
+
+// BenchmarkEmbeddingLookup looks up a batch of 256 rows of 50 ids each.
+func BenchmarkEmbeddingLookup(b *testing.B) {
+	const rows, dim, ids = 100000, 16, 256 * 50
+	m := newLookupParams(b, rows, dim)
+	r := rand.New(rand.NewSource(1))
+	index := make(Index, ids)
+	for i := range index {
+		index[i] = r.Intn(rows)
+	}
+
+	b.Run("slice_stack", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			sliceStackLookup(m.tensors["dense"], index)
+		}
+	})
+	b.Run("gather", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := m.EmbeddingLookup("dense", index); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("gather_int8", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if _, err := m.EmbeddingLookup("int8", index); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+// modelFile writes a model file of the current format with Writer.
+func modelFile(t *testing.T) []byte {
+	t.Helper()
+	w := NewWriter("test", 1)
+	if err := w.AddDense("x", newTable(2, 3)); err != nil {
+		t.Fatal(err)
+	}
+	w.AddField(&proto.Field{Name: "x", Dim: 3, Records: map[string]int64{"a": 0, "b": 1}})
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// legacyFile replaces the footer of file with a format version 0 one.
+func legacyFile(file []byte) []byte {
+	footer := file[len(file)-FooterSize:]
+	legacy := make([]byte, LegacyFooterSize)
+	copy(legacy, footer[:20]) // offsets
+	copy(legacy[20:], Magic)
+	return append(file[:len(file)-FooterSize:len(file)-FooterSize], legacy...)
+}
+
+// rawLegacyFile joins the sections with a format version 0 footer, which has no checksums.
+func rawLegacyFile(header, data, index []byte) []byte {
+	footer := make([]byte, LegacyFooterSize)
+	binary.PutUvarint(footer[:10], uint64(len(header)))
+	binary.PutUvarint(footer[10:20], uint64(len(header)+len(data)))
+	copy(footer[20:], Magic)
+	return bytes.Join([][]byte{header, data, index, footer}, nil)
+}
+
+// mismatchedFile writes a tensor whose shape doesn't match its values.
+func mismatchedFile(t *testing.T) []byte {
+	t.Helper()
+	w := NewWriter("test", 1)
+	w.AddTensor("x", &proto.Tensor{Dtype: proto.DataType_DT_FLOAT, TensorShape: []int32{5}, FloatVal: []float32{1, 2, 3}})
+	var buf bytes.Buffer
+	if _, err := w.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// offsets returns the data and index offsets of a current format file.
+func offsets(file []byte) (int, int) {
+	footer := file[len(file)-FooterSize:]
+	data, _ := binary.Uvarint(footer[:10])
+	index, _ := binary.Uvarint(footer[10:20])
+	return int(data), int(index)
+}
+
+func TestLoadFrom(t *testing.T) {
+	file := modelFile(t)
+	dataOffset, indexOffset := offsets(file)
+	// flip returns a copy of file with the byte at i flipped, from the end if negative
+	flip := func(file []byte, i int) []byte {
+		res := append([]byte(nil), file...)
+		if i < 0 {
+			i += len(res)
+		}
+		res[i] ^= 0xff
+		return res
+	}
+	version := func(v byte) []byte {
+		res := append([]byte(nil), file...)
+		res[len(res)-len(Magic)-1] = v
+		return res
+	}
+
+	tests := []struct {
+		name   string
+		file   []byte
+		size   int64 // of the file if 0
+		err    error
+		format uint8
+	}{
+		{name: "current", file: file, format: FormatVersion},
+		{name: "legacy", file: legacyFile(file), format: 0},
+		{name: "header checksum", file: flip(file, 0), err: HeaderChecksumErr},
+		{name: "data checksum", file: flip(file, dataOffset), err: DataChecksumErr},
+		{name: "index checksum", file: flip(file, indexOffset), err: IndexChecksumErr},
+		{name: "footer checksum", file: flip(file, -FooterSize+24), err: DataChecksumErr},
+		{name: "unknown format version", file: version(FormatVersion + 1), err: UnsupportedFormatErr},
+		{name: "magic", file: flip(file, -1), err: InvalidMagicErr},
+		{name: "empty", file: nil, err: FooterInvalidLengthErr},
+		{name: "shorter than legacy footer", file: file[len(file)-LegacyFooterSize+1:], err: FooterInvalidLengthErr},
+		{name: "only footer", file: file[len(file)-FooterSize:], err: IndexInvalidLengthErr},
+		{name: "truncated head", file: file[indexOffset:], err: IndexInvalidLengthErr},
+		{name: "truncated reader", file: file[:len(file)-1], size: int64(len(file)), err: FooterInvalidLengthErr},
+		{name: "short legacy header", file: rawLegacyFile([]byte("test"), nil, nil), err: HeaderInvalidLengthErr},
+		{name: "shape mismatch", file: mismatchedFile(t), err: InvalidTensorErr},
+	}
+	for _, test := range tests {
+		size := test.size
+		if size == 0 {
+			size = int64(len(test.file))
+		}
+		m := New("")
+		err := m.LoadFrom(bytes.NewReader(test.file), size)
+		if !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if m.FormatVersion() != test.format || m.ModelName() != "test" || m.Version() != 1 {
+			t.Errorf("%s: got format %d, %s %d", test.name, m.FormatVersion(), m.ModelName(), m.Version())
+		}
+		if got := m.IndexLookup("x", "a", "b"); len(got) != 1 || got[0] != 1 {
+			t.Errorf("%s: index: got %v", test.name, got)
+		}
+	}
+}
+
+func TestParseFooter(t *testing.T) {
+	file := modelFile(t)
+	legacy := legacyFile(file)
+	tests := []struct {
+		name string
+		buf  []byte
+		err  error
+	}{
+		{"current", file[len(file)-FooterSize:], nil},
+		{"legacy", legacy[len(legacy)-LegacyFooterSize:], nil},
+		{"short", file[len(file)-LegacyFooterSize+1:], FooterInvalidLengthErr},
+		{"between sizes", file[len(file)-FooterSize+1:], FooterInvalidLengthErr},
+		{"no magic", make([]byte, FooterSize), InvalidMagicErr},
+	}
+	for _, test := range tests {
+		m := New("")
+		if err := m.parseFooter(test.buf); !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+func TestDecodeChecked(t *testing.T) {
+	tests := []struct {
+		name   string
+		tensor *proto.Tensor
+		err    error
+	}{
+		{"float", &proto.Tensor{Dtype: proto.DataType_DT_FLOAT, TensorShape: []int32{2, 1}, FloatVal: []float32{1, 2}}, nil},
+		{"scalar", &proto.Tensor{Dtype: proto.DataType_DT_INT32, IntVal: []int32{1}}, nil},
+		{"empty", &proto.Tensor{Dtype: proto.DataType_DT_STRING, TensorShape: []int32{2, 0}}, nil},
+		{"fewer values", &proto.Tensor{Dtype: proto.DataType_DT_FLOAT, TensorShape: []int32{5}, FloatVal: []float32{1, 2, 3}}, InvalidTensorErr},
+		{"more values", &proto.Tensor{Dtype: proto.DataType_DT_INT32, TensorShape: []int32{1}, IntVal: []int32{1, 2}}, InvalidTensorErr},
+		{"negative dim", &proto.Tensor{Dtype: proto.DataType_DT_FLOAT, TensorShape: []int32{-1, -2}, FloatVal: []float32{1, 2}}, InvalidTensorErr},
+		{"overflowed shape", &proto.Tensor{Dtype: proto.DataType_DT_FLOAT, TensorShape: []int32{1 << 30, 1 << 30, 1 << 30, 4}, FloatVal: []float32{1}}, InvalidTensorErr},
+		{"invalid dtype", &proto.Tensor{TensorShape: []int32{1}}, UnsupportedDtypeErr},
+		{"quantized", &proto.Tensor{Dtype: proto.DataType_DT_INT8, TensorShape: []int32{1}, ByteVal: []byte{1}}, UnsupportedDtypeErr},
+	}
+	for _, test := range tests {
+		if _, err := DecodeChecked(test.tensor); !errors.Is(err, test.err) {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !unix
+
+/*
+* @Author: Yajun
+* @Date:   2022/4/23 11:05
+ */
+
+package params
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the whole file where mmap is unavailable.
+func mapFile(f *os.File, size int64) ([]byte, func() error, error) {
+	data := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(f, 0, size), data); err != nil {
+		return nil, nil, err
+	}
+	return data, func() error { return nil }, nil
+}
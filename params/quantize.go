@@ -0,0 +1,163 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/8 16:40
+ */
+
+package params
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+
+	"github.com/x448/float16"
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+// quantizedTable is an embedding table stored as per-row int8 or fp16,
+// rows are only dequantized when looked up.
+type quantizedTable struct {
+	dtype  proto.DataType
+	rows   int
+	dim    int
+	data   []byte
+	scales []float32
+}
+
+func newQuantizedTable(t *proto.Tensor) (*quantizedTable, error) {
+	shape := t.GetTensorShape()
+	if len(shape) != 2 {
+		return nil, fmt.Errorf("%w: expected 2 dims, but got %v", InvalidQuantizedErr, shape)
+	}
+	q := &quantizedTable{
+		dtype:  t.GetDtype(),
+		rows:   int(shape[0]),
+		dim:    int(shape[1]),
+		data:   t.GetByteVal(),
+		scales: t.GetScale(),
+	}
+	switch q.dtype {
+	case proto.DataType_DT_INT8:
+		if len(q.data) != q.rows*q.dim || len(q.scales) != q.rows {
+			return nil, fmt.Errorf("%w: int8 table %v has %d bytes and %d scales",
+				InvalidQuantizedErr, shape, len(q.data), len(q.scales))
+		}
+	case proto.DataType_DT_HALF:
+		if len(q.data) != 2*q.rows*q.dim {
+			return nil, fmt.Errorf("%w: fp16 table %v has %d bytes", InvalidQuantizedErr, shape, len(q.data))
+		}
+	default:
+		return nil, fmt.Errorf("%w: %v", UnsupportedDtypeErr, q.dtype)
+	}
+	return q, nil
+}
+
+// row dequantizes the i-th row into dst, which has length dim.
+func (q *quantizedTable) row(i int, dst []float32) {
+	var scale float32
+	if q.dtype == proto.DataType_DT_INT8 {
+		scale = q.scales[i]
+	}
+	n := rowSize(q.dtype, q.dim)
+	dequantizeRow(q.dtype, q.data[i*n:(i+1)*n], scale, dst)
+}
+
+// rowSize returns the bytes of a quantized row of dim values.
+func rowSize(dtype proto.DataType, dim int) int {
+	if dtype == proto.DataType_DT_HALF {
+		return 2 * dim
+	}
+	return dim
+}
+
+// dequantizeRow converts the bytes of a row into dst, scale is only used by DT_INT8.
+func dequantizeRow(dtype proto.DataType, src []byte, scale float32, dst []float32) {
+	switch dtype {
+	case proto.DataType_DT_INT8:
+		for j := range dst {
+			dst[j] = float32(int8(src[j])) * scale
+		}
+	case proto.DataType_DT_HALF:
+		for j := range dst {
+			dst[j] = float16.Frombits(binary.LittleEndian.Uint16(src[2*j:])).Float32()
+		}
+	}
+}
+
+// dense dequantizes the whole table.
+func (q *quantizedTable) dense() *tensor.Dense {
+	backing := make([]float32, q.rows*q.dim)
+	for i := 0; i < q.rows; i++ {
+		q.row(i, backing[i*q.dim:(i+1)*q.dim])
+	}
+	return tensor.New(tensor.WithBacking(backing), tensor.WithShape(q.rows, q.dim))
+}
+
+func (q *quantizedTable) proto() *proto.Tensor {
+	return &proto.Tensor{
+		Dtype:       q.dtype,
+		TensorShape: []int32{int32(q.rows), int32(q.dim)},
+		ByteVal:     q.data,
+		Scale:       q.scales,
+	}
+}
+
+// Dequantize converts a DT_INT8 or DT_HALF tensor back to float32.
+func Dequantize(t *proto.Tensor) (*tensor.Dense, error) {
+	q, err := newQuantizedTable(t)
+	if err != nil {
+		return nil, err
+	}
+	return q.dense(), nil
+}
+
+// Quantize converts a 2-D float32 embedding table to a per-row int8 or fp16 tensor.
+// Rows of int8 are scaled by their max absolute values, so they must be finite.
+func Quantize(t *tensor.Dense, dtype proto.DataType) (*proto.Tensor, error) {
+	shape := t.Shape()
+	if len(shape) != 2 {
+		return nil, fmt.Errorf("%w: expected 2 dims, but got %v", InvalidQuantizedErr, shape)
+	}
+	if t.IsMaterializable() {
+		t = t.Materialize().(*tensor.Dense)
+	}
+	values, ok := t.Data().([]float32)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", UnsupportedDtypeErr, t.Dtype())
+	}
+	rows, dim := shape[0], shape[1]
+	pt := &proto.Tensor{Dtype: dtype, TensorShape: []int32{int32(rows), int32(dim)}}
+
+	switch dtype {
+	case proto.DataType_DT_INT8:
+		pt.ByteVal = make([]byte, rows*dim)
+		pt.Scale = make([]float32, rows)
+		for i := 0; i < rows; i++ {
+			row := values[i*dim : (i+1)*dim]
+			var maxAbs float32
+			for _, v := range row {
+				if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+					return nil, fmt.Errorf("%w: row %d has %v, expected finite int8 values", InvalidQuantizedErr, i, v)
+				}
+				maxAbs = float32(math.Max(float64(maxAbs), math.Abs(float64(v))))
+			}
+			if maxAbs == 0 {
+				continue
+			}
+			scale := maxAbs / math.MaxInt8
+			pt.Scale[i] = scale
+			for j, v := range row {
+				pt.ByteVal[i*dim+j] = byte(int8(math.Round(float64(v / scale))))
+			}
+		}
+	case proto.DataType_DT_HALF:
+		pt.ByteVal = make([]byte, 2*rows*dim)
+		for i, v := range values {
+			binary.LittleEndian.PutUint16(pt.ByteVal[2*i:], float16.Fromfloat32(v).Bits())
+		}
+	default:
+		return nil, fmt.Errorf("%w: %v", UnsupportedDtypeErr, dtype)
+	}
+	return pt, nil
+}
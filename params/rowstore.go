@@ -0,0 +1,221 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/23 10:20
+ */
+
+package params
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gorgonia.org/tensor"
+)
+
+// RowStore stores the rows of embedding tables too large for memory, e.g. on
+// disk or in a remote key-value store.
+type RowStore interface {
+	// Shape returns the rows and dimension of the table, false if not stored.
+	Shape(table string) (rows, dim int, ok bool)
+	// ReadRows reads the rows of ids into dst, which has len(ids)*dim.
+	ReadRows(table string, ids []int, dst []float32) error
+	Close() error
+}
+
+// RowsExt is the extension of row files, <table>.rows in the directory of a disk store.
+const RowsExt = ".rows"
+
+const rowsHeaderSize = 16
+
+// WriteRows writes a 2-D float32 table as a row file: rows and dim as uint64, then
+// the little-endian rows.
+func WriteRows(w io.Writer, t *tensor.Dense) error {
+	if t.Dims() != 2 {
+		return fmt.Errorf("%w: expected 2 dims, but got %v", UnsupportedDtypeErr, t.Shape())
+	}
+	if t.IsMaterializable() {
+		t = t.Materialize().(*tensor.Dense)
+	}
+	values, ok := t.Data().([]float32)
+	if !ok {
+		return fmt.Errorf("%w: %v", UnsupportedDtypeErr, t.Dtype())
+	}
+	buf := make([]byte, rowsHeaderSize+4*len(values))
+	binary.LittleEndian.PutUint64(buf, uint64(t.Shape()[0]))
+	binary.LittleEndian.PutUint64(buf[8:], uint64(t.Shape()[1]))
+	for i, v := range values {
+		binary.LittleEndian.PutUint32(buf[rowsHeaderSize+4*i:], math.Float32bits(v))
+	}
+	_, err := w.Write(buf)
+	return err
+}
+
+type rowFile struct {
+	rows, dim int
+	data      []byte // mapped rows, without header
+	release   func() error
+}
+
+// DiskRowStore maps the row files of a directory into memory, so only the
+// touched pages are resident. The mappings are released by Close, or when the
+// store is garbage collected.
+type DiskRowStore struct {
+	tables map[string]*rowFile
+}
+
+func OpenDiskRowStore(dir string) (*DiskRowStore, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+RowsExt))
+	if err != nil {
+		return nil, err
+	}
+	s := &DiskRowStore{tables: make(map[string]*rowFile)}
+	for _, file := range files {
+		rf, err := openRowFile(file)
+		if err != nil {
+			s.Close()
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		s.tables[strings.TrimSuffix(filepath.Base(file), RowsExt)] = rf
+	}
+	runtime.SetFinalizer(s, (*DiskRowStore).Close)
+	return s, nil
+}
+
+func openRowFile(file string) (*rowFile, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, rowsHeaderSize)
+	if _, err := f.ReadAt(header, 0); err != nil {
+		return nil, err
+	}
+	// checked before multiplying, so that a corrupted header can't overflow the size
+	rows := binary.LittleEndian.Uint64(header)
+	dim := binary.LittleEndian.Uint64(header[8:])
+	if rows == 0 || dim == 0 || rows > math.MaxInt64/4/dim || 4*rows*dim != uint64(stat.Size()-rowsHeaderSize) {
+		return nil, fmt.Errorf("%w: %d rows of dim %d in %d bytes", DataInvalidLengthErr, rows, dim, stat.Size())
+	}
+	data, release, err := mapFile(f, stat.Size())
+	if err != nil {
+		return nil, err
+	}
+	return &rowFile{rows: int(rows), dim: int(dim), data: data[rowsHeaderSize:], release: release}, nil
+}
+
+// Tables returns the sorted names of the stored tables.
+func (s *DiskRowStore) Tables() []string {
+	tables := make([]string, 0, len(s.tables))
+	for t := range s.tables {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	return tables
+}
+
+func (s *DiskRowStore) Shape(table string) (int, int, bool) {
+	rf, ok := s.tables[table]
+	if !ok {
+		return 0, 0, false
+	}
+	return rf.rows, rf.dim, true
+}
+
+func (s *DiskRowStore) ReadRows(table string, ids []int, dst []float32) error {
+	rf, ok := s.tables[table]
+	if !ok {
+		return fmt.Errorf("%w: %s", TensorNotFoundErr, table)
+	}
+	for i, id := range ids {
+		if id < 0 || id >= rf.rows {
+			return fmt.Errorf("%w: %s[%d], rows %d", IndexOutOfRangeErr, table, id, rf.rows)
+		}
+		src := rf.data[4*id*rf.dim : 4*(id+1)*rf.dim]
+		for j := 0; j < rf.dim; j++ {
+			dst[i*rf.dim+j] = math.Float32frombits(binary.LittleEndian.Uint32(src[4*j:]))
+		}
+	}
+	return nil
+}
+
+func (s *DiskRowStore) Close() error {
+	var err error
+	for _, rf := range s.tables {
+		if e := rf.release(); e != nil && err == nil {
+			err = e
+		}
+	}
+	s.tables = nil
+	runtime.SetFinalizer(s, nil)
+	return err
+}
+
+// MemRowStore is an in-memory stand-in of remote stores, with an optional
+// latency per read, e.g. for testing the tiers.
+type MemRowStore struct {
+	mu      sync.RWMutex
+	tables  map[string]*tensor.Dense
+	latency time.Duration
+}
+
+func NewMemRowStore(latency time.Duration) *MemRowStore {
+	return &MemRowStore{tables: make(map[string]*tensor.Dense), latency: latency}
+}
+
+// Set stores a 2-D float32 table.
+func (s *MemRowStore) Set(table string, t *tensor.Dense) error {
+	if _, ok := t.Data().([]float32); !ok || t.Dims() != 2 {
+		return fmt.Errorf("%w: %s is %v%v, expected 2-D float32", UnsupportedDtypeErr, table, t.Dtype(), t.Shape())
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tables[table] = t
+	return nil
+}
+
+func (s *MemRowStore) Shape(table string) (int, int, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	t, ok := s.tables[table]
+	if !ok {
+		return 0, 0, false
+	}
+	return t.Shape()[0], t.Shape()[1], true
+}
+
+func (s *MemRowStore) ReadRows(table string, ids []int, dst []float32) error {
+	time.Sleep(s.latency)
+	s.mu.RLock()
+	t, ok := s.tables[table]
+	s.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("%w: %s", TensorNotFoundErr, table)
+	}
+	data := t.Data().([]float32)
+	rows, dim := t.Shape()[0], t.Shape()[1]
+	for i, id := range ids {
+		if id < 0 || id >= rows {
+			return fmt.Errorf("%w: %s[%d], rows %d", IndexOutOfRangeErr, table, id, rows)
+		}
+		copy(dst[i*dim:(i+1)*dim], data[id*dim:(id+1)*dim])
+	}
+	return nil
+}
+
+func (s *MemRowStore) Close() error {
+	return nil
+}
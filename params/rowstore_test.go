@@ -0,0 +1,74 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 20:10
+ */
+
+package params
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// rowFileOf returns a row file of the header and the bytes of data.
+func rowFileOf(rows, dim uint64, data int) []byte {
+	buf := make([]byte, rowsHeaderSize+data)
+	binary.LittleEndian.PutUint64(buf, rows)
+	binary.LittleEndian.PutUint64(buf[8:], dim)
+	return buf
+}
+
+func TestOpenDiskRowStore(t *testing.T) {
+	var valid bytes.Buffer
+	if err := WriteRows(&valid, newTable(3, 2)); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		file []byte
+		err  error
+	}{
+		{"valid", valid.Bytes(), nil},
+		{"short header", make([]byte, rowsHeaderSize-1), io.EOF},
+		{"short data", rowFileOf(3, 2, 20), DataInvalidLengthErr},
+		{"zero rows", rowFileOf(0, 2, 0), DataInvalidLengthErr},
+		{"zero dim", rowFileOf(3, 0, 0), DataInvalidLengthErr},
+		{"negative rows", rowFileOf(math.MaxUint64, 2, 0), DataInvalidLengthErr},
+		// 4*rows*dim wraps to 0
+		{"overflow", rowFileOf(1<<62, 1, 0), DataInvalidLengthErr},
+		{"overflow dim", rowFileOf(2, 1<<61, 0), DataInvalidLengthErr},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, "x"+RowsExt), tt.file, 0644); err != nil {
+				t.Fatal(err)
+			}
+			s, err := OpenDiskRowStore(dir)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			defer s.Close()
+			if rows, dim, ok := s.Shape("x"); !ok || rows != 3 || dim != 2 {
+				t.Fatalf("got shape %d %d %v", rows, dim, ok)
+			}
+			dst := make([]float32, 4)
+			if err := s.ReadRows("x", []int{2, 0}, dst); err != nil {
+				t.Fatal(err)
+			}
+			if want := []float32{4, 5, 0, 1}; !reflect.DeepEqual(dst, want) {
+				t.Fatalf("got %v, want %v", dst, want)
+			}
+		})
+	}
+}
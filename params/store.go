@@ -0,0 +1,227 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/24 9:30
+ */
+
+package params
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"gorgonia.org/tensor"
+)
+
+// VocabularyStore maps the feature names of a field to the indices of its embedding table.
+type VocabularyStore interface {
+	IndexLookup(fieldName, defaultFeat string, featNames ...string) Index
+}
+
+// EmbeddingStore gathers the rows of embedding tables.
+type EmbeddingStore interface {
+	EmbeddingLookup(fieldName string, index Index) (tensor.Tensor, error)
+}
+
+// TensorStore holds the dense tensors, e.g. the weights of linear layers.
+type TensorStore interface {
+	GetTensor(fieldName string) tensor.Tensor
+}
+
+// Composite assembles the params of a model from several stores by field name,
+// e.g. weights from the model files, vocabularies from text files and embeddings
+// from a RowStore. Stores are added before serving, it is not safe to add them
+// concurrently with lookups.
+type Composite struct {
+	vocabularies map[string]VocabularyStore
+	embeddings   map[string]EmbeddingStore
+	tensors      map[string]TensorStore
+}
+
+func NewComposite() *Composite {
+	return &Composite{
+		vocabularies: make(map[string]VocabularyStore),
+		embeddings:   make(map[string]EmbeddingStore),
+		tensors:      make(map[string]TensorStore),
+	}
+}
+
+// AddVocabulary looks up the vocabularies of fields in store.
+func (c *Composite) AddVocabulary(store VocabularyStore, fields ...string) *Composite {
+	for _, f := range fields {
+		c.vocabularies[f] = store
+	}
+	return c
+}
+
+// AddEmbedding looks up the embedding tables of fields in store.
+func (c *Composite) AddEmbedding(store EmbeddingStore, fields ...string) *Composite {
+	for _, f := range fields {
+		c.embeddings[f] = store
+	}
+	return c
+}
+
+// AddTensor looks up the tensors of fields in store.
+func (c *Composite) AddTensor(store TensorStore, fields ...string) *Composite {
+	for _, f := range fields {
+		c.tensors[f] = store
+	}
+	return c
+}
+
+// Close closes the added stores which are io.Closers, e.g. a TieredStore.
+func (c *Composite) Close() error {
+	closed := make(map[io.Closer]bool)
+	var err error
+	closeStore := func(store any) {
+		if cl, ok := store.(io.Closer); ok && !closed[cl] {
+			closed[cl] = true
+			if e := cl.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+	}
+	for _, s := range c.vocabularies {
+		closeStore(s)
+	}
+	for _, s := range c.embeddings {
+		closeStore(s)
+	}
+	for _, s := range c.tensors {
+		closeStore(s)
+	}
+	return err
+}
+
+// Meta returns the params whose fields of no store are looked up in base,
+// base can be nil if all fields are in the stores.
+func (c *Composite) Meta(base Meta) Meta {
+	return &composed{Composite: c, base: base}
+}
+
+type composed struct {
+	*Composite
+	base Meta
+}
+
+func (m *composed) IndexLookup(fieldName, defaultFeat string, featNames ...string) Index {
+	if s, ok := m.vocabularies[fieldName]; ok {
+		return s.IndexLookup(fieldName, defaultFeat, featNames...)
+	}
+	if m.base == nil {
+		return nil
+	}
+	return m.base.IndexLookup(fieldName, defaultFeat, featNames...)
+}
+
+func (m *composed) EmbeddingLookup(fieldName string, index Index) (tensor.Tensor, error) {
+	if s, ok := m.embeddings[fieldName]; ok {
+		return s.EmbeddingLookup(fieldName, index)
+	}
+	if m.base == nil {
+		return nil, fmt.Errorf("%w: %s", TensorNotFoundErr, fieldName)
+	}
+	return m.base.EmbeddingLookup(fieldName, index)
+}
+
+func (m *composed) GetTensor(fieldName string) tensor.Tensor {
+	if s, ok := m.tensors[fieldName]; ok {
+		return s.GetTensor(fieldName)
+	}
+	if m.base == nil {
+		return nil
+	}
+	return m.base.GetTensor(fieldName)
+}
+
+// VocabExt is the extension of vocabulary files, <field>.txt in the directory of TextVocabularies.
+const VocabExt = ".txt"
+
+// TextVocabularies reads vocabularies from text files of one feature per line,
+// the index of a feature is its line number from 0, like tensorflow's
+// categorical_column_with_vocabulary_file.
+type TextVocabularies struct {
+	mu     sync.RWMutex
+	fields map[string]map[string]int
+}
+
+func OpenTextVocabularies(dir string) (*TextVocabularies, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*"+VocabExt))
+	if err != nil {
+		return nil, err
+	}
+	v := &TextVocabularies{fields: make(map[string]map[string]int)}
+	for _, file := range files {
+		records, err := readVocabulary(file)
+		if err != nil {
+			return nil, err
+		}
+		v.fields[strings.TrimSuffix(filepath.Base(file), VocabExt)] = records
+	}
+	return v, nil
+}
+
+func readVocabulary(file string) (map[string]int, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	records := make(map[string]int)
+	scanner := bufio.NewScanner(f)
+	for i := 0; scanner.Scan(); i++ {
+		feat := strings.TrimRight(scanner.Text(), "\r")
+		if _, ok := records[feat]; !ok {
+			records[feat] = i
+		}
+	}
+	return records, scanner.Err()
+}
+
+// Fields returns the sorted names of the vocabularies.
+func (v *TextVocabularies) Fields() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	fields := make([]string, 0, len(v.fields))
+	for f := range v.fields {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Close releases the vocabularies, all features are mapped to -1 afterwards,
+// so the lookups fail by EmbeddingLookup.
+func (v *TextVocabularies) Close() error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.fields = make(map[string]map[string]int)
+	return nil
+}
+
+// IndexLookup maps features out of the vocabulary to defaultFeat, or to -1 if
+// neither is in it, which is out of range for EmbeddingLookup.
+func (v *TextVocabularies) IndexLookup(fieldName, defaultFeat string, featNames ...string) Index {
+	v.mu.RLock()
+	records := v.fields[fieldName]
+	v.mu.RUnlock()
+	defaultIdx, ok := records[defaultFeat]
+	if !ok {
+		defaultIdx = -1
+	}
+	indices := make(Index, len(featNames))
+	for i, feat := range featNames {
+		idx, ok := records[feat]
+		if !ok {
+			idx = defaultIdx
+		}
+		indices[i] = idx
+	}
+	return indices
+}
@@ -0,0 +1,45 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 21:00
+ */
+
+package params
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestTextVocabulariesClose(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "x"+VocabExt), []byte("a\nb\r\na\nc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	v, err := OpenTextVocabularies(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := v.IndexLookup("x", "c", "a", "b", "z"); !reflect.DeepEqual(got, Index{0, 1, 3}) {
+		t.Fatalf("got %v", got)
+	}
+
+	// lookups race with Close
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				v.IndexLookup("x", "c", "a")
+			}
+		}()
+	}
+	v.Close()
+	wg.Wait()
+	if got := v.IndexLookup("x", "c", "a", "z"); !reflect.DeepEqual(got, Index{-1, -1}) {
+		t.Fatalf("got %v after Close", got)
+	}
+}
@@ -0,0 +1,37 @@
+# -*- coding: utf-8 -*-
+
+"""
+Writes the golden model file read by params/golden_test.go with python/exporter.py,
+in the environment pinned by requirements.txt next to it.
+
+    cd go-serving && pip install -r params/testdata/requirements.txt && python params/testdata/golden.py
+"""
+
+import os
+import sys
+
+import numpy as np
+import tensorflow as tf
+
+sys.path.insert(0, os.path.join(os.path.dirname(os.path.abspath(__file__)), "..", ".."))
+
+from python.exporter import export_model  # noqa: E402
+
+VERSION = 1650000000
+
+columns = [
+    tf.feature_column.categorical_column_with_identity("age", 3, default_value=0),
+    tf.feature_column.embedding_column(
+        tf.feature_column.categorical_column_with_vocabulary_list("city", ["beijing", "shanghai"], num_oov_buckets=1),
+        2),
+]
+# fixed values instead of training, so the golden test can assert them
+variables = {
+    "linear/age/weights": np.array([[0.5, 1.], [-1.25, 0.], [2., -2.]], dtype=np.float64),
+    "linear/city_embedding/embedding_weights": np.array([[1., 2.], [3., 4.], [0., -0.5]], dtype=np.float32),
+    "linear/bias_weights": np.array([0.25], dtype=np.float32),
+}
+
+if __name__ == "__main__":
+    export_model("golden", columns, variables, version=VERSION, units=2,
+                 export_dir=os.path.dirname(os.path.abspath(__file__)))
@@ -0,0 +1,155 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/23 14:40
+ */
+
+package params
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"gorgonia.org/tensor"
+)
+
+// TieredStore keeps the hot rows of a RowStore in an LRU cache, so only they
+// are resident for tables too large for memory.
+// It is an EmbeddingStore of a Composite.
+type TieredStore struct {
+	store   RowStore
+	observe func(table string, hits, misses int)
+
+	mu      sync.Mutex
+	maxRows int
+	ll      *list.List
+	rows    map[rowKey]*list.Element
+}
+
+type rowKey struct {
+	table string
+	id    int
+}
+
+type cachedRow struct {
+	key  rowKey
+	data []float32
+}
+
+// NewTieredStore caches at most maxRows rows of store, observe is called with
+// the hits and misses of every lookup if not nil.
+func NewTieredStore(store RowStore, maxRows int, observe func(table string, hits, misses int)) *TieredStore {
+	if observe == nil {
+		observe = func(string, int, int) {}
+	}
+	return &TieredStore{
+		store:   store,
+		observe: observe,
+		maxRows: maxRows,
+		ll:      list.New(),
+		rows:    make(map[rowKey]*list.Element),
+	}
+}
+
+// Meta returns base whose embedding tables are looked up in the store if it
+// has them. Cached rows are shared by all the versions of base.
+func (s *TieredStore) Meta(base Meta) Meta {
+	return &tieredMeta{Meta: base, store: s}
+}
+
+// EmbeddingLookup gathers the rows of index from the table, it reads the missed
+// rows from the store at once.
+func (s *TieredStore) EmbeddingLookup(table string, index Index) (tensor.Tensor, error) {
+	if len(index) == 0 {
+		return nil, EmptyIndexErr
+	}
+	rows, dim, ok := s.store.Shape(table)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", TensorNotFoundErr, table)
+	}
+	backing := make([]float32, len(index)*dim)
+	var missed []int // positions in index
+	s.mu.Lock()
+	for i, idx := range index {
+		if idx < 0 || idx >= rows {
+			s.mu.Unlock()
+			return nil, fmt.Errorf("%w: %s[%d], rows %d", IndexOutOfRangeErr, table, idx, rows)
+		}
+		if e, ok := s.rows[rowKey{table, idx}]; ok {
+			s.ll.MoveToFront(e)
+			copy(backing[i*dim:(i+1)*dim], e.Value.(*cachedRow).data)
+			continue
+		}
+		missed = append(missed, i)
+	}
+	s.mu.Unlock()
+	s.observe(table, len(index)-len(missed), len(missed))
+	if len(missed) == 0 {
+		return tensor.New(tensor.WithBacking(backing), tensor.WithShape(len(index), dim)), nil
+	}
+
+	ids := make([]int, len(missed))
+	for j, i := range missed {
+		ids[j] = index[i]
+	}
+	fetched := make([]float32, len(ids)*dim)
+	if err := s.store.ReadRows(table, ids, fetched); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	for j, i := range missed {
+		row := fetched[j*dim : (j+1)*dim : (j+1)*dim]
+		copy(backing[i*dim:(i+1)*dim], row)
+		s.put(rowKey{table, ids[j]}, row)
+	}
+	s.mu.Unlock()
+	return tensor.New(tensor.WithBacking(backing), tensor.WithShape(len(index), dim)), nil
+}
+
+func (s *TieredStore) put(key rowKey, data []float32) {
+	if s.maxRows <= 0 {
+		return
+	}
+	if e, ok := s.rows[key]; ok {
+		s.ll.MoveToFront(e)
+		return
+	}
+	s.rows[key] = s.ll.PushFront(&cachedRow{key: key, data: data})
+	for s.ll.Len() > s.maxRows {
+		e := s.ll.Back()
+		s.ll.Remove(e)
+		delete(s.rows, e.Value.(*cachedRow).key)
+	}
+}
+
+// Len returns the number of cached rows.
+func (s *TieredStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.ll.Len()
+}
+
+// Purge drops the cached rows, e.g. after the rows of the store are updated.
+func (s *TieredStore) Purge() {
+	s.mu.Lock()
+	s.ll.Init()
+	s.rows = make(map[rowKey]*list.Element)
+	s.mu.Unlock()
+}
+
+func (s *TieredStore) Close() error {
+	s.Purge()
+	return s.store.Close()
+}
+
+type tieredMeta struct {
+	Meta
+	store *TieredStore
+}
+
+func (m *tieredMeta) EmbeddingLookup(fieldName string, index Index) (tensor.Tensor, error) {
+	if _, _, ok := m.store.store.Shape(fieldName); ok {
+		return m.store.EmbeddingLookup(fieldName, index)
+	}
+	return m.Meta.EmbeddingLookup(fieldName, index)
+}
@@ -0,0 +1,235 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/6 10:12
+ */
+
+package params
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+// Writer produces a model file: header | data | index | footer.
+type Writer struct {
+	modelName string
+	version   uint64
+	data      *proto.Data
+	index     *proto.Index
+}
+
+func NewWriter(modelName string, version uint64) *Writer {
+	return &Writer{
+		modelName: modelName,
+		version:   version,
+		data:      &proto.Data{Data: make(map[string]*proto.Tensor)},
+		index:     &proto.Index{Embeddings: make(map[string]*proto.Field)},
+	}
+}
+
+// FromParams returns a Writer holding all tensors and fields of the loaded params.
+func FromParams(m *Params) (*Writer, error) {
+	w := NewWriter(m.ModelName(), m.Version())
+	for k, v := range m.tensors {
+		if err := w.AddDense(k, v); err != nil {
+			return nil, err
+		}
+	}
+	for k, v := range m.quantized {
+		w.AddTensor(k, v.proto())
+	}
+	for k, v := range m.chunked {
+		pt, err := v.proto()
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", k, err)
+		}
+		w.AddTensor(k, pt)
+	}
+	for k := range m.index {
+		f, _ := m.GetField(k)
+		w.AddField(f)
+	}
+	if m.IsDelta() {
+		w.data.BaseVersion = m.base
+	}
+	return w, nil
+}
+
+// NewDeltaWriter returns a Writer of a delta file updating the full file of base, see AddRows.
+func NewDeltaWriter(modelName string, version, base uint64) *Writer {
+	w := NewWriter(modelName, version)
+	w.data.BaseVersion = base
+	return w
+}
+
+func (w *Writer) SetModelName(modelName string) { w.modelName = modelName }
+
+func (w *Writer) SetVersion(version uint64) { w.version = version }
+
+func (w *Writer) AddTensor(name string, t *proto.Tensor) {
+	w.data.Data[name] = t
+}
+
+func (w *Writer) AddDense(name string, t *tensor.Dense) error {
+	pt, err := Encode(t)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	w.AddTensor(name, pt)
+	return nil
+}
+
+// AddQuantized adds a 2-D float32 embedding table quantized to DT_INT8 or DT_HALF.
+func (w *Writer) AddQuantized(name string, t *tensor.Dense, dtype proto.DataType) error {
+	pt, err := Quantize(t, dtype)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	w.AddTensor(name, pt)
+	return nil
+}
+
+// AddRows adds the changed rows of a 2-D float32 embedding table to a delta
+// file, ids can be beyond the rows of the base table to append the rows of the
+// records added to the vocabulary of name by the same delta.
+func (w *Writer) AddRows(name string, ids []int, rows *tensor.Dense) error {
+	if rows.Dims() != 2 || rows.Shape()[0] != len(ids) {
+		return fmt.Errorf("%s: %w: %d ids of rows %v", name, InvalidDeltaErr, len(ids), rows.Shape())
+	}
+	if err := w.AddDense(name, rows); err != nil {
+		return err
+	}
+	w.addIDs(name, ids)
+	return nil
+}
+
+// AddQuantizedRows is AddRows of a DT_INT8 or DT_HALF table, rows are kept quantized.
+func (w *Writer) AddQuantizedRows(name string, ids []int, rows *proto.Tensor) error {
+	if _, err := newQuantizedTable(rows); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if int(rows.TensorShape[0]) != len(ids) {
+		return fmt.Errorf("%s: %w: %d ids of rows %v", name, InvalidDeltaErr, len(ids), rows.TensorShape)
+	}
+	w.AddTensor(name, rows)
+	w.addIDs(name, ids)
+	return nil
+}
+
+func (w *Writer) addIDs(name string, ids []int) {
+	pt := &proto.Tensor{Dtype: proto.DataType_DT_INT32, TensorShape: []int32{int32(len(ids))}}
+	for _, id := range ids {
+		pt.IntVal = append(pt.IntVal, int32(id))
+	}
+	w.AddTensor(name+DeltaIDsSuffix, pt)
+}
+
+func (w *Writer) AddField(f *proto.Field) {
+	w.index.Embeddings[f.Name] = f
+}
+
+func (w *Writer) header() []byte {
+	buf := make([]byte, len(w.modelName)+10)
+	copy(buf, w.modelName)
+	binary.PutUvarint(buf[len(w.modelName):], w.version)
+	return buf
+}
+
+func footerOf(header, data, index []byte) []byte {
+	buf := make([]byte, FooterSize)
+	binary.PutUvarint(buf[:10], uint64(len(header)))
+	binary.PutUvarint(buf[10:20], uint64(len(header)+len(data)))
+	binary.LittleEndian.PutUint32(buf[20:24], Checksum(header))
+	binary.LittleEndian.PutUint32(buf[24:28], Checksum(data))
+	binary.LittleEndian.PutUint32(buf[28:32], Checksum(index))
+	buf[32] = FormatVersion
+	copy(buf[33:], Magic)
+	return buf
+}
+
+func (w *Writer) WriteTo(out io.Writer) (int64, error) {
+	data, err := w.data.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	index, err := w.index.Marshal()
+	if err != nil {
+		return 0, err
+	}
+	header := w.header()
+
+	var total int64
+	for _, section := range [][]byte{header, data, index, footerOf(header, data, index)} {
+		n, err := out.Write(section)
+		total += int64(n)
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+// WriteFile writes the model file, the file is removed if writing fails.
+func (w *Writer) WriteFile(file string) (err error) {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err != nil {
+			os.Remove(file)
+		}
+	}()
+
+	bw := bufio.NewWriter(f)
+	if _, err = w.WriteTo(bw); err != nil {
+		f.Close()
+		return err
+	}
+	if err = bw.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Encode converts a float32, int32 or string tensor.
+func Encode(t *tensor.Dense) (*proto.Tensor, error) {
+	pt := &proto.Tensor{}
+	for _, s := range t.Shape() {
+		pt.TensorShape = append(pt.TensorShape, int32(s))
+	}
+	if t.IsMaterializable() {
+		t = t.Materialize().(*tensor.Dense)
+	}
+
+	switch data := t.Data().(type) {
+	case []float32:
+		pt.Dtype = proto.DataType_DT_FLOAT
+		pt.FloatVal = data
+	case []int32:
+		pt.Dtype = proto.DataType_DT_INT32
+		pt.IntVal = data
+	case []string:
+		pt.Dtype = proto.DataType_DT_STRING
+		pt.StringVal = data
+	case float32:
+		pt.Dtype = proto.DataType_DT_FLOAT
+		pt.FloatVal = []float32{data}
+	case int32:
+		pt.Dtype = proto.DataType_DT_INT32
+		pt.IntVal = []int32{data}
+	case string:
+		pt.Dtype = proto.DataType_DT_STRING
+		pt.StringVal = []string{data}
+	default:
+		return nil, fmt.Errorf("%w: %v", UnsupportedDtypeErr, t.Dtype())
+	}
+	return pt, nil
+}
@@ -30,6 +30,8 @@ const (
 	DataType_DT_FLOAT   DataType = 1
 	DataType_DT_INT32   DataType = 2
 	DataType_DT_STRING  DataType = 3
+	DataType_DT_INT8    DataType = 4
+	DataType_DT_HALF    DataType = 5
 )
 
 var DataType_name = map[int32]string{
@@ -37,6 +39,8 @@ var DataType_name = map[int32]string{
 	1: "DT_FLOAT",
 	2: "DT_INT32",
 	3: "DT_STRING",
+	4: "DT_INT8",
+	5: "DT_HALF",
 }
 
 var DataType_value = map[string]int32{
@@ -44,6 +48,8 @@ var DataType_value = map[string]int32{
 	"DT_FLOAT":   1,
 	"DT_INT32":   2,
 	"DT_STRING":  3,
+	"DT_INT8":    4,
+	"DT_HALF":    5,
 }
 
 func (x DataType) String() string {
@@ -60,6 +66,8 @@ type Tensor struct {
 	FloatVal    []float32 `protobuf:"fixed32,3,rep,packed,name=float_val,json=floatVal,proto3" json:"float_val,omitempty"`
 	IntVal      []int32   `protobuf:"varint,4,rep,packed,name=int_val,json=intVal,proto3" json:"int_val,omitempty"`
 	StringVal   []string  `protobuf:"bytes,5,rep,name=string_val,json=stringVal,proto3" json:"string_val,omitempty"`
+	ByteVal     []byte    `protobuf:"bytes,6,opt,name=byte_val,json=byteVal,proto3" json:"byte_val,omitempty"`
+	Scale       []float32 `protobuf:"fixed32,7,rep,packed,name=scale,proto3" json:"scale,omitempty"`
 }
 
 func (m *Tensor) Reset()         { *m = Tensor{} }
@@ -130,8 +138,23 @@ func (m *Tensor) GetStringVal() []string {
 	return nil
 }
 
+func (m *Tensor) GetByteVal() []byte {
+	if m != nil {
+		return m.ByteVal
+	}
+	return nil
+}
+
+func (m *Tensor) GetScale() []float32 {
+	if m != nil {
+		return m.Scale
+	}
+	return nil
+}
+
 type Data struct {
-	Data map[string]*Tensor `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	Data        map[string]*Tensor `protobuf:"bytes,1,rep,name=data,proto3" json:"data,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
+	BaseVersion uint64             `protobuf:"varint,2,opt,name=base_version,json=baseVersion,proto3" json:"base_version,omitempty"`
 }
 
 func (m *Data) Reset()         { *m = Data{} }
@@ -174,6 +197,13 @@ func (m *Data) GetData() map[string]*Tensor {
 	return nil
 }
 
+func (m *Data) GetBaseVersion() uint64 {
+	if m != nil {
+		return m.BaseVersion
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("proto.DataType", DataType_name, DataType_value)
 	proto.RegisterType((*Tensor)(nil), "proto.Tensor")
@@ -184,29 +214,32 @@ func init() {
 func init() { proto.RegisterFile("data.proto", fileDescriptor_871986018790d2fd) }
 
 var fileDescriptor_871986018790d2fd = []byte{
-	// 339 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0xcd, 0x4a, 0xfb, 0x40,
-	0x10, 0xc0, 0xb3, 0xd9, 0xa6, 0xff, 0x66, 0xfa, 0xf1, 0x0f, 0x0b, 0x42, 0x50, 0x8c, 0xb1, 0x22,
-	0x44, 0x0f, 0x3d, 0xa4, 0x17, 0xf1, 0xd6, 0x92, 0x56, 0x02, 0xa5, 0xc2, 0x76, 0xe9, 0x35, 0xac,
-	0x34, 0x6a, 0x31, 0x24, 0x21, 0x5d, 0x0b, 0x79, 0x0b, 0xdf, 0xc4, 0xd7, 0xf0, 0xd8, 0xa3, 0x47,
-	0x69, 0x5e, 0x44, 0xb2, 0x5b, 0xc5, 0xcb, 0x7e, 0xfc, 0x7e, 0x33, 0xbb, 0x33, 0x03, 0xb0, 0xe2,
-	0x82, 0x0f, 0xf2, 0x22, 0x13, 0x19, 0x31, 0xe4, 0xd6, 0x7f, 0x47, 0xd0, 0x64, 0x71, 0xba, 0xc9,
-	0x0a, 0x72, 0x09, 0xc6, 0x4a, 0x94, 0x79, 0x6c, 0x23, 0x17, 0x79, 0x3d, 0xff, 0xbf, 0x0a, 0x1c,
-	0x04, 0x5c, 0x70, 0x56, 0xe6, 0x31, 0x55, 0x96, 0x9c, 0x43, 0x47, 0xc8, 0x84, 0x68, 0xf3, 0xcc,
-	0xf3, 0xd8, 0xd6, 0x5d, 0xec, 0x19, 0xb4, 0xad, 0xd8, 0xa2, 0x46, 0xe4, 0x0c, 0xcc, 0xc7, 0x24,
-	0xe3, 0x22, 0xda, 0xf2, 0xc4, 0xc6, 0x2e, 0xf6, 0xf4, 0xb1, 0x6e, 0x21, 0xda, 0x92, 0x70, 0xc9,
-	0x13, 0x72, 0x02, 0xff, 0xd6, 0xa9, 0xd2, 0x8d, 0x3a, 0x5d, 0xea, 0xe6, 0x3a, 0x95, 0xf2, 0x14,
-	0x60, 0x23, 0x8a, 0x75, 0xfa, 0x24, 0xbd, 0xe1, 0x62, 0xcf, 0xa4, 0xa6, 0x22, 0x4b, 0x9e, 0xf4,
-	0x4b, 0x68, 0xd4, 0x25, 0x91, 0x2b, 0x68, 0xd4, 0xed, 0xd8, 0xc8, 0xc5, 0x5e, 0xdb, 0x3f, 0xfa,
-	0x53, 0xad, 0x5c, 0x26, 0xa9, 0x28, 0x4a, 0x2a, 0x43, 0x8e, 0xa7, 0x60, 0xfe, 0x22, 0x62, 0x01,
-	0x7e, 0x89, 0x4b, 0xd9, 0xa4, 0x49, 0xeb, 0x23, 0xb9, 0x00, 0x63, 0xcb, 0x93, 0xd7, 0xba, 0x15,
-	0xe4, 0xb5, 0xfd, 0xee, 0xe1, 0x29, 0x35, 0x16, 0xaa, 0xdc, 0xad, 0x7e, 0x83, 0xae, 0x27, 0xd0,
-	0xfa, 0x99, 0x06, 0xe9, 0x01, 0x04, 0x2c, 0x0a, 0xe7, 0xcb, 0xd1, 0x2c, 0x0c, 0x2c, 0x8d, 0x74,
-	0xa0, 0x15, 0xb0, 0x68, 0x3a, 0xbb, 0x1f, 0x31, 0x0b, 0x1d, 0x6e, 0xe1, 0x9c, 0x0d, 0x7d, 0x4b,
-	0x27, 0x5d, 0x30, 0x03, 0x16, 0x2d, 0x18, 0x0d, 0xe7, 0x77, 0x16, 0x1e, 0xdb, 0x1f, 0x7b, 0x07,
-	0xed, 0xf6, 0x0e, 0xfa, 0xda, 0x3b, 0xe8, 0xad, 0x72, 0xb4, 0x5d, 0xe5, 0x68, 0x9f, 0x95, 0xa3,
-	0x3d, 0x34, 0xe5, 0xcf, 0xc3, 0xef, 0x00, 0x00, 0x00, 0xff, 0xff, 0x72, 0x2f, 0x74, 0x97, 0xa9,
-	0x01, 0x00, 0x00,
+	// 398 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x90, 0xcf, 0x8e, 0xd3, 0x30,
+	0x10, 0xc6, 0xeb, 0xfc, 0x69, 0x93, 0x49, 0x77, 0xb1, 0x2c, 0x21, 0x19, 0x10, 0x21, 0x2c, 0x42,
+	0x0a, 0x1c, 0xf6, 0x90, 0xbd, 0xac, 0xb8, 0x75, 0x15, 0x0a, 0x91, 0xaa, 0x22, 0x79, 0xad, 0x5e,
+	0x23, 0x97, 0x35, 0x10, 0x11, 0x25, 0x51, 0x62, 0x2a, 0xe5, 0x2d, 0x78, 0x00, 0x1e, 0x88, 0xe3,
+	0x1e, 0x39, 0xa2, 0x96, 0x07, 0x41, 0xb6, 0x4b, 0xc5, 0xc5, 0xf6, 0x7c, 0xbf, 0xf9, 0x34, 0x9e,
+	0x0f, 0xe0, 0x4e, 0x28, 0x71, 0xd9, 0xf5, 0xad, 0x6a, 0x89, 0x6f, 0xae, 0x8b, 0x3f, 0x08, 0xa6,
+	0x5c, 0x36, 0x43, 0xdb, 0x93, 0x97, 0xe0, 0xdf, 0xa9, 0xb1, 0x93, 0x14, 0x25, 0x28, 0x3d, 0xcf,
+	0x1e, 0xd8, 0xc6, 0xcb, 0x5c, 0x28, 0xc1, 0xc7, 0x4e, 0x32, 0x4b, 0xc9, 0x73, 0x98, 0x2b, 0x63,
+	0x28, 0x87, 0x2f, 0xa2, 0x93, 0xd4, 0x49, 0xdc, 0xd4, 0x67, 0x91, 0xd5, 0x6e, 0xb5, 0x44, 0x9e,
+	0x41, 0xf8, 0xa9, 0x6e, 0x85, 0x2a, 0x77, 0xa2, 0xa6, 0x6e, 0xe2, 0xa6, 0xce, 0x8d, 0x83, 0x11,
+	0x0b, 0x8c, 0xb8, 0x11, 0x35, 0x79, 0x02, 0xb3, 0xaa, 0xb1, 0xd8, 0xd3, 0x76, 0x83, 0xa7, 0x55,
+	0x63, 0xe0, 0x53, 0x80, 0x41, 0xf5, 0x55, 0xf3, 0xd9, 0x70, 0x3f, 0x71, 0xd3, 0x90, 0x85, 0x56,
+	0xd1, 0xf8, 0x11, 0x04, 0xdb, 0x51, 0x49, 0x03, 0xa7, 0x09, 0x4a, 0xe7, 0x6c, 0xa6, 0x6b, 0x8d,
+	0x28, 0xf8, 0xc3, 0x47, 0x51, 0x4b, 0x3a, 0x3b, 0xcd, 0xb4, 0xc2, 0xc5, 0x0f, 0x04, 0x9e, 0x5e,
+	0x84, 0xbc, 0x02, 0x4f, 0x87, 0x40, 0x51, 0xe2, 0xa6, 0x51, 0xf6, 0xf0, 0xbf, 0x1d, 0xcd, 0xf1,
+	0xb6, 0x51, 0xfd, 0xc8, 0x4c, 0x8b, 0x5e, 0x74, 0x2b, 0x06, 0x59, 0xee, 0x64, 0x3f, 0x54, 0x6d,
+	0x43, 0x9d, 0x04, 0xa5, 0x1e, 0x8b, 0xb4, 0xb6, 0xb1, 0xd2, 0xe3, 0x25, 0x84, 0x27, 0x17, 0xc1,
+	0xe0, 0x7e, 0x95, 0xa3, 0x49, 0x2f, 0x64, 0xfa, 0x49, 0x5e, 0x80, 0xbf, 0x13, 0xf5, 0x37, 0x69,
+	0xac, 0x51, 0x76, 0x76, 0x9c, 0x66, 0xf3, 0x66, 0x96, 0xbd, 0x71, 0xae, 0xd1, 0xeb, 0x12, 0x82,
+	0x7f, 0x31, 0x93, 0x73, 0x80, 0x9c, 0x97, 0xc5, 0x7a, 0xb3, 0x58, 0x15, 0x39, 0x9e, 0x90, 0x39,
+	0x04, 0x39, 0x2f, 0x97, 0xab, 0x0f, 0x0b, 0x8e, 0xd1, 0xb1, 0x2a, 0xd6, 0xfc, 0x2a, 0xc3, 0x0e,
+	0x39, 0x83, 0x30, 0xe7, 0xe5, 0x2d, 0x67, 0xc5, 0xfa, 0x1d, 0x76, 0x49, 0x04, 0x33, 0x0b, 0xaf,
+	0xb1, 0x77, 0x2c, 0xde, 0x2f, 0x56, 0x4b, 0xec, 0xdf, 0xd0, 0x9f, 0xfb, 0x18, 0xdd, 0xef, 0x63,
+	0xf4, 0x7b, 0x1f, 0xa3, 0xef, 0x87, 0x78, 0x72, 0x7f, 0x88, 0x27, 0xbf, 0x0e, 0xf1, 0x64, 0x3b,
+	0x35, 0x7f, 0xba, 0xfa, 0x3b, 0x00, 0xfb, 0x7d, 0xd7, 0x45, 0x1c, 0x02, 0x00, 0x00,
 }
 
 func (m *Tensor) Marshal() (dAtA []byte, err error) {
@@ -229,6 +262,23 @@ func (m *Tensor) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if len(m.Scale) > 0 {
+		for iNdEx := len(m.Scale) - 1; iNdEx >= 0; iNdEx-- {
+			f1 := math.Float32bits(float32(m.Scale[iNdEx]))
+			i -= 4
+			encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(f1))
+		}
+		i = encodeVarintData(dAtA, i, uint64(len(m.Scale)*4))
+		i--
+		dAtA[i] = 0x3a
+	}
+	if len(m.ByteVal) > 0 {
+		i -= len(m.ByteVal)
+		copy(dAtA[i:], m.ByteVal)
+		i = encodeVarintData(dAtA, i, uint64(len(m.ByteVal)))
+		i--
+		dAtA[i] = 0x32
+	}
 	if len(m.StringVal) > 0 {
 		for iNdEx := len(m.StringVal) - 1; iNdEx >= 0; iNdEx-- {
 			i -= len(m.StringVal[iNdEx])
@@ -239,50 +289,50 @@ func (m *Tensor) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 		}
 	}
 	if len(m.IntVal) > 0 {
-		dAtA2 := make([]byte, len(m.IntVal)*10)
-		var j1 int
+		dAtA3 := make([]byte, len(m.IntVal)*10)
+		var j2 int
 		for _, num1 := range m.IntVal {
 			num := uint64(num1)
 			for num >= 1<<7 {
-				dAtA2[j1] = uint8(uint64(num)&0x7f | 0x80)
+				dAtA3[j2] = uint8(uint64(num)&0x7f | 0x80)
 				num >>= 7
-				j1++
+				j2++
 			}
-			dAtA2[j1] = uint8(num)
-			j1++
+			dAtA3[j2] = uint8(num)
+			j2++
 		}
-		i -= j1
-		copy(dAtA[i:], dAtA2[:j1])
-		i = encodeVarintData(dAtA, i, uint64(j1))
+		i -= j2
+		copy(dAtA[i:], dAtA3[:j2])
+		i = encodeVarintData(dAtA, i, uint64(j2))
 		i--
 		dAtA[i] = 0x22
 	}
 	if len(m.FloatVal) > 0 {
 		for iNdEx := len(m.FloatVal) - 1; iNdEx >= 0; iNdEx-- {
-			f3 := math.Float32bits(float32(m.FloatVal[iNdEx]))
+			f4 := math.Float32bits(float32(m.FloatVal[iNdEx]))
 			i -= 4
-			encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(f3))
+			encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(f4))
 		}
 		i = encodeVarintData(dAtA, i, uint64(len(m.FloatVal)*4))
 		i--
 		dAtA[i] = 0x1a
 	}
 	if len(m.TensorShape) > 0 {
-		dAtA5 := make([]byte, len(m.TensorShape)*10)
-		var j4 int
+		dAtA6 := make([]byte, len(m.TensorShape)*10)
+		var j5 int
 		for _, num1 := range m.TensorShape {
 			num := uint64(num1)
 			for num >= 1<<7 {
-				dAtA5[j4] = uint8(uint64(num)&0x7f | 0x80)
+				dAtA6[j5] = uint8(uint64(num)&0x7f | 0x80)
 				num >>= 7
-				j4++
+				j5++
 			}
-			dAtA5[j4] = uint8(num)
-			j4++
+			dAtA6[j5] = uint8(num)
+			j5++
 		}
-		i -= j4
-		copy(dAtA[i:], dAtA5[:j4])
-		i = encodeVarintData(dAtA, i, uint64(j4))
+		i -= j5
+		copy(dAtA[i:], dAtA6[:j5])
+		i = encodeVarintData(dAtA, i, uint64(j5))
 		i--
 		dAtA[i] = 0x12
 	}
@@ -314,6 +364,11 @@ func (m *Data) MarshalToSizedBuffer(dAtA []byte) (int, error) {
 	_ = i
 	var l int
 	_ = l
+	if m.BaseVersion != 0 {
+		i = encodeVarintData(dAtA, i, uint64(m.BaseVersion))
+		i--
+		dAtA[i] = 0x10
+	}
 	if len(m.Data) > 0 {
 		for k := range m.Data {
 			v := m.Data[k]
@@ -386,6 +441,13 @@ func (m *Tensor) Size() (n int) {
 			n += 1 + l + sovData(uint64(l))
 		}
 	}
+	l = len(m.ByteVal)
+	if l > 0 {
+		n += 1 + l + sovData(uint64(l))
+	}
+	if len(m.Scale) > 0 {
+		n += 1 + sovData(uint64(len(m.Scale)*4)) + len(m.Scale)*4
+	}
 	return n
 }
 
@@ -408,6 +470,9 @@ func (m *Data) Size() (n int) {
 			n += mapEntrySize + 1 + sovData(uint64(mapEntrySize))
 		}
 	}
+	if m.BaseVersion != 0 {
+		n += 1 + sovData(uint64(m.BaseVersion))
+	}
 	return n
 }
 
@@ -703,6 +768,94 @@ func (m *Tensor) Unmarshal(dAtA []byte) error {
 			}
 			m.StringVal = append(m.StringVal, string(dAtA[iNdEx:postIndex]))
 			iNdEx = postIndex
+		case 6:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ByteVal", wireType)
+			}
+			var byteLen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowData
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				byteLen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if byteLen < 0 {
+				return ErrInvalidLengthData
+			}
+			postIndex := iNdEx + byteLen
+			if postIndex < 0 {
+				return ErrInvalidLengthData
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.ByteVal = append(m.ByteVal[:0], dAtA[iNdEx:postIndex]...)
+			if m.ByteVal == nil {
+				m.ByteVal = []byte{}
+			}
+			iNdEx = postIndex
+		case 7:
+			if wireType == 5 {
+				var v uint32
+				if (iNdEx + 4) > l {
+					return io.ErrUnexpectedEOF
+				}
+				v = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+				iNdEx += 4
+				v2 := float32(math.Float32frombits(v))
+				m.Scale = append(m.Scale, v2)
+			} else if wireType == 2 {
+				var packedLen int
+				for shift := uint(0); ; shift += 7 {
+					if shift >= 64 {
+						return ErrIntOverflowData
+					}
+					if iNdEx >= l {
+						return io.ErrUnexpectedEOF
+					}
+					b := dAtA[iNdEx]
+					iNdEx++
+					packedLen |= int(b&0x7F) << shift
+					if b < 0x80 {
+						break
+					}
+				}
+				if packedLen < 0 {
+					return ErrInvalidLengthData
+				}
+				postIndex := iNdEx + packedLen
+				if postIndex < 0 {
+					return ErrInvalidLengthData
+				}
+				if postIndex > l {
+					return io.ErrUnexpectedEOF
+				}
+				var elementCount int
+				elementCount = packedLen / 4
+				if elementCount != 0 && len(m.Scale) == 0 {
+					m.Scale = make([]float32, 0, elementCount)
+				}
+				for iNdEx < postIndex {
+					var v uint32
+					if (iNdEx + 4) > l {
+						return io.ErrUnexpectedEOF
+					}
+					v = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+					iNdEx += 4
+					v2 := float32(math.Float32frombits(v))
+					m.Scale = append(m.Scale, v2)
+				}
+			} else {
+				return fmt.Errorf("proto: wrong wireType = %d for field Scale", wireType)
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipData(dAtA[iNdEx:])
@@ -882,6 +1035,25 @@ func (m *Data) Unmarshal(dAtA []byte) error {
 			}
 			m.Data[mapkey] = mapvalue
 			iNdEx = postIndex
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field BaseVersion", wireType)
+			}
+			m.BaseVersion = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowData
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.BaseVersion |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
 		default:
 			iNdEx = preIndex
 			skippy, err := skipData(dAtA[iNdEx:])
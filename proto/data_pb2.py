@@ -20,7 +20,7 @@ DESCRIPTOR = _descriptor.FileDescriptor(
   syntax='proto3',
   serialized_options=None,
   create_key=_descriptor._internal_create_key,
-  serialized_pb=b'\n\ndata.proto\x12\x05proto\"~\n\x06Tensor\x12\x1e\n\x05\x64type\x18\x01 \x01(\x0e\x32\x0f.proto.DataType\x12\x14\n\x0ctensor_shape\x18\x02 \x03(\x05\x12\x15\n\tfloat_val\x18\x03 \x03(\x02\x42\x02\x10\x01\x12\x13\n\x07int_val\x18\x04 \x03(\x05\x42\x02\x10\x01\x12\x12\n\nstring_val\x18\x05 \x03(\t\"g\n\x04\x44\x61ta\x12#\n\x04\x64\x61ta\x18\x01 \x03(\x0b\x32\x15.proto.Data.DataEntry\x1a:\n\tDataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1c\n\x05value\x18\x02 \x01(\x0b\x32\r.proto.Tensor:\x02\x38\x01*E\n\x08\x44\x61taType\x12\x0e\n\nDT_INVALID\x10\x00\x12\x0c\n\x08\x44T_FLOAT\x10\x01\x12\x0c\n\x08\x44T_INT32\x10\x02\x12\r\n\tDT_STRING\x10\x03\x62\x06proto3'
+  serialized_pb=b'\n\ndata.proto\x12\x05proto\"\xa3\x01\n\x06Tensor\x12\x1e\n\x05\x64type\x18\x01 \x01(\x0e\x32\x0f.proto.DataType\x12\x14\n\x0ctensor_shape\x18\x02 \x03(\x05\x12\x15\n\tfloat_val\x18\x03 \x03(\x02\x42\x02\x10\x01\x12\x13\n\x07int_val\x18\x04 \x03(\x05\x42\x02\x10\x01\x12\x12\n\nstring_val\x18\x05 \x03(\t\x12\x10\n\x08\x62yte_val\x18\x06 \x01(\x0c\x12\x11\n\x05scale\x18\x07 \x03(\x02\x42\x02\x10\x01\"}\n\x04\x44\x61ta\x12#\n\x04\x64\x61ta\x18\x01 \x03(\x0b\x32\x15.proto.Data.DataEntry\x12\x14\n\x0c\x62\x61se_version\x18\x02 \x01(\x04\x1a:\n\tDataEntry\x12\x0b\n\x03key\x18\x01 \x01(\t\x12\x1c\n\x05value\x18\x02 \x01(\x0b\x32\r.proto.Tensor:\x02\x38\x01*_\n\x08\x44\x61taType\x12\x0e\n\nDT_INVALID\x10\x00\x12\x0c\n\x08\x44T_FLOAT\x10\x01\x12\x0c\n\x08\x44T_INT32\x10\x02\x12\r\n\tDT_STRING\x10\x03\x12\x0b\n\x07\x44T_INT8\x10\x04\x12\x0b\n\x07\x44T_HALF\x10\x05\x62\x06proto3'
 )
 
 _DATATYPE = _descriptor.EnumDescriptor(
@@ -50,11 +50,21 @@ _DATATYPE = _descriptor.EnumDescriptor(
       serialized_options=None,
       type=None,
       create_key=_descriptor._internal_create_key),
+    _descriptor.EnumValueDescriptor(
+      name='DT_INT8', index=4, number=4,
+      serialized_options=None,
+      type=None,
+      create_key=_descriptor._internal_create_key),
+    _descriptor.EnumValueDescriptor(
+      name='DT_HALF', index=5, number=5,
+      serialized_options=None,
+      type=None,
+      create_key=_descriptor._internal_create_key),
   ],
   containing_type=None,
   serialized_options=None,
-  serialized_start=254,
-  serialized_end=323,
+  serialized_start=314,
+  serialized_end=409,
 )
 _sym_db.RegisterEnumDescriptor(_DATATYPE)
 
@@ -63,6 +73,8 @@ DT_INVALID = 0
 DT_FLOAT = 1
 DT_INT32 = 2
 DT_STRING = 3
+DT_INT8 = 4
+DT_HALF = 5
 
 
 
@@ -109,6 +121,20 @@ _TENSOR = _descriptor.Descriptor(
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       serialized_options=None, file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
+    _descriptor.FieldDescriptor(
+      name='byte_val', full_name='proto.Tensor.byte_val', index=5,
+      number=6, type=12, cpp_type=9, label=1,
+      has_default_value=False, default_value=b"",
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      serialized_options=None, file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
+    _descriptor.FieldDescriptor(
+      name='scale', full_name='proto.Tensor.scale', index=6,
+      number=7, type=2, cpp_type=6, label=3,
+      has_default_value=False, default_value=[],
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      serialized_options=b'\020\001', file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
   ],
   extensions=[
   ],
@@ -121,8 +147,8 @@ _TENSOR = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=21,
-  serialized_end=147,
+  serialized_start=22,
+  serialized_end=185,
 )
 
 
@@ -160,8 +186,8 @@ _DATA_DATAENTRY = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=194,
-  serialized_end=252,
+  serialized_start=254,
+  serialized_end=312,
 )
 
 _DATA = _descriptor.Descriptor(
@@ -179,6 +205,13 @@ _DATA = _descriptor.Descriptor(
       message_type=None, enum_type=None, containing_type=None,
       is_extension=False, extension_scope=None,
       serialized_options=None, file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
+    _descriptor.FieldDescriptor(
+      name='base_version', full_name='proto.Data.base_version', index=1,
+      number=2, type=4, cpp_type=4, label=1,
+      has_default_value=False, default_value=0,
+      message_type=None, enum_type=None, containing_type=None,
+      is_extension=False, extension_scope=None,
+      serialized_options=None, file=DESCRIPTOR,  create_key=_descriptor._internal_create_key),
   ],
   extensions=[
   ],
@@ -191,8 +224,8 @@ _DATA = _descriptor.Descriptor(
   extension_ranges=[],
   oneofs=[
   ],
-  serialized_start=149,
-  serialized_end=252,
+  serialized_start=187,
+  serialized_end=312,
 )
 
 _TENSOR.fields_by_name['dtype'].enum_type = _DATATYPE
@@ -229,5 +262,6 @@ _sym_db.RegisterMessage(Data.DataEntry)
 
 _TENSOR.fields_by_name['float_val']._options = None
 _TENSOR.fields_by_name['int_val']._options = None
+_TENSOR.fields_by_name['scale']._options = None
 _DATA_DATAENTRY._options = None
 # @@protoc_insertion_point(module_scope)
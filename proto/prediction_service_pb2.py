@@ -0,0 +1,21 @@
+# -*- coding: utf-8 -*-
+# Generated by the protocol buffer compiler.  DO NOT EDIT!
+# source: prediction_service.proto
+"""Generated protocol buffer code."""
+from google.protobuf import descriptor as _descriptor
+from google.protobuf import descriptor_pool as _descriptor_pool
+from google.protobuf import symbol_database as _symbol_database
+from google.protobuf.internal import builder as _builder
+# @@protoc_insertion_point(imports)
+
+_sym_db = _symbol_database.Default()
+
+from . import data_pb2 as data__pb2
+
+
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x18prediction_service.proto\x12\x05proto\x1a\ndata.proto\"\xb4\x01\n\x0ePredictRequest\x12\x1d\n\nmodel_name\x18\x01 \x01(\x09R\x09modelName\x129\n\x06inputs\x18\x02 \x03(\x0b2!.proto.PredictRequest.InputsEntryR\x06inputs\x1aH\n\x0bInputsEntry\x12\x10\n\x03key\x18\x01 \x01(\x09R\x03key\x12#\n\x05value\x18\x02 \x01(\x0b2\x0d.proto.TensorR\x05value:\x028\x01\"|\n\x0fPredictResponse\x12\x1d\n\nmodel_name\x18\x01 \x01(\x09R\x09modelName\x12#\n\x0dmodel_version\x18\x02 \x01(\x04R\x0cmodelVersion\x12%\n\x06output\x18\x03 \x01(\x0b2\x0d.proto.TensorR\x06output2M\n\x11PredictionService\x128\n\x07Predict\x12\x15.proto.PredictRequest\x1a\x16.proto.PredictResponseb\x06proto3')
+
+_globals = globals()
+_builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
+_builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'prediction_service_pb2', _globals)
+# @@protoc_insertion_point(module_scope)
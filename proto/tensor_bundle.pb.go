@@ -0,0 +1,1639 @@
+// Code generated by protoc-gen-gogo. DO NOT EDIT.
+// source: tensor_bundle.proto
+
+package proto
+
+import (
+	encoding_binary "encoding/binary"
+	fmt "fmt"
+	proto "github.com/gogo/protobuf/proto"
+	io "io"
+	math "math"
+	math_bits "math/bits"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.GoGoProtoPackageIsVersion3 // please upgrade the proto package
+
+type BundleHeaderProto_Endianness int32
+
+const (
+	BundleHeaderProto_LITTLE BundleHeaderProto_Endianness = 0
+	BundleHeaderProto_BIG    BundleHeaderProto_Endianness = 1
+)
+
+var BundleHeaderProto_Endianness_name = map[int32]string{
+	0: "LITTLE",
+	1: "BIG",
+}
+
+var BundleHeaderProto_Endianness_value = map[string]int32{
+	"LITTLE": 0,
+	"BIG":    1,
+}
+
+func (x BundleHeaderProto_Endianness) String() string {
+	return proto.EnumName(BundleHeaderProto_Endianness_name, int32(x))
+}
+
+func (BundleHeaderProto_Endianness) EnumDescriptor() ([]byte, []int) {
+	return fileDescriptor_2bf7a626254a7cdc, []int{2, 0}
+}
+
+type TensorShapeProto struct {
+	Dim         []*TensorShapeProto_Dim `protobuf:"bytes,2,rep,name=dim,proto3" json:"dim,omitempty"`
+	UnknownRank bool                    `protobuf:"varint,3,opt,name=unknown_rank,json=unknownRank,proto3" json:"unknown_rank,omitempty"`
+}
+
+func (m *TensorShapeProto) Reset()         { *m = TensorShapeProto{} }
+func (m *TensorShapeProto) String() string { return proto.CompactTextString(m) }
+func (*TensorShapeProto) ProtoMessage()    {}
+func (*TensorShapeProto) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2bf7a626254a7cdc, []int{0}
+}
+func (m *TensorShapeProto) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TensorShapeProto) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TensorShapeProto.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TensorShapeProto) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TensorShapeProto.Merge(m, src)
+}
+func (m *TensorShapeProto) XXX_Size() int {
+	return m.Size()
+}
+func (m *TensorShapeProto) XXX_DiscardUnknown() {
+	xxx_messageInfo_TensorShapeProto.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TensorShapeProto proto.InternalMessageInfo
+
+func (m *TensorShapeProto) GetDim() []*TensorShapeProto_Dim {
+	if m != nil {
+		return m.Dim
+	}
+	return nil
+}
+
+func (m *TensorShapeProto) GetUnknownRank() bool {
+	if m != nil {
+		return m.UnknownRank
+	}
+	return false
+}
+
+type TensorShapeProto_Dim struct {
+	Size_ int64  `protobuf:"varint,1,opt,name=size,proto3" json:"size,omitempty"`
+	Name  string `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+}
+
+func (m *TensorShapeProto_Dim) Reset()         { *m = TensorShapeProto_Dim{} }
+func (m *TensorShapeProto_Dim) String() string { return proto.CompactTextString(m) }
+func (*TensorShapeProto_Dim) ProtoMessage()    {}
+func (*TensorShapeProto_Dim) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2bf7a626254a7cdc, []int{0, 0}
+}
+func (m *TensorShapeProto_Dim) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TensorShapeProto_Dim) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TensorShapeProto_Dim.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TensorShapeProto_Dim) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TensorShapeProto_Dim.Merge(m, src)
+}
+func (m *TensorShapeProto_Dim) XXX_Size() int {
+	return m.Size()
+}
+func (m *TensorShapeProto_Dim) XXX_DiscardUnknown() {
+	xxx_messageInfo_TensorShapeProto_Dim.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TensorShapeProto_Dim proto.InternalMessageInfo
+
+func (m *TensorShapeProto_Dim) GetSize_() int64 {
+	if m != nil {
+		return m.Size_
+	}
+	return 0
+}
+
+func (m *TensorShapeProto_Dim) GetName() string {
+	if m != nil {
+		return m.Name
+	}
+	return ""
+}
+
+type TensorSliceProto struct {
+	Extent []*TensorSliceProto_Extent `protobuf:"bytes,1,rep,name=extent,proto3" json:"extent,omitempty"`
+}
+
+func (m *TensorSliceProto) Reset()         { *m = TensorSliceProto{} }
+func (m *TensorSliceProto) String() string { return proto.CompactTextString(m) }
+func (*TensorSliceProto) ProtoMessage()    {}
+func (*TensorSliceProto) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2bf7a626254a7cdc, []int{1}
+}
+func (m *TensorSliceProto) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TensorSliceProto) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TensorSliceProto.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TensorSliceProto) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TensorSliceProto.Merge(m, src)
+}
+func (m *TensorSliceProto) XXX_Size() int {
+	return m.Size()
+}
+func (m *TensorSliceProto) XXX_DiscardUnknown() {
+	xxx_messageInfo_TensorSliceProto.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TensorSliceProto proto.InternalMessageInfo
+
+func (m *TensorSliceProto) GetExtent() []*TensorSliceProto_Extent {
+	if m != nil {
+		return m.Extent
+	}
+	return nil
+}
+
+type TensorSliceProto_Extent struct {
+	Start int64 `protobuf:"varint,1,opt,name=start,proto3" json:"start,omitempty"`
+	// Types that are valid to be assigned to HasLength:
+	//	*TensorSliceProto_Extent_Length
+	HasLength isTensorSliceProto_Extent_HasLength `protobuf_oneof:"has_length"`
+}
+
+func (m *TensorSliceProto_Extent) Reset()         { *m = TensorSliceProto_Extent{} }
+func (m *TensorSliceProto_Extent) String() string { return proto.CompactTextString(m) }
+func (*TensorSliceProto_Extent) ProtoMessage()    {}
+func (*TensorSliceProto_Extent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2bf7a626254a7cdc, []int{1, 0}
+}
+func (m *TensorSliceProto_Extent) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *TensorSliceProto_Extent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_TensorSliceProto_Extent.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *TensorSliceProto_Extent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_TensorSliceProto_Extent.Merge(m, src)
+}
+func (m *TensorSliceProto_Extent) XXX_Size() int {
+	return m.Size()
+}
+func (m *TensorSliceProto_Extent) XXX_DiscardUnknown() {
+	xxx_messageInfo_TensorSliceProto_Extent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_TensorSliceProto_Extent proto.InternalMessageInfo
+
+type isTensorSliceProto_Extent_HasLength interface {
+	isTensorSliceProto_Extent_HasLength()
+	MarshalTo([]byte) (int, error)
+	Size() int
+}
+
+type TensorSliceProto_Extent_Length struct {
+	Length int64 `protobuf:"varint,2,opt,name=length,proto3,oneof" json:"length,omitempty"`
+}
+
+func (*TensorSliceProto_Extent_Length) isTensorSliceProto_Extent_HasLength() {}
+
+func (m *TensorSliceProto_Extent) GetHasLength() isTensorSliceProto_Extent_HasLength {
+	if m != nil {
+		return m.HasLength
+	}
+	return nil
+}
+
+func (m *TensorSliceProto_Extent) GetStart() int64 {
+	if m != nil {
+		return m.Start
+	}
+	return 0
+}
+
+func (m *TensorSliceProto_Extent) GetLength() int64 {
+	if x, ok := m.GetHasLength().(*TensorSliceProto_Extent_Length); ok {
+		return x.Length
+	}
+	return 0
+}
+
+// XXX_OneofWrappers is for the internal use of the proto package.
+func (*TensorSliceProto_Extent) XXX_OneofWrappers() []interface{} {
+	return []interface{}{
+		(*TensorSliceProto_Extent_Length)(nil),
+	}
+}
+
+// the value of the empty key in the index file
+type BundleHeaderProto struct {
+	NumShards  int32                        `protobuf:"varint,1,opt,name=num_shards,json=numShards,proto3" json:"num_shards,omitempty"`
+	Endianness BundleHeaderProto_Endianness `protobuf:"varint,2,opt,name=endianness,proto3,enum=proto.BundleHeaderProto_Endianness" json:"endianness,omitempty"`
+}
+
+func (m *BundleHeaderProto) Reset()         { *m = BundleHeaderProto{} }
+func (m *BundleHeaderProto) String() string { return proto.CompactTextString(m) }
+func (*BundleHeaderProto) ProtoMessage()    {}
+func (*BundleHeaderProto) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2bf7a626254a7cdc, []int{2}
+}
+func (m *BundleHeaderProto) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *BundleHeaderProto) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_BundleHeaderProto.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *BundleHeaderProto) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BundleHeaderProto.Merge(m, src)
+}
+func (m *BundleHeaderProto) XXX_Size() int {
+	return m.Size()
+}
+func (m *BundleHeaderProto) XXX_DiscardUnknown() {
+	xxx_messageInfo_BundleHeaderProto.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BundleHeaderProto proto.InternalMessageInfo
+
+func (m *BundleHeaderProto) GetNumShards() int32 {
+	if m != nil {
+		return m.NumShards
+	}
+	return 0
+}
+
+func (m *BundleHeaderProto) GetEndianness() BundleHeaderProto_Endianness {
+	if m != nil {
+		return m.Endianness
+	}
+	return BundleHeaderProto_LITTLE
+}
+
+type BundleEntryProto struct {
+	Dtype   int32               `protobuf:"varint,1,opt,name=dtype,proto3" json:"dtype,omitempty"`
+	Shape   *TensorShapeProto   `protobuf:"bytes,2,opt,name=shape,proto3" json:"shape,omitempty"`
+	ShardId int32               `protobuf:"varint,3,opt,name=shard_id,json=shardId,proto3" json:"shard_id,omitempty"`
+	Offset  int64               `protobuf:"varint,4,opt,name=offset,proto3" json:"offset,omitempty"`
+	Size_   int64               `protobuf:"varint,5,opt,name=size,proto3" json:"size,omitempty"`
+	Crc32C  uint32              `protobuf:"fixed32,6,opt,name=crc32c,proto3" json:"crc32c,omitempty"`
+	Slices  []*TensorSliceProto `protobuf:"bytes,7,rep,name=slices,proto3" json:"slices,omitempty"`
+}
+
+func (m *BundleEntryProto) Reset()         { *m = BundleEntryProto{} }
+func (m *BundleEntryProto) String() string { return proto.CompactTextString(m) }
+func (*BundleEntryProto) ProtoMessage()    {}
+func (*BundleEntryProto) Descriptor() ([]byte, []int) {
+	return fileDescriptor_2bf7a626254a7cdc, []int{3}
+}
+func (m *BundleEntryProto) XXX_Unmarshal(b []byte) error {
+	return m.Unmarshal(b)
+}
+func (m *BundleEntryProto) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	if deterministic {
+		return xxx_messageInfo_BundleEntryProto.Marshal(b, m, deterministic)
+	} else {
+		b = b[:cap(b)]
+		n, err := m.MarshalToSizedBuffer(b)
+		if err != nil {
+			return nil, err
+		}
+		return b[:n], nil
+	}
+}
+func (m *BundleEntryProto) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_BundleEntryProto.Merge(m, src)
+}
+func (m *BundleEntryProto) XXX_Size() int {
+	return m.Size()
+}
+func (m *BundleEntryProto) XXX_DiscardUnknown() {
+	xxx_messageInfo_BundleEntryProto.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_BundleEntryProto proto.InternalMessageInfo
+
+func (m *BundleEntryProto) GetDtype() int32 {
+	if m != nil {
+		return m.Dtype
+	}
+	return 0
+}
+
+func (m *BundleEntryProto) GetShape() *TensorShapeProto {
+	if m != nil {
+		return m.Shape
+	}
+	return nil
+}
+
+func (m *BundleEntryProto) GetShardId() int32 {
+	if m != nil {
+		return m.ShardId
+	}
+	return 0
+}
+
+func (m *BundleEntryProto) GetOffset() int64 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *BundleEntryProto) GetSize_() int64 {
+	if m != nil {
+		return m.Size_
+	}
+	return 0
+}
+
+func (m *BundleEntryProto) GetCrc32C() uint32 {
+	if m != nil {
+		return m.Crc32C
+	}
+	return 0
+}
+
+func (m *BundleEntryProto) GetSlices() []*TensorSliceProto {
+	if m != nil {
+		return m.Slices
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterEnum("proto.BundleHeaderProto_Endianness", BundleHeaderProto_Endianness_name, BundleHeaderProto_Endianness_value)
+	proto.RegisterType((*TensorShapeProto)(nil), "proto.TensorShapeProto")
+	proto.RegisterType((*TensorShapeProto_Dim)(nil), "proto.TensorShapeProto.Dim")
+	proto.RegisterType((*TensorSliceProto)(nil), "proto.TensorSliceProto")
+	proto.RegisterType((*TensorSliceProto_Extent)(nil), "proto.TensorSliceProto.Extent")
+	proto.RegisterType((*BundleHeaderProto)(nil), "proto.BundleHeaderProto")
+	proto.RegisterType((*BundleEntryProto)(nil), "proto.BundleEntryProto")
+}
+
+func init() { proto.RegisterFile("tensor_bundle.proto", fileDescriptor_2bf7a626254a7cdc) }
+
+var fileDescriptor_2bf7a626254a7cdc = []byte{
+	// 457 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x74, 0x91, 0x41, 0x6f, 0xd3, 0x30,
+	0x14, 0xc7, 0xeb, 0x65, 0x71, 0xb7, 0xd7, 0x09, 0x15, 0x33, 0x0d, 0x33, 0x44, 0x94, 0x85, 0x4b,
+	0x2e, 0x0d, 0x52, 0x27, 0xf1, 0x01, 0xca, 0x0a, 0xab, 0xb4, 0x03, 0xf2, 0x7a, 0x8f, 0xbc, 0xc6,
+	0xa3, 0x51, 0x1b, 0xa7, 0x8a, 0x5d, 0xc1, 0xf8, 0x14, 0x08, 0x6e, 0x7c, 0x22, 0x8e, 0x3b, 0x72,
+	0x44, 0xed, 0x81, 0xaf, 0x81, 0xfa, 0x6c, 0x75, 0x83, 0x89, 0x53, 0xfc, 0x7f, 0xef, 0xff, 0x9e,
+	0x7f, 0xf1, 0x1f, 0x9e, 0x58, 0xa5, 0x4d, 0xdd, 0xe4, 0x57, 0x4b, 0x5d, 0xcc, 0x55, 0xb6, 0x68,
+	0x6a, 0x5b, 0xb3, 0x10, 0x3f, 0xc9, 0x37, 0x02, 0xdd, 0x31, 0xb6, 0x2f, 0xa7, 0x72, 0xa1, 0xde,
+	0x63, 0xaf, 0x07, 0x41, 0x51, 0x56, 0x7c, 0x27, 0x0e, 0xd2, 0x4e, 0xff, 0xb9, 0x1b, 0xc8, 0xfe,
+	0x75, 0x65, 0x67, 0x65, 0x25, 0x36, 0x3e, 0x76, 0x02, 0x07, 0x4b, 0x3d, 0xd3, 0xf5, 0x47, 0x9d,
+	0x37, 0x52, 0xcf, 0x78, 0x10, 0x93, 0x74, 0x4f, 0x74, 0x7c, 0x4d, 0x48, 0x3d, 0x3b, 0xee, 0x41,
+	0x70, 0x56, 0x56, 0x8c, 0xc1, 0xae, 0x29, 0x3f, 0x2b, 0x4e, 0x62, 0x92, 0x06, 0x02, 0xcf, 0x9b,
+	0x9a, 0x96, 0x95, 0xe2, 0x3b, 0x31, 0x49, 0xf7, 0x05, 0x9e, 0x93, 0xaf, 0x77, 0x54, 0xf3, 0x72,
+	0xe2, 0xa9, 0x5e, 0x03, 0x55, 0x9f, 0xac, 0xd2, 0x96, 0x13, 0x04, 0x8b, 0xfe, 0x06, 0xdb, 0x1a,
+	0xb3, 0x21, 0xba, 0x84, 0x77, 0x1f, 0xbf, 0x05, 0xea, 0x2a, 0xec, 0x10, 0x42, 0x63, 0x65, 0x63,
+	0xfd, 0xfd, 0x4e, 0x30, 0x0e, 0x74, 0xae, 0xf4, 0x07, 0x3b, 0x45, 0x84, 0xe0, 0xbc, 0x25, 0xbc,
+	0x1e, 0x1c, 0x00, 0x4c, 0xa5, 0xc9, 0x9d, 0x4a, 0xbe, 0x13, 0x78, 0x3c, 0xc0, 0x27, 0x3c, 0x57,
+	0xb2, 0x50, 0x8d, 0xa3, 0x7a, 0x01, 0xa0, 0x97, 0x55, 0x6e, 0xa6, 0xb2, 0x29, 0x0c, 0x2e, 0x0e,
+	0xc5, 0xbe, 0x5e, 0x56, 0x97, 0x58, 0x60, 0x6f, 0x00, 0x94, 0x2e, 0x4a, 0xa9, 0xb5, 0x32, 0x06,
+	0x2f, 0x78, 0xd4, 0x7f, 0xe9, 0xc1, 0x1f, 0x2c, 0xcb, 0x86, 0x5b, 0xab, 0xb8, 0x37, 0x96, 0x9c,
+	0x00, 0xdc, 0x75, 0x18, 0x00, 0xbd, 0x18, 0x8d, 0xc7, 0x17, 0xc3, 0x6e, 0x8b, 0xb5, 0x21, 0x18,
+	0x8c, 0xde, 0x75, 0x49, 0xf2, 0x9b, 0x40, 0xd7, 0xed, 0x1b, 0x6a, 0xdb, 0xdc, 0x38, 0xb6, 0x43,
+	0x08, 0x0b, 0x7b, 0xb3, 0x50, 0x1e, 0xcb, 0x09, 0xd6, 0x83, 0xd0, 0x6c, 0x52, 0x44, 0x9a, 0x4e,
+	0xff, 0xe9, 0x7f, 0xf2, 0x15, 0xce, 0xc5, 0x9e, 0xc1, 0x1e, 0xfe, 0x5c, 0x5e, 0x16, 0x98, 0x6c,
+	0x28, 0xda, 0xa8, 0x47, 0x05, 0x3b, 0x02, 0x5a, 0x5f, 0x5f, 0x1b, 0x65, 0xf9, 0x2e, 0x3e, 0xa8,
+	0x57, 0xdb, 0x98, 0xc3, 0x7b, 0x31, 0x1f, 0x01, 0x9d, 0x34, 0x93, 0xd3, 0xfe, 0x84, 0xd3, 0x98,
+	0xa4, 0x6d, 0xe1, 0x15, 0x7b, 0x05, 0xd4, 0x6c, 0xa2, 0x33, 0xbc, 0x1d, 0x07, 0x0f, 0x71, 0xb6,
+	0xa9, 0x0a, 0x6f, 0x1b, 0xf0, 0x1f, 0xab, 0x88, 0xdc, 0xae, 0x22, 0xf2, 0x6b, 0x15, 0x91, 0x2f,
+	0xeb, 0xa8, 0x75, 0xbb, 0x8e, 0x5a, 0x3f, 0xd7, 0x51, 0xeb, 0x8a, 0xe2, 0xe4, 0xe9, 0x9f, 0x01,
+	0x00, 0x4e, 0x2c, 0xab, 0x23, 0xf0, 0x02, 0x00, 0x00,
+}
+
+func (m *TensorShapeProto) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TensorShapeProto) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TensorShapeProto) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.UnknownRank {
+		i--
+		if m.UnknownRank {
+			dAtA[i] = 1
+		} else {
+			dAtA[i] = 0
+		}
+		i--
+		dAtA[i] = 0x18
+	}
+	if len(m.Dim) > 0 {
+		for iNdEx := len(m.Dim) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Dim[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTensorBundle(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x12
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *TensorShapeProto_Dim) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TensorShapeProto_Dim) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TensorShapeProto_Dim) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Name) > 0 {
+		i -= len(m.Name)
+		copy(dAtA[i:], m.Name)
+		i = encodeVarintTensorBundle(dAtA, i, uint64(len(m.Name)))
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Size_ != 0 {
+		i = encodeVarintTensorBundle(dAtA, i, uint64(m.Size_))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *TensorSliceProto) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TensorSliceProto) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TensorSliceProto) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Extent) > 0 {
+		for iNdEx := len(m.Extent) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Extent[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTensorBundle(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0xa
+		}
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *TensorSliceProto_Extent) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *TensorSliceProto_Extent) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TensorSliceProto_Extent) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.HasLength != nil {
+		{
+			size := m.HasLength.Size()
+			i -= size
+			if _, err := m.HasLength.MarshalTo(dAtA[i:]); err != nil {
+				return 0, err
+			}
+		}
+	}
+	if m.Start != 0 {
+		i = encodeVarintTensorBundle(dAtA, i, uint64(m.Start))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *TensorSliceProto_Extent_Length) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *TensorSliceProto_Extent_Length) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	i = encodeVarintTensorBundle(dAtA, i, uint64(m.Length))
+	i--
+	dAtA[i] = 0x10
+	return len(dAtA) - i, nil
+}
+func (m *BundleHeaderProto) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *BundleHeaderProto) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BundleHeaderProto) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if m.Endianness != 0 {
+		i = encodeVarintTensorBundle(dAtA, i, uint64(m.Endianness))
+		i--
+		dAtA[i] = 0x10
+	}
+	if m.NumShards != 0 {
+		i = encodeVarintTensorBundle(dAtA, i, uint64(m.NumShards))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func (m *BundleEntryProto) Marshal() (dAtA []byte, err error) {
+	size := m.Size()
+	dAtA = make([]byte, size)
+	n, err := m.MarshalToSizedBuffer(dAtA[:size])
+	if err != nil {
+		return nil, err
+	}
+	return dAtA[:n], nil
+}
+
+func (m *BundleEntryProto) MarshalTo(dAtA []byte) (int, error) {
+	size := m.Size()
+	return m.MarshalToSizedBuffer(dAtA[:size])
+}
+
+func (m *BundleEntryProto) MarshalToSizedBuffer(dAtA []byte) (int, error) {
+	i := len(dAtA)
+	_ = i
+	var l int
+	_ = l
+	if len(m.Slices) > 0 {
+		for iNdEx := len(m.Slices) - 1; iNdEx >= 0; iNdEx-- {
+			{
+				size, err := m.Slices[iNdEx].MarshalToSizedBuffer(dAtA[:i])
+				if err != nil {
+					return 0, err
+				}
+				i -= size
+				i = encodeVarintTensorBundle(dAtA, i, uint64(size))
+			}
+			i--
+			dAtA[i] = 0x3a
+		}
+	}
+	if m.Crc32C != 0 {
+		i -= 4
+		encoding_binary.LittleEndian.PutUint32(dAtA[i:], uint32(m.Crc32C))
+		i--
+		dAtA[i] = 0x35
+	}
+	if m.Size_ != 0 {
+		i = encodeVarintTensorBundle(dAtA, i, uint64(m.Size_))
+		i--
+		dAtA[i] = 0x28
+	}
+	if m.Offset != 0 {
+		i = encodeVarintTensorBundle(dAtA, i, uint64(m.Offset))
+		i--
+		dAtA[i] = 0x20
+	}
+	if m.ShardId != 0 {
+		i = encodeVarintTensorBundle(dAtA, i, uint64(m.ShardId))
+		i--
+		dAtA[i] = 0x18
+	}
+	if m.Shape != nil {
+		{
+			size, err := m.Shape.MarshalToSizedBuffer(dAtA[:i])
+			if err != nil {
+				return 0, err
+			}
+			i -= size
+			i = encodeVarintTensorBundle(dAtA, i, uint64(size))
+		}
+		i--
+		dAtA[i] = 0x12
+	}
+	if m.Dtype != 0 {
+		i = encodeVarintTensorBundle(dAtA, i, uint64(m.Dtype))
+		i--
+		dAtA[i] = 0x8
+	}
+	return len(dAtA) - i, nil
+}
+
+func encodeVarintTensorBundle(dAtA []byte, offset int, v uint64) int {
+	offset -= sovTensorBundle(v)
+	base := offset
+	for v >= 1<<7 {
+		dAtA[offset] = uint8(v&0x7f | 0x80)
+		v >>= 7
+		offset++
+	}
+	dAtA[offset] = uint8(v)
+	return base
+}
+func (m *TensorShapeProto) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Dim) > 0 {
+		for _, e := range m.Dim {
+			l = e.Size()
+			n += 1 + l + sovTensorBundle(uint64(l))
+		}
+	}
+	if m.UnknownRank {
+		n += 2
+	}
+	return n
+}
+
+func (m *TensorShapeProto_Dim) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Size_ != 0 {
+		n += 1 + sovTensorBundle(uint64(m.Size_))
+	}
+	l = len(m.Name)
+	if l > 0 {
+		n += 1 + l + sovTensorBundle(uint64(l))
+	}
+	return n
+}
+
+func (m *TensorSliceProto) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if len(m.Extent) > 0 {
+		for _, e := range m.Extent {
+			l = e.Size()
+			n += 1 + l + sovTensorBundle(uint64(l))
+		}
+	}
+	return n
+}
+
+func (m *TensorSliceProto_Extent) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Start != 0 {
+		n += 1 + sovTensorBundle(uint64(m.Start))
+	}
+	if m.HasLength != nil {
+		n += m.HasLength.Size()
+	}
+	return n
+}
+
+func (m *TensorSliceProto_Extent_Length) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	n += 1 + sovTensorBundle(uint64(m.Length))
+	return n
+}
+func (m *BundleHeaderProto) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.NumShards != 0 {
+		n += 1 + sovTensorBundle(uint64(m.NumShards))
+	}
+	if m.Endianness != 0 {
+		n += 1 + sovTensorBundle(uint64(m.Endianness))
+	}
+	return n
+}
+
+func (m *BundleEntryProto) Size() (n int) {
+	if m == nil {
+		return 0
+	}
+	var l int
+	_ = l
+	if m.Dtype != 0 {
+		n += 1 + sovTensorBundle(uint64(m.Dtype))
+	}
+	if m.Shape != nil {
+		l = m.Shape.Size()
+		n += 1 + l + sovTensorBundle(uint64(l))
+	}
+	if m.ShardId != 0 {
+		n += 1 + sovTensorBundle(uint64(m.ShardId))
+	}
+	if m.Offset != 0 {
+		n += 1 + sovTensorBundle(uint64(m.Offset))
+	}
+	if m.Size_ != 0 {
+		n += 1 + sovTensorBundle(uint64(m.Size_))
+	}
+	if m.Crc32C != 0 {
+		n += 5
+	}
+	if len(m.Slices) > 0 {
+		for _, e := range m.Slices {
+			l = e.Size()
+			n += 1 + l + sovTensorBundle(uint64(l))
+		}
+	}
+	return n
+}
+
+func sovTensorBundle(x uint64) (n int) {
+	return (math_bits.Len64(x|1) + 6) / 7
+}
+func sozTensorBundle(x uint64) (n int) {
+	return sovTensorBundle(uint64((x << 1) ^ uint64((int64(x) >> 63))))
+}
+func (m *TensorShapeProto) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTensorBundle
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TensorShapeProto: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TensorShapeProto: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Dim", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Dim = append(m.Dim, &TensorShapeProto_Dim{})
+			if err := m.Dim[len(m.Dim)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field UnknownRank", wireType)
+			}
+			var v int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.UnknownRank = bool(v != 0)
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTensorBundle(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TensorShapeProto_Dim) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTensorBundle
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Dim: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Dim: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+			}
+			m.Size_ = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Size_ |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Name", wireType)
+			}
+			var stringLen uint64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				stringLen |= uint64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			intStringLen := int(stringLen)
+			if intStringLen < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			postIndex := iNdEx + intStringLen
+			if postIndex < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Name = string(dAtA[iNdEx:postIndex])
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTensorBundle(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TensorSliceProto) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTensorBundle
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: TensorSliceProto: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: TensorSliceProto: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Extent", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Extent = append(m.Extent, &TensorSliceProto_Extent{})
+			if err := m.Extent[len(m.Extent)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTensorBundle(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *TensorSliceProto_Extent) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTensorBundle
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: Extent: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: Extent: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Start", wireType)
+			}
+			m.Start = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Start |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Length", wireType)
+			}
+			var v int64
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				v |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			m.HasLength = &TensorSliceProto_Extent_Length{v}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTensorBundle(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *BundleHeaderProto) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTensorBundle
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BundleHeaderProto: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BundleHeaderProto: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field NumShards", wireType)
+			}
+			m.NumShards = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.NumShards |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Endianness", wireType)
+			}
+			m.Endianness = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Endianness |= BundleHeaderProto_Endianness(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTensorBundle(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func (m *BundleEntryProto) Unmarshal(dAtA []byte) error {
+	l := len(dAtA)
+	iNdEx := 0
+	for iNdEx < l {
+		preIndex := iNdEx
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return ErrIntOverflowTensorBundle
+			}
+			if iNdEx >= l {
+				return io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= uint64(b&0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		fieldNum := int32(wire >> 3)
+		wireType := int(wire & 0x7)
+		if wireType == 4 {
+			return fmt.Errorf("proto: BundleEntryProto: wiretype end group for non-group")
+		}
+		if fieldNum <= 0 {
+			return fmt.Errorf("proto: BundleEntryProto: illegal tag %d (wire type %d)", fieldNum, wire)
+		}
+		switch fieldNum {
+		case 1:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Dtype", wireType)
+			}
+			m.Dtype = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Dtype |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 2:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Shape", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			if m.Shape == nil {
+				m.Shape = &TensorShapeProto{}
+			}
+			if err := m.Shape.Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		case 3:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field ShardId", wireType)
+			}
+			m.ShardId = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.ShardId |= int32(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 4:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Offset", wireType)
+			}
+			m.Offset = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Offset |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 5:
+			if wireType != 0 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Size_", wireType)
+			}
+			m.Size_ = 0
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				m.Size_ |= int64(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+		case 6:
+			if wireType != 5 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Crc32C", wireType)
+			}
+			m.Crc32C = 0
+			if (iNdEx + 4) > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Crc32C = uint32(encoding_binary.LittleEndian.Uint32(dAtA[iNdEx:]))
+			iNdEx += 4
+		case 7:
+			if wireType != 2 {
+				return fmt.Errorf("proto: wrong wireType = %d for field Slices", wireType)
+			}
+			var msglen int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				msglen |= int(b&0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if msglen < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			postIndex := iNdEx + msglen
+			if postIndex < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if postIndex > l {
+				return io.ErrUnexpectedEOF
+			}
+			m.Slices = append(m.Slices, &TensorSliceProto{})
+			if err := m.Slices[len(m.Slices)-1].Unmarshal(dAtA[iNdEx:postIndex]); err != nil {
+				return err
+			}
+			iNdEx = postIndex
+		default:
+			iNdEx = preIndex
+			skippy, err := skipTensorBundle(dAtA[iNdEx:])
+			if err != nil {
+				return err
+			}
+			if (skippy < 0) || (iNdEx+skippy) < 0 {
+				return ErrInvalidLengthTensorBundle
+			}
+			if (iNdEx + skippy) > l {
+				return io.ErrUnexpectedEOF
+			}
+			iNdEx += skippy
+		}
+	}
+
+	if iNdEx > l {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+func skipTensorBundle(dAtA []byte) (n int, err error) {
+	l := len(dAtA)
+	iNdEx := 0
+	depth := 0
+	for iNdEx < l {
+		var wire uint64
+		for shift := uint(0); ; shift += 7 {
+			if shift >= 64 {
+				return 0, ErrIntOverflowTensorBundle
+			}
+			if iNdEx >= l {
+				return 0, io.ErrUnexpectedEOF
+			}
+			b := dAtA[iNdEx]
+			iNdEx++
+			wire |= (uint64(b) & 0x7F) << shift
+			if b < 0x80 {
+				break
+			}
+		}
+		wireType := int(wire & 0x7)
+		switch wireType {
+		case 0:
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				iNdEx++
+				if dAtA[iNdEx-1] < 0x80 {
+					break
+				}
+			}
+		case 1:
+			iNdEx += 8
+		case 2:
+			var length int
+			for shift := uint(0); ; shift += 7 {
+				if shift >= 64 {
+					return 0, ErrIntOverflowTensorBundle
+				}
+				if iNdEx >= l {
+					return 0, io.ErrUnexpectedEOF
+				}
+				b := dAtA[iNdEx]
+				iNdEx++
+				length |= (int(b) & 0x7F) << shift
+				if b < 0x80 {
+					break
+				}
+			}
+			if length < 0 {
+				return 0, ErrInvalidLengthTensorBundle
+			}
+			iNdEx += length
+		case 3:
+			depth++
+		case 4:
+			if depth == 0 {
+				return 0, ErrUnexpectedEndOfGroupTensorBundle
+			}
+			depth--
+		case 5:
+			iNdEx += 4
+		default:
+			return 0, fmt.Errorf("proto: illegal wireType %d", wireType)
+		}
+		if iNdEx < 0 {
+			return 0, ErrInvalidLengthTensorBundle
+		}
+		if depth == 0 {
+			return iNdEx, nil
+		}
+	}
+	return 0, io.ErrUnexpectedEOF
+}
+
+var (
+	ErrInvalidLengthTensorBundle        = fmt.Errorf("proto: negative length found during unmarshaling")
+	ErrIntOverflowTensorBundle          = fmt.Errorf("proto: integer overflow")
+	ErrUnexpectedEndOfGroupTensorBundle = fmt.Errorf("proto: unexpected end of group")
+)
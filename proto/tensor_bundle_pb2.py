@@ -0,0 +1,20 @@
+# -*- coding: utf-8 -*-
+# Generated by the protocol buffer compiler.  DO NOT EDIT!
+# source: tensor_bundle.proto
+"""Generated protocol buffer code."""
+from google.protobuf import descriptor as _descriptor
+from google.protobuf import descriptor_pool as _descriptor_pool
+from google.protobuf import symbol_database as _symbol_database
+from google.protobuf.internal import builder as _builder
+# @@protoc_insertion_point(imports)
+
+_sym_db = _symbol_database.Default()
+
+
+
+DESCRIPTOR = _descriptor_pool.Default().AddSerializedFile(b'\n\x13tensor_bundle.proto\x12\x05proto\"\x93\x01\n\x10TensorShapeProto\x12-\n\x03dim\x18\x02 \x03(\x0b2\x1b.proto.TensorShapeProto.DimR\x03dim\x12!\n\x0cunknown_rank\x18\x03 \x01(\x08R\x0bunknownRank\x1a-\n\x03Dim\x12\x12\n\x04size\x18\x01 \x01(\x03R\x04size\x12\x12\n\x04name\x18\x02 \x01(\x09R\x04name\"\x92\x01\n\x10TensorSliceProto\x126\n\x06extent\x18\x01 \x03(\x0b2\x1e.proto.TensorSliceProto.ExtentR\x06extent\x1aF\n\x06Extent\x12\x14\n\x05start\x18\x01 \x01(\x03R\x05start\x12\x18\n\x06length\x18\x02 \x01(\x03H\x00R\x06lengthB\x0c\n\nhas_length\"\x9a\x01\n\x11BundleHeaderProto\x12\x1d\n\nnum_shards\x18\x01 \x01(\x05R\x09numShards\x12C\n\nendianness\x18\x02 \x01(\x0e2#.proto.BundleHeaderProto.EndiannessR\nendianness\"!\n\nEndianness\x12\n\n\x06LITTLE\x10\x00\x12\x07\n\x03BIG\x10\x01\"\xe7\x01\n\x10BundleEntryProto\x12\x14\n\x05dtype\x18\x01 \x01(\x05R\x05dtype\x12-\n\x05shape\x18\x02 \x01(\x0b2\x17.proto.TensorShapeProtoR\x05shape\x12\x19\n\x08shard_id\x18\x03 \x01(\x05R\x07shardId\x12\x16\n\x06offset\x18\x04 \x01(\x03R\x06offset\x12\x12\n\x04size\x18\x05 \x01(\x03R\x04size\x12\x16\n\x06crc32c\x18\x06 \x01(\x07R\x06crc32c\x12/\n\x06slices\x18\x07 \x03(\x0b2\x17.proto.TensorSliceProtoR\x06slicesb\x06proto3')
+
+_globals = globals()
+_builder.BuildMessageAndEnumDescriptors(DESCRIPTOR, _globals)
+_builder.BuildTopDescriptorsAndMessages(DESCRIPTOR, 'tensor_bundle_pb2', _globals)
+# @@protoc_insertion_point(module_scope)
@@ -0,0 +1,107 @@
+# -*- coding: utf-8 -*-
+
+"""
+Export a trained TF/Keras model to a go-serving model file and a matching model spec.
+
+    spec = export_model("wide_deep", feature_columns, variables_of(keras_model),
+                        version=int(time.time()), units=1, export_dir="/tmp/data/wide_deep")
+    save_spec(spec, "/tmp/data/wide_deep.spec.json")
+
+The model spec is read by `model.LoadSpec` in Go. proto/data_pb2.py and index_pb2.py are generated
+in the protoc 3.x style, they need protobuf<4, or PROTOCOL_BUFFERS_PYTHON_IMPLEMENTATION=python.
+"""
+
+import json
+
+import numpy as np
+
+from proto.index_pb2 import Field
+from .saved_model import SavedModel, numpy_tensor
+
+
+def variables_of(model):
+    """returns {variable name: numpy value} of a Keras model or an Estimator"""
+    if hasattr(model, "get_variable_names"):
+        return {name: np.asarray(model.get_variable_value(name)) for name in model.get_variable_names()}
+    return {v.name.split(":")[0]: v.numpy() for v in model.variables}
+
+
+def _find_variable(variables, *suffixes):
+    for suffix in suffixes:
+        for name in sorted(variables):
+            if name == suffix or name.endswith("/" + suffix):
+                return variables[name]
+    raise KeyError("cannot find variable %s" % " or ".join(suffixes))
+
+
+def _categorical_spec(col):
+    """returns (spec, vocabulary) of a categorical column"""
+    kind = type(col).__name__
+    if kind == "IdentityCategoricalColumn":
+        default = col.default_value if col.default_value is not None else 0
+        vocab = [str(i) for i in range(col.num_buckets)]
+        spec = {"type": "identity", "field": col.key, "default": str(default), "buckets": col.num_buckets}
+        return spec, vocab
+    if kind == "VocabularyListCategoricalColumn":
+        if col.num_oov_buckets > 1:
+            raise ValueError("%s: only one oov bucket is supported" % col.key)
+        vocab = [v.decode("utf-8") if isinstance(v, bytes) else str(v) for v in col.vocabulary_list]
+        if col.num_oov_buckets == 1:
+            default = "__oov__"
+            vocab.append(default)
+        elif col.default_value >= 0:
+            default = vocab[col.default_value]
+        else:
+            raise ValueError("%s: default_value or num_oov_buckets is required" % col.key)
+        spec = {"type": "identity", "field": col.key, "default": default, "buckets": len(vocab)}
+        return spec, vocab
+    if kind == "BucketizedColumn":
+        source = col.source_column
+        default = float(source.default_value[0]) if source.default_value is not None else 0.
+        spec = {"type": "bucketized", "field": source.key, "default_value": default,
+                "omitted_value": -1. if default != -1. else -2., "boundaries": list(col.boundaries)}
+        return spec, None
+    raise ValueError("unsupported categorical column %s" % kind)
+
+
+def export_model(model_name, feature_columns, variables, version, units=1, export_dir="."):
+    """
+    exports a linear model over feature_columns, each categorical column is exported as an
+    embedding table of `units` dimension named by its field, along with its vocabulary.
+    """
+    m = SavedModel()
+    columns = []
+    for col in feature_columns:
+        kind = type(col).__name__
+        if kind == "EmbeddingColumn":
+            cat, dimension, combiner = col.categorical_column, col.dimension, col.combiner
+            weights = _find_variable(variables,
+                                     "%s_embedding/embedding_weights" % cat.name,
+                                     "%s/embedding_weights" % col.name)
+        else:
+            cat, dimension, combiner = col, units, "sum"
+            weights = _find_variable(variables, "%s/weights" % col.name)
+
+        spec, vocab = _categorical_spec(cat)
+        if weights.shape[1] != dimension:
+            raise ValueError("%s: expected dimension %d, but got %s" % (col.name, dimension, weights.shape))
+        m.add_named_tensor(spec["field"], numpy_tensor(weights.astype(np.float32)))
+        if vocab is not None:
+            m.add_field_index(Field(name=spec["field"], dim=dimension,
+                                    records={v: i for i, v in enumerate(vocab)}))
+        columns.append({"type": "embedding", "dimension": dimension, "combiner": combiner,
+                        "categorical": spec})
+
+    try:
+        bias = _find_variable(variables, "bias_weights")
+        m.add_named_tensor("bias_weights", numpy_tensor(bias.astype(np.float32)))
+    except KeyError:
+        pass
+
+    m.export(model_name, version, export_dir)
+    return {"name": model_name, "type": "lr", "units": units, "columns": columns}
+
+
+def save_spec(spec, file):
+    with open(file, "w") as f:
+        json.dump(spec, f, indent=2, sort_keys=True)
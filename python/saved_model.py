@@ -1,6 +1,7 @@
 # -*- coding: utf-8 -*-
 
 import os
+import struct
 
 import time
 import varint
@@ -9,17 +10,80 @@ from proto.data_pb2 import Data, Tensor, DataType
 from proto.index_pb2 import Index, Field
 
 
+FORMAT_VERSION = 1
+FOOTER_SIZE = 43
+LEGACY_FOOTER_SIZE = 30
+MAGIC = b'go_serving'
+
+
+def _crc32c_table():
+    table = []
+    for i in range(256):
+        crc = i
+        for _ in range(8):
+            crc = (crc >> 1) ^ 0x82F63B78 if crc & 1 else crc >> 1
+        table.append(crc)
+    return table
+
+
+_CRC32C_TABLE = _crc32c_table()
+
+
+try:
+    from crc32c import crc32c
+except ImportError:
+    def crc32c(buf):
+        crc = 0xFFFFFFFF
+        for b in bytearray(buf):
+            crc = _CRC32C_TABLE[(crc ^ b) & 0xFF] ^ (crc >> 8)
+        return crc ^ 0xFFFFFFFF
+
+
 def float_tensor(shape, float_val):
     return Tensor(dtype=DataType.Value("DT_FLOAT"),
                   tensor_shape=shape,
                   float_val=float_val)
 
 
+def int_tensor(shape, int_val):
+    return Tensor(dtype=DataType.Value("DT_INT32"),
+                  tensor_shape=shape,
+                  int_val=int_val)
+
+
+def string_tensor(shape, string_val):
+    return Tensor(dtype=DataType.Value("DT_STRING"),
+                  tensor_shape=shape,
+                  string_val=string_val)
+
+
+def numpy_tensor(array):
+    """converts a numpy array to a Tensor by its dtype"""
+    shape = list(array.shape)
+    values = array.reshape(-1).tolist()
+    kind = array.dtype.kind
+    if kind == "f":
+        return float_tensor(shape, values)
+    if kind in ("i", "u", "b"):
+        return int_tensor(shape, [int(v) for v in values])
+    if kind in ("S", "U", "O"):
+        return string_tensor(shape, [v.decode("utf-8") if isinstance(v, bytes) else str(v) for v in values])
+    raise ValueError("unsupported dtype %s" % array.dtype)
+
+
 class SavedModel(object):
-    def __init__(self):
+    def __init__(self, model_name=None, version=None):
+        self.model_name = model_name
+        self.version = version
         self.data = Data()
         self.index = Index()
 
+    def tensor(self, key):
+        return self.data.data[key] if key in self.data.data else None
+
+    def field(self, name):
+        return self.index.embeddings[name] if name in self.index.embeddings else None
+
     def add_named_tensor(self, key, tensor):
         self.data.data[key].CopyFrom(tensor)
 
@@ -33,36 +97,75 @@ class SavedModel(object):
         return model_name + version
 
     @staticmethod
-    def footer(header_size, data_size):
-        magic = b'go_serving'
-        data_offset = header_size
-        index_offset = data_offset + data_size
+    def footer(header, data, index):
+        """dataOffset(10) | indexOffset(10) | headerCRC(4) | dataCRC(4) | indexCRC(4) | version(1) | magic(10)"""
+        data_offset = len(header)
+        index_offset = data_offset + len(data)
         return varint.encode(data_offset).ljust(10, b'\x00') + \
-               varint.encode(index_offset).ljust(10, b'\x00') + magic
+               varint.encode(index_offset).ljust(10, b'\x00') + \
+               struct.pack("<III", crc32c(header), crc32c(data), crc32c(index)) + \
+               struct.pack("<B", FORMAT_VERSION) + MAGIC
 
-    def export(self, model_name, version):
+    def export(self, model_name, version, export_dir="."):
         assert (isinstance(version, int))
-        file = "%d.pb" % version
+        file = os.path.join(export_dir, "%d.pb" % version)
         try:
+            header = self.header(model_name, version)
+            data = self.data.SerializeToString()
+            index = self.index.SerializeToString()
             with open(file, "wb") as f:
                 # header
-                header_size = f.write(self.header(model_name, version))
+                header_size = f.write(header)
                 # data
-                data_size = f.write(self.data.SerializeToString())
+                data_size = f.write(data)
                 # index
-                index_size = f.write(self.index.SerializeToString())
+                index_size = f.write(index)
                 # footer
-                footer_size = f.write(self.footer(header_size, data_size))
-                assert (footer_size == 30)
+                footer_size = f.write(self.footer(header, data, index))
+                assert (footer_size == FOOTER_SIZE)
             print("[%s] save %s ok (%d, %d, %d, %d)" % (
                 model_name, file, header_size, data_size, index_size, footer_size))
         except Exception as e:
             print("[%s] save %s fails: %s" % (model_name, file, e))
-            os.remove(f.name)
+            if os.path.exists(file):
+                os.remove(file)
+            raise
+        self.model_name, self.version = model_name, version
+        return file
+
+    @classmethod
+    def load(cls, file):
+        with open(file, "rb") as f:
+            buf = f.read()
+        if len(buf) < LEGACY_FOOTER_SIZE or buf[-len(MAGIC):] != MAGIC:
+            raise ValueError("%s: invalid magic number" % file)
+        # the byte before magic is the format version, which is the zero padding of indexOffset in legacy files
+        format_version = bytearray(buf[-len(MAGIC) - 1:-len(MAGIC)])[0]
+        if format_version == 0:
+            footer_size = LEGACY_FOOTER_SIZE
+        elif format_version == FORMAT_VERSION:
+            footer_size = FOOTER_SIZE
+        else:
+            raise ValueError("%s: unsupported format version %d" % (file, format_version))
+
+        footer = buf[-footer_size:]
+        data_offset = varint.decode_bytes(footer[:10])
+        index_offset = varint.decode_bytes(footer[10:20])
+        if not 0 < data_offset <= index_offset <= len(buf) - footer_size:
+            raise ValueError("%s: invalid offsets" % file)
+        header = buf[:data_offset]
+        data = buf[data_offset:index_offset]
+        index = buf[index_offset:len(buf) - footer_size]
+        if format_version > 0:
+            for name, section, crc in zip(("header", "data", "index"), (header, data, index),
+                                          struct.unpack("<III", footer[20:32])):
+                if crc32c(section) != crc:
+                    raise ValueError("%s: %s checksum mismatch" % (file, name))
 
-        def load(self, file):
-            # todo:
-            pass
+        m = cls(model_name=header[:-10].decode("utf-8"), version=varint.decode_bytes(header[-10:]))
+        m.data.ParseFromString(data)
+        m.index.ParseFromString(index)
+        return m
 
 
 # m = SavedModel()
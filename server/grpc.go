@@ -0,0 +1,88 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/18 14:10
+ */
+
+package server
+
+import (
+	"context"
+
+	gogoproto "github.com/gogo/protobuf/proto"
+	"github.com/yinyajun/go-serving/model"
+	"github.com/yinyajun/go-serving/params"
+	"github.com/yinyajun/go-serving/proto"
+	"github.com/yinyajun/go-serving/serving"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorgonia.org/tensor"
+)
+
+// codec marshals the gogo generated messages, which the default grpc codec can't.
+type codec struct{}
+
+func (codec) Marshal(v interface{}) ([]byte, error) {
+	return gogoproto.Marshal(v.(gogoproto.Message))
+}
+
+func (codec) Unmarshal(data []byte, v interface{}) error {
+	return gogoproto.Unmarshal(data, v.(gogoproto.Message))
+}
+
+func (codec) Name() string {
+	return "proto"
+}
+
+type predictionService struct {
+	serving *serving.Serving
+}
+
+func (p *predictionService) Predict(ctx context.Context, req *proto.PredictRequest) (*proto.PredictResponse, error) {
+	feats := make(model.Features, len(req.GetInputs()))
+	for name, t := range req.GetInputs() {
+		v, err := params.DecodeChecked(t)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "input %s: %v", name, err)
+		}
+		feats[name] = v
+	}
+	m, err := p.serving.GetModel(req.GetModelName())
+	if err != nil {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	version := m.GetMeta().Version()
+	out, err := p.serving.RequestContext(ctx, req.GetModelName(), feats)
+	if err != nil {
+		return nil, status.Error(grpcCode(err), err.Error())
+	}
+	dense, ok := out.(*tensor.Dense)
+	if !ok {
+		return nil, status.Errorf(codes.Internal, "unsupported output %T", out)
+	}
+	output, err := params.Encode(dense)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return &proto.PredictResponse{ModelName: req.GetModelName(), ModelVersion: version, Output: output}, nil
+}
+
+func grpcCode(err error) codes.Code {
+	switch serving.ErrorType(err) {
+	case "":
+		return codes.OK
+	case "not_found":
+		return codes.NotFound
+	case "deadline_exceeded":
+		return codes.DeadlineExceeded
+	case "canceled":
+		return codes.Canceled
+	case "queue_full":
+		return codes.ResourceExhausted
+	case "not_available", "shutdown":
+		return codes.Unavailable
+	case "batch_size", "not_match":
+		return codes.InvalidArgument
+	default:
+		return codes.Internal
+	}
+}
@@ -0,0 +1,65 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 18:10
+ */
+
+package server
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net"
+	"testing"
+
+	"github.com/yinyajun/go-serving/proto"
+	"github.com/yinyajun/go-serving/serving"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+)
+
+func newClient(t *testing.T) proto.PredictionServiceClient {
+	t.Helper()
+	s := serving.New(serving.WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	t.Cleanup(func() { s.Close() })
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.ForceServerCodec(codec{}))
+	proto.RegisterPredictionServiceServer(srv, &predictionService{serving: s})
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.ForceCodec(codec{})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return proto.NewPredictionServiceClient(conn)
+}
+
+func TestPredictInvalidTensors(t *testing.T) {
+	client := newClient(t)
+	tests := []struct {
+		name  string
+		input *proto.Tensor
+		code  codes.Code
+	}{
+		{"shape mismatch", &proto.Tensor{Dtype: proto.DataType_DT_FLOAT, TensorShape: []int32{5}, FloatVal: []float32{1, 2, 3}}, codes.InvalidArgument},
+		{"invalid dtype", &proto.Tensor{Dtype: proto.DataType_DT_INVALID, TensorShape: []int32{1}}, codes.InvalidArgument},
+		{"negative dim", &proto.Tensor{Dtype: proto.DataType_DT_STRING, TensorShape: []int32{-1}, StringVal: []string{"a"}}, codes.InvalidArgument},
+		// the server survives the invalid ones, and valid inputs reach the model lookup
+		{"valid", &proto.Tensor{Dtype: proto.DataType_DT_STRING, TensorShape: []int32{2, 1}, StringVal: []string{"a", "b"}}, codes.NotFound},
+	}
+	for _, test := range tests {
+		req := &proto.PredictRequest{ModelName: "missing", Inputs: map[string]*proto.Tensor{"x": test.input}}
+		_, err := client.Predict(context.Background(), req)
+		if got := status.Code(err); got != test.code {
+			t.Errorf("%s: got %v (%v), want %v", test.name, got, err, test.code)
+		}
+	}
+}
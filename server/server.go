@@ -0,0 +1,201 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/18 13:30
+ */
+
+// Package server runs go-serving as a standalone server. A binary serving
+// Go-defined models registers them by model.Register before Main.
+package server
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/yinyajun/go-serving/metrics"
+	"github.com/yinyajun/go-serving/proto"
+	"github.com/yinyajun/go-serving/serving"
+	"google.golang.org/grpc"
+)
+
+type Config struct {
+	GRPCPort        int
+	RESTPort        int
+	MetricsPort     int    // serves /metrics on RESTPort if 0
+	AdminAddr       string // listen address of the admin api, disabled if empty
+	ModelConfigFile string
+	ConfigPollWait  time.Duration // reloads on SIGHUP only if 0
+	ShutdownTimeout time.Duration
+
+	// single model shortcut instead of ModelConfigFile
+	ModelName     string
+	ModelBasePath string
+	ModelSpec     string // spec file
+	Model         string // registered by model.Register
+
+	FileSystemPollWait time.Duration // polls the single model path instead of fsnotify if set
+}
+
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.IntVar(&c.GRPCPort, "port", 8500, "port of the grpc api, 0 disables it")
+	fs.IntVar(&c.RESTPort, "rest_api_port", 8501, "port of the predict and health http api")
+	fs.IntVar(&c.MetricsPort, "monitoring_port", 0, "port of /metrics, the rest api port if 0")
+	fs.StringVar(&c.AdminAddr, "admin_listen_addr", "", "listen address of the admin http api, e.g. 127.0.0.1:8502, disabled if empty")
+	fs.StringVar(&c.ModelConfigFile, "model_config_file", "", "yaml or json config of the served models")
+	fs.DurationVar(&c.ConfigPollWait, "model_config_file_poll_wait", 0, "interval to reload the config file, 0 reloads on SIGHUP only")
+	fs.DurationVar(&c.ShutdownTimeout, "shutdown_timeout", 30*time.Second, "max time to drain requests on shutdown")
+	fs.StringVar(&c.ModelName, "model_name", "", "name of the single served model")
+	fs.StringVar(&c.ModelBasePath, "model_base_path", "", "path of the single served model")
+	fs.StringVar(&c.ModelSpec, "model_spec", "", "spec file of the single served model")
+	fs.StringVar(&c.Model, "model", "", "registered Go-defined model of the single served model")
+	fs.DurationVar(&c.FileSystemPollWait, "file_system_poll_wait", 0, "interval to poll the single model path instead of fsnotify, e.g. on NFS")
+}
+
+func (c *Config) validate() error {
+	single := c.ModelName != "" || c.ModelBasePath != ""
+	switch {
+	case single && c.ModelConfigFile != "":
+		return errors.New("model_config_file can't be used with model_name and model_base_path")
+	case !single && c.ModelConfigFile == "":
+		return errors.New("model_config_file or model_name and model_base_path is required")
+	case single && (c.ModelName == "" || c.ModelBasePath == ""):
+		return errors.New("both model_name and model_base_path are required")
+	case single && (c.ModelSpec == "") == (c.Model == ""):
+		return errors.New("exactly one of model_spec and model is required")
+	}
+	return nil
+}
+
+// Main parses the flags and runs until SIGINT or SIGTERM.
+func Main() {
+	var c Config
+	c.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if err := c.validate(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		flag.Usage()
+		os.Exit(2)
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := Run(ctx, c); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Run serves until ctx is done, then shuts down gracefully.
+func Run(ctx context.Context, c Config) error {
+	if err := c.validate(); err != nil {
+		return err
+	}
+	m := metrics.NewPrometheus("go_serving")
+	s := serving.New(serving.WithMetrics(m))
+	s.Launch()
+	go s.Watch()
+
+	reload := func() error { return s.ReloadConfig(c.ModelConfigFile) }
+	if c.ModelConfigFile == "" {
+		entry := serving.ModelEntry{Name: c.ModelName, Path: c.ModelBasePath, SpecPath: c.ModelSpec, Model: c.Model}
+		if c.FileSystemPollWait > 0 {
+			entry.PollInterval = c.FileSystemPollWait.String()
+		}
+		reload = func() error { return s.ApplyConfig(&serving.Config{Models: []serving.ModelEntry{entry}}) }
+	}
+	if err := reload(); err != nil {
+		return err
+	}
+
+	errc := make(chan error, 4)
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", s.HealthHandler())
+	mux.Handle("/readyz", s.HealthHandler())
+	mux.Handle("/v1/models/", s.PredictHandler())
+	if c.MetricsPort == 0 {
+		mux.Handle("/metrics", m.Handler())
+	} else {
+		go serveHTTP(s, fmt.Sprintf(":%d", c.MetricsPort), m.Handler(), errc)
+	}
+	// the admin api has no auth, it's only served on its own address, e.g. a loopback one
+	if c.AdminAddr != "" {
+		admin := http.NewServeMux()
+		admin.Handle("/v1/models", s.AdminHandler())
+		admin.Handle("/v1/models/", s.AdminHandler())
+		go serveHTTP(s, c.AdminAddr, admin, errc)
+	}
+	go serveHTTP(s, fmt.Sprintf(":%d", c.RESTPort), mux, errc)
+	if c.GRPCPort != 0 {
+		go serveGRPC(s, c.GRPCPort, errc)
+	}
+
+	var tick <-chan time.Time
+	if c.ConfigPollWait > 0 && c.ModelConfigFile != "" {
+		ticker := time.NewTicker(c.ConfigPollWait)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var err error
+loop:
+	for {
+		select {
+		case <-tick:
+			_ = reload() // failures are logged
+		case <-hup:
+			_ = reload()
+		case err = <-errc:
+			break loop
+		case <-ctx.Done():
+			break loop
+		}
+	}
+	sctx, cancel := context.WithTimeout(context.Background(), c.ShutdownTimeout)
+	defer cancel()
+	if serr := s.Shutdown(sctx); err == nil {
+		err = serr
+	}
+	return err
+}
+
+func serveHTTP(s *serving.Serving, addr string, h http.Handler, errc chan<- error) {
+	if err := s.ListenAndServe(addr, h); err != http.ErrServerClosed {
+		errc <- err
+	}
+}
+
+func serveGRPC(s *serving.Serving, port int, errc chan<- error) {
+	lis, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		errc <- err
+		return
+	}
+	srv := grpc.NewServer(grpc.ForceServerCodec(codec{}))
+	proto.RegisterPredictionServiceServer(srv, &predictionService{serving: s})
+	s.OnShutdown(func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			srv.GracefulStop()
+			close(done)
+		}()
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			srv.Stop()
+			return ctx.Err()
+		}
+	})
+	if err := srv.Serve(lis); err != nil && err != grpc.ErrServerStopped {
+		errc <- err
+	}
+}
@@ -0,0 +1,91 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/16 15:30
+ */
+
+package serving
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// AdminHandler manages models at runtime:
+//
+//	GET    /v1/models          statuses of all models
+//	GET    /v1/models/{name}   status of a model
+//	PUT    /v1/models/{name}   add or update a model by ModelEntry
+//	DELETE /v1/models/{name}   remove a model
+func (s *Serving) AdminHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.Trim(strings.TrimPrefix(r.URL.Path, "/v1/models"), "/")
+		switch {
+		case name == "" && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, s.Statuses())
+		case name == "":
+			writeError(w, http.StatusMethodNotAllowed, errors.New(r.Method+" not allowed"))
+		case r.Method == http.MethodGet:
+			m, err := s.GetModel(name)
+			if err != nil {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, m.Status())
+		case r.Method == http.MethodPut:
+			s.putModel(w, r, name)
+		case r.Method == http.MethodDelete:
+			if err := s.RemoveModel(name); err != nil {
+				writeError(w, http.StatusNotFound, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			writeError(w, http.StatusMethodNotAllowed, errors.New(r.Method+" not allowed"))
+		}
+	})
+}
+
+func (s *Serving) putModel(w http.ResponseWriter, r *http.Request, name string) {
+	var e ModelEntry
+	if err := json.NewDecoder(r.Body).Decode(&e); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	e.Name = name
+	c, err := e.ModelConfig()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	status := http.StatusCreated
+	if _, ok := s.models.GetModelByName(name); ok {
+		status = http.StatusOK
+		err = s.UpdateModel(c)
+	} else {
+		err = s.AddModel(c)
+	}
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	m, err := s.GetModel(name)
+	if err != nil {
+		writeError(w, http.StatusConflict, err)
+		return
+	}
+	writeJSON(w, status, m.Status())
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, code int, err error) {
+	writeJSON(w, code, struct {
+		Error string `json:"error"`
+	}{err.Error()})
+}
@@ -0,0 +1,276 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/9 15:12
+ */
+
+package serving
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yinyajun/go-serving/model"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"gorgonia.org/tensor"
+)
+
+// BatchingConfig enables merging concurrent requests of a model into one
+// Predict, like tensorflow serving's --enable_batching.
+type BatchingConfig struct {
+	MaxBatchSize    int           `validate:"required,gt=0"` // max rows of a merged batch
+	BatchTimeout    time.Duration `validate:"gte=0"`         // max time a request waits for others
+	NumBatchThreads int           `validate:"gte=0"`         // goroutines running merged batches, default 1
+	MaxEnqueued     int           `validate:"gte=0"`         // queue capacity in requests, default 1000
+}
+
+type batchResult struct {
+	out tensor.Tensor
+	err error
+}
+
+type batchTask struct {
+	ctx   context.Context
+	feats model.Features
+	size  int
+	done  chan batchResult
+}
+
+type predictFunc func(context.Context, model.Features) (tensor.Tensor, error)
+
+type batcher struct {
+	conf    BatchingConfig
+	predict predictFunc
+	observe func(size int)
+	queue   chan *batchTask
+	quit    chan struct{}
+	wg      sync.WaitGroup
+
+	mu     sync.RWMutex // guards enqueuing against Close
+	closed bool
+}
+
+func newBatcher(conf BatchingConfig, predict predictFunc, observe func(size int)) *batcher {
+	if conf.NumBatchThreads == 0 {
+		conf.NumBatchThreads = 1
+	}
+	if conf.MaxEnqueued == 0 {
+		conf.MaxEnqueued = 1000
+	}
+	b := &batcher{
+		conf:    conf,
+		predict: predict,
+		observe: observe,
+		queue:   make(chan *batchTask, conf.MaxEnqueued),
+		quit:    make(chan struct{}),
+	}
+	b.wg.Add(conf.NumBatchThreads)
+	for i := 0; i < conf.NumBatchThreads; i++ {
+		go b.loop()
+	}
+	return b
+}
+
+// Submit enqueues the features and waits for the result of its merged batch,
+// or returns when ctx is done. It returns ClosedError after Close.
+func (b *batcher) Submit(ctx context.Context, feats model.Features) (tensor.Tensor, error) {
+	b.mu.RLock()
+	closed := b.closed
+	b.mu.RUnlock()
+	if closed {
+		return nil, ClosedError
+	}
+	size := feats.BatchSize()
+	if size == -1 {
+		return nil, BatchSizeError
+	}
+	if size >= b.conf.MaxBatchSize {
+		return b.predict(ctx, feats)
+	}
+
+	task := &batchTask{ctx: ctx, feats: feats, size: size, done: make(chan batchResult, 1)}
+	if err := b.enqueue(task); err != nil {
+		return nil, err
+	}
+	select {
+	case res := <-task.done:
+		return res.out, res.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// enqueue adds the task unless closed, tasks enqueued before Close are drained by it.
+func (b *batcher) enqueue(task *batchTask) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ClosedError
+	}
+	select {
+	case b.queue <- task:
+		return nil
+	default:
+		return QueueFullError
+	}
+}
+
+// Close stops the batch goroutines after the enqueued requests are done.
+func (b *batcher) Close() {
+	b.mu.Lock()
+	if b.closed {
+		b.mu.Unlock()
+		return
+	}
+	b.closed = true
+	close(b.quit)
+	b.mu.Unlock()
+	b.wg.Wait()
+}
+
+func (b *batcher) loop() {
+	defer b.wg.Done()
+	var next *batchTask
+	for {
+		if next == nil {
+			select {
+			case next = <-b.queue:
+			case <-b.quit:
+				b.drain()
+				return
+			}
+		}
+		batch, total := []*batchTask{next}, next.size
+		next = nil
+
+		timer := time.NewTimer(b.conf.BatchTimeout)
+	collect:
+		for total < b.conf.MaxBatchSize {
+			select {
+			case t := <-b.queue:
+				if total+t.size > b.conf.MaxBatchSize {
+					next = t // starts the next batch
+					break collect
+				}
+				batch = append(batch, t)
+				total += t.size
+			case <-timer.C:
+				break collect
+			}
+		}
+		timer.Stop()
+		b.run(batch)
+	}
+}
+
+// drain runs the remaining enqueued requests one by one.
+func (b *batcher) drain() {
+	for {
+		select {
+		case t := <-b.queue:
+			b.run([]*batchTask{t})
+		default:
+			return
+		}
+	}
+}
+
+func (b *batcher) run(tasks []*batchTask) {
+	// skip the requests which are already cancelled
+	batch := tasks[:0]
+	for _, t := range tasks {
+		if err := t.ctx.Err(); err != nil {
+			t.done <- batchResult{nil, err}
+			continue
+		}
+		batch = append(batch, t)
+	}
+	if len(batch) == 0 {
+		return
+	}
+	if len(batch) == 1 {
+		b.observe(batch[0].size)
+		out, err := b.predict(batch[0].ctx, batch[0].feats)
+		batch[0].done <- batchResult{out, err}
+		return
+	}
+
+	feats, err := mergeFeatures(batch)
+	if err != nil {
+		// features can't be merged, e.g. different shapes
+		for _, t := range batch {
+			b.observe(t.size)
+			out, err := b.predict(t.ctx, t.feats)
+			t.done <- batchResult{out, err}
+		}
+		return
+	}
+
+	b.observe(feats.BatchSize())
+	// the merged batch is not bound to any single request, but linked to them
+	links := make([]trace.Link, len(batch))
+	for i, t := range batch {
+		links[i] = trace.LinkFromContext(t.ctx)
+	}
+	ctx, span := tracer.Start(context.Background(), "Serving.Batch", trace.WithLinks(links...),
+		trace.WithAttributes(attribute.Int("batch.size", feats.BatchSize()), attribute.Int("batch.requests", len(batch))))
+	out, err := b.predict(ctx, feats)
+	span.End()
+	if err != nil {
+		for _, t := range batch {
+			t.done <- batchResult{nil, err}
+		}
+		return
+	}
+
+	start := 0
+	for _, t := range batch {
+		res, err := splitBatch(out, start, t.size)
+		t.done <- batchResult{res, err}
+		start += t.size
+	}
+}
+
+// splitBatch copies rows [start, start+size) of the merged output.
+func splitBatch(out tensor.Tensor, start, size int) (tensor.Tensor, error) {
+	view, err := out.Slice(tensor.S(start, start+size))
+	if err != nil {
+		return nil, err
+	}
+	res := view.Materialize()
+	// slicing a single row drops dimension 0
+	shape := append([]int{size}, out.Shape()[1:]...)
+	if err := res.Reshape(shape...); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// mergeFeatures concatenates the features of the batch along dimension 0.
+func mergeFeatures(batch []*batchTask) (model.Features, error) {
+	for _, t := range batch[1:] {
+		if len(t.feats) != len(batch[0].feats) {
+			return nil, NotMatchError{expected: fmt.Sprintf("%d features", len(batch[0].feats)),
+				provided: fmt.Sprintf("%d features", len(t.feats))}
+		}
+	}
+	merged := make(model.Features, len(batch[0].feats))
+	for name, first := range batch[0].feats {
+		others := make([]tensor.Tensor, 0, len(batch)-1)
+		for _, t := range batch[1:] {
+			v, ok := t.feats[name]
+			if !ok {
+				return nil, NotFoundError{name: name, field: "features"}
+			}
+			others = append(others, v)
+		}
+		v, err := tensor.Concat(0, first, others...)
+		if err != nil {
+			return nil, err
+		}
+		merged[name] = v
+	}
+	return merged, nil
+}
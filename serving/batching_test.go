@@ -0,0 +1,185 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/26 10:30
+ */
+
+package serving
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/yinyajun/go-serving/model"
+	"gorgonia.org/tensor"
+)
+
+func TestBatcherSubmitAfterClose(t *testing.T) {
+	predict := func(ctx context.Context, feats model.Features) (tensor.Tensor, error) {
+		return feats["x"], nil
+	}
+	b := newBatcher(BatchingConfig{MaxBatchSize: 8}, predict, func(int) {})
+	b.Close()
+	b.Close() // idempotent
+	if _, err := b.Submit(context.Background(), features("0")); !errors.Is(err, ClosedError) {
+		t.Fatalf("Submit after Close: got %v, want ClosedError", err)
+	}
+}
+
+func TestRemoveModelRacesRequest(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, 1)
+	s := newTestServing(t)
+	s.Launch()
+
+	for i := 0; i < 100; i++ {
+		err := s.AddModel(&ModelConfig{Name: "test", Path: dir, Model: sumModel{},
+			Batching: &BatchingConfig{MaxBatchSize: 4, BatchTimeout: time.Millisecond}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, _ := s.models.GetModelByName("test")
+		var wg sync.WaitGroup
+		start := make(chan struct{})
+		for j := 0; j < 16; j++ {
+			wg.Add(1)
+			go func(j int) {
+				defer wg.Done()
+				<-start
+				for k := 0; k < 20; k++ {
+					var err error
+					if j%2 == 0 {
+						_, err = s.Request("test", features("0", "1"))
+					} else {
+						// the requests which passed the state check of Predict before close
+						_, err = m.submit(context.Background(), features("0", "1"))
+					}
+					var notFound NotFoundError
+					var notAvailable NotAvailableError
+					if err != nil && !errors.Is(err, ClosedError) && !errors.As(err, &notFound) && !errors.As(err, &notAvailable) {
+						t.Errorf("Request: %v", err)
+						return
+					}
+				}
+			}(j)
+		}
+		close(start)
+		if err := s.RemoveModel("test"); err != nil {
+			t.Fatal(err)
+		}
+
+		done := make(chan struct{})
+		go func() {
+			wg.Wait()
+			close(done)
+		}()
+		select {
+		case <-done:
+		case <-time.After(5 * time.Second):
+			t.Fatal("requests are blocked after RemoveModel")
+		}
+	}
+}
+
+// recorder predicts rows [v, -v] of the values of the only feature, and records the batch sizes.
+type recorder struct {
+	mu    sync.Mutex
+	calls []int
+}
+
+func (r *recorder) predict(ctx context.Context, feats model.Features) (tensor.Tensor, error) {
+	var values []string
+	for _, t := range feats {
+		values = t.Data().([]string)
+	}
+	out := make([]float32, 0, 2*len(values))
+	for _, v := range values {
+		f, _ := strconv.ParseFloat(v, 32)
+		out = append(out, float32(f), -float32(f))
+	}
+	r.mu.Lock()
+	r.calls = append(r.calls, len(values))
+	r.mu.Unlock()
+	return tensor.New(tensor.WithBacking(out), tensor.WithShape(len(values), 2)), nil
+}
+
+func TestBatcherMerges(t *testing.T) {
+	feature := func(name string, x ...string) model.Features {
+		return model.Features{name: tensor.New(tensor.WithBacking(x), tensor.WithShape(len(x)))}
+	}
+	tests := []struct {
+		name     string
+		maxBatch int
+		requests []model.Features
+		calls    []int // sorted batch sizes of the predict calls
+	}{
+		{"merged", 4, []model.Features{feature("x", "1"), feature("x", "2", "3"), feature("x", "4")}, []int{4}},
+		{"single rows", 2, []model.Features{feature("x", "1"), feature("x", "2")}, []int{2}},
+		{"other features", 2, []model.Features{feature("x", "1"), feature("y", "2")}, []int{1, 1}},
+		{"other shapes", 2, []model.Features{feature("x", "1"),
+			{"x": tensor.New(tensor.WithBacking([]string{"2", "3"}), tensor.WithShape(1, 2))}}, []int{1, 2}},
+		{"oversized", 2, []model.Features{feature("x", "1", "2", "3")}, []int{3}},
+	}
+	for _, test := range tests {
+		r := &recorder{}
+		// the batches are full before the timeout
+		b := newBatcher(BatchingConfig{MaxBatchSize: test.maxBatch, BatchTimeout: 5 * time.Second}, r.predict, func(int) {})
+		var wg sync.WaitGroup
+		for _, feats := range test.requests {
+			wg.Add(1)
+			go func(feats model.Features) {
+				defer wg.Done()
+				out, err := b.Submit(context.Background(), feats)
+				if err != nil {
+					t.Errorf("%s: %v", test.name, err)
+					return
+				}
+				// each caller gets its own rows
+				var want []float32
+				for _, t := range feats {
+					for _, v := range t.Data().([]string) {
+						f, _ := strconv.ParseFloat(v, 32)
+						want = append(want, float32(f), -float32(f))
+					}
+				}
+				if !out.Shape().Eq(tensor.Shape{len(want) / 2, 2}) || !reflect.DeepEqual(out.Data(), want) {
+					t.Errorf("%s: got %v %v, want %v", test.name, out.Shape(), out.Data(), want)
+				}
+			}(feats)
+		}
+		wg.Wait()
+		b.Close()
+		sort.Ints(r.calls)
+		if !reflect.DeepEqual(r.calls, test.calls) {
+			t.Errorf("%s: got calls %v, want %v", test.name, r.calls, test.calls)
+		}
+	}
+}
+
+func TestSplitBatch(t *testing.T) {
+	out := tensor.New(tensor.WithBacking([]float32{1, 2, 3, 4, 5, 6}), tensor.WithShape(3, 2))
+	tests := []struct {
+		start, size int
+		shape       tensor.Shape
+		data        []float32
+	}{
+		{0, 1, tensor.Shape{1, 2}, []float32{1, 2}}, // a single row keeps dimension 0
+		{1, 2, tensor.Shape{2, 2}, []float32{3, 4, 5, 6}},
+		{0, 3, tensor.Shape{3, 2}, []float32{1, 2, 3, 4, 5, 6}},
+	}
+	for _, test := range tests {
+		res, err := splitBatch(out, test.start, test.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !res.Shape().Eq(test.shape) || !reflect.DeepEqual(res.Data(), test.data) {
+			t.Errorf("[%d, %d): got %v %v, want %v %v", test.start, test.start+test.size,
+				res.Shape(), res.Data(), test.shape, test.data)
+		}
+	}
+}
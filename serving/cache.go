@@ -0,0 +1,266 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/22 10:10
+ */
+
+package serving
+
+import (
+	"container/list"
+	"context"
+	"encoding/binary"
+	"hash"
+	"hash/fnv"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yinyajun/go-serving/model"
+	"gorgonia.org/tensor"
+)
+
+// CacheConfig caches the outputs of each row, so that a batch of repeated
+// candidates partially hits.
+type CacheConfig struct {
+	Size int           `validate:"required,gt=0"` // max cached rows
+	TTL  time.Duration `validate:"gte=0"`         // no expiration if 0
+}
+
+type cacheKey [16]byte
+
+type cachedRow struct {
+	data  []float32
+	shape tensor.Shape
+}
+
+type cacheEntry struct {
+	key     cacheKey
+	row     cachedRow
+	expires time.Time
+}
+
+// outputCache is a LRU of the output rows, keyed by the hash of the feature row and the version.
+type outputCache struct {
+	conf    CacheConfig
+	observe func(hits, misses int)
+
+	mu    sync.Mutex
+	ll    *list.List
+	items map[cacheKey]*list.Element
+	gen   uint64 // increased by purge
+}
+
+func newOutputCache(conf CacheConfig, observe func(hits, misses int)) *outputCache {
+	return &outputCache{conf: conf, observe: observe, ll: list.New(), items: make(map[cacheKey]*list.Element)}
+}
+
+func (c *outputCache) get(key cacheKey, now time.Time) (cachedRow, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.items[key]
+	if !ok {
+		return cachedRow{}, false
+	}
+	entry := e.Value.(*cacheEntry)
+	if c.conf.TTL > 0 && now.After(entry.expires) {
+		c.ll.Remove(e)
+		delete(c.items, key)
+		return cachedRow{}, false
+	}
+	c.ll.MoveToFront(e)
+	return entry.row, true
+}
+
+// put caches the row predicted in generation gen, it's dropped if the cache is
+// purged since then, e.g. the row may be predicted by the swapped version.
+func (c *outputCache) put(key cacheKey, row cachedRow, gen uint64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.gen {
+		return
+	}
+	if e, ok := c.items[key]; ok {
+		e.Value.(*cacheEntry).row = row
+		e.Value.(*cacheEntry).expires = now.Add(c.conf.TTL)
+		c.ll.MoveToFront(e)
+		return
+	}
+	c.items[key] = c.ll.PushFront(&cacheEntry{key: key, row: row, expires: now.Add(c.conf.TTL)})
+	for c.ll.Len() > c.conf.Size {
+		e := c.ll.Back()
+		c.ll.Remove(e)
+		delete(c.items, e.Value.(*cacheEntry).key)
+	}
+}
+
+// purge drops all rows, e.g. of the swapped version.
+func (c *outputCache) purge() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ll.Init()
+	c.items = make(map[cacheKey]*list.Element)
+	c.gen++
+}
+
+func (c *outputCache) generation() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.gen
+}
+
+// predict predicts the missed rows of feats only. version is the served version
+// when the request starts, it's read after the generation of the cache.
+func (c *outputCache) predict(ctx context.Context, gen, version uint64, feats model.Features, predict predictFunc) (tensor.Tensor, error) {
+	batch := feats.BatchSize()
+	keys, ok := rowKeys(version, feats, batch)
+	if !ok {
+		return predict(ctx, feats)
+	}
+	now := time.Now()
+	rows := make([]cachedRow, batch)
+	var missed []int
+	for i, key := range keys {
+		if row, ok := c.get(key, now); ok {
+			rows[i] = row
+		} else {
+			missed = append(missed, i)
+		}
+	}
+	c.observe(batch-len(missed), len(missed))
+
+	if len(missed) > 0 {
+		sub := feats
+		if len(missed) < batch {
+			sub = gatherRows(feats, missed)
+		}
+		out, err := predict(ctx, sub)
+		if err != nil {
+			return nil, err
+		}
+		data, ok := out.Data().([]float32)
+		if !ok || out.Dims() == 0 || out.Shape()[0] != len(missed) {
+			return out, nil // not cacheable, e.g. of other dtypes
+		}
+		shape := out.Shape()[1:].Clone()
+		size := len(data) / len(missed)
+		for j, i := range missed {
+			rows[i] = cachedRow{data: append([]float32(nil), data[j*size:(j+1)*size]...), shape: shape}
+			c.put(keys[i], rows[i], gen, now)
+		}
+		if len(missed) == batch {
+			return out, nil
+		}
+	}
+
+	shape := rows[0].shape
+	backing := make([]float32, 0, batch*len(rows[0].data))
+	for _, row := range rows {
+		if !row.shape.Eq(shape) {
+			return predict(ctx, feats) // rows cached of other shapes
+		}
+		backing = append(backing, row.data...)
+	}
+	return tensor.New(tensor.WithBacking(backing), tensor.WithShape(append([]int{batch}, shape...)...)), nil
+}
+
+// rowKeys hashes each row of the features in the order of names, it returns
+// false if some features can't be hashed.
+func rowKeys(version uint64, feats model.Features, batch int) ([]cacheKey, bool) {
+	if batch <= 0 {
+		return nil, false
+	}
+	names := make([]string, 0, len(feats))
+	for name := range feats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hashes := make([]rowHash, batch)
+	for i := range hashes {
+		hashes[i] = rowHash{fnv.New128a()}
+		hashes[i].uint64(version)
+	}
+	for _, name := range names {
+		t := feats[name]
+		size := t.Shape().TotalSize() / batch
+		for i := range hashes {
+			hashes[i].string(name)
+			for _, d := range t.Shape()[1:] {
+				hashes[i].uint64(uint64(d))
+			}
+		}
+		switch data := t.Data().(type) {
+		case []string:
+			for i := range hashes {
+				for _, v := range data[i*size : (i+1)*size] {
+					hashes[i].string(v)
+				}
+			}
+		case []float32:
+			for i := range hashes {
+				for _, v := range data[i*size : (i+1)*size] {
+					hashes[i].uint64(uint64(math.Float32bits(v)))
+				}
+			}
+		case []int:
+			for i := range hashes {
+				for _, v := range data[i*size : (i+1)*size] {
+					hashes[i].uint64(uint64(v))
+				}
+			}
+		default:
+			return nil, false
+		}
+	}
+	keys := make([]cacheKey, batch)
+	for i, h := range hashes {
+		h.Sum(keys[i][:0])
+	}
+	return keys, true
+}
+
+type rowHash struct {
+	hash.Hash
+}
+
+func (h rowHash) uint64(v uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], v)
+	h.Write(buf[:])
+}
+
+// string writes the length first, so that ("ab", "c") differs from ("a", "bc").
+func (h rowHash) string(v string) {
+	h.uint64(uint64(len(v)))
+	h.Write([]byte(v))
+}
+
+// gatherRows copies the rows of the features.
+func gatherRows(feats model.Features, rows []int) model.Features {
+	batch := feats.BatchSize()
+	res := make(model.Features, len(feats))
+	for name, t := range feats {
+		size := t.Shape().TotalSize() / batch
+		shape := append([]int{len(rows)}, t.Shape()[1:]...)
+		var backing interface{}
+		switch data := t.Data().(type) {
+		case []string:
+			backing = gather(data, rows, size)
+		case []float32:
+			backing = gather(data, rows, size)
+		case []int:
+			backing = gather(data, rows, size)
+		}
+		res[name] = tensor.New(tensor.WithBacking(backing), tensor.WithShape(shape...))
+	}
+	return res
+}
+
+func gather[T any](data []T, rows []int, size int) []T {
+	res := make([]T, 0, len(rows)*size)
+	for _, i := range rows {
+		res = append(res, data[i*size:(i+1)*size]...)
+	}
+	return res
+}
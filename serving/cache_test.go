@@ -0,0 +1,179 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 16:40
+ */
+
+package serving
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yinyajun/go-serving/model"
+	"gorgonia.org/tensor"
+)
+
+// scorer outputs score(x) for each row of feature x, and records the predicted rows.
+type scorer struct {
+	score func(x string) float32
+	rows  [][]string
+}
+
+func (s *scorer) predict(ctx context.Context, feats model.Features) (tensor.Tensor, error) {
+	x := feats["x"].Data().([]string)
+	s.rows = append(s.rows, append([]string(nil), x...))
+	out := make([]float32, len(x))
+	for i, v := range x {
+		out[i] = s.score(v)
+	}
+	return tensor.New(tensor.WithBacking(out), tensor.WithShape(len(x), 1)), nil
+}
+
+// cached returns the number of cached rows.
+func cached(c *outputCache) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ll.Len()
+}
+
+func byteScore(x string) float32 { return float32(x[0]) }
+
+func TestCachePartialHits(t *testing.T) {
+	var hits, misses int
+	c := newOutputCache(CacheConfig{Size: 10}, func(h, m int) { hits, misses = h, m })
+	s := &scorer{score: byteScore}
+	for _, batch := range [][]string{{"a", "b"}, {"b", "c", "a", "d"}} {
+		out, err := c.predict(context.Background(), 0, 1, features(batch...), s.predict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := make([]float32, len(batch))
+		for i, x := range batch {
+			want[i] = byteScore(x)
+		}
+		if !reflect.DeepEqual(out.Data(), want) || !out.Shape().Eq(tensor.Shape{len(batch), 1}) {
+			t.Fatalf("got %v of shape %v, want %v", out.Data(), out.Shape(), want)
+		}
+	}
+	// only the missed rows of the second batch are predicted, in order
+	if want := [][]string{{"a", "b"}, {"c", "d"}}; !reflect.DeepEqual(s.rows, want) {
+		t.Fatalf("predicted %v, want %v", s.rows, want)
+	}
+	if hits != 2 || misses != 2 {
+		t.Fatalf("got %d hits and %d misses, want 2 and 2", hits, misses)
+	}
+}
+
+func TestCacheKeyedByVersion(t *testing.T) {
+	c := newOutputCache(CacheConfig{Size: 10}, func(int, int) {})
+	s := &scorer{score: byteScore}
+	for _, version := range []uint64{1, 2, 1} {
+		if _, err := c.predict(context.Background(), 0, version, features("a"), s.predict); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(s.rows) != 2 {
+		t.Fatalf("predicted %v, want the rows of versions 1 and 2", s.rows)
+	}
+}
+
+func TestCachePurgedDuringRequest(t *testing.T) {
+	c := newOutputCache(CacheConfig{Size: 10}, func(int, int) {})
+	// the version is swapped while predicting, so the outputs are of the new version
+	swapped := &scorer{score: func(string) float32 { return 2 }}
+	predict := func(ctx context.Context, feats model.Features) (tensor.Tensor, error) {
+		c.purge()
+		return swapped.predict(ctx, feats)
+	}
+	gen := c.generation()
+	if _, err := c.predict(context.Background(), gen, 1, features("a"), predict); err != nil {
+		t.Fatal(err)
+	}
+	// e.g. rolled back to version 1, the outputs of version 2 are not served
+	s := &scorer{score: func(string) float32 { return 1 }}
+	out, err := c.predict(context.Background(), c.generation(), 1, features("a"), s.predict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := out.Data().([]float32)[0]; got != 1 {
+		t.Fatalf("got %v, want 1", got)
+	}
+}
+
+func TestCacheExpires(t *testing.T) {
+	c := newOutputCache(CacheConfig{Size: 10, TTL: time.Minute}, func(int, int) {})
+	key := cacheKey{1}
+	now := time.Now()
+	c.put(key, cachedRow{data: []float32{1}}, 0, now)
+	if _, ok := c.get(key, now.Add(time.Minute)); !ok {
+		t.Fatal("expired before the ttl")
+	}
+	if _, ok := c.get(key, now.Add(time.Minute+1)); ok {
+		t.Fatal("not expired after the ttl")
+	}
+	if c.ll.Len() != 0 || len(c.items) != 0 {
+		t.Fatal("the expired row is not dropped")
+	}
+
+	// never expires without ttl
+	c = newOutputCache(CacheConfig{Size: 10}, func(int, int) {})
+	c.put(key, cachedRow{data: []float32{1}}, 0, now)
+	if _, ok := c.get(key, now.Add(time.Hour)); !ok {
+		t.Fatal("expired without ttl")
+	}
+}
+
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	c := newOutputCache(CacheConfig{Size: 2}, func(int, int) {})
+	now := time.Now()
+	for i := byte(0); i < 2; i++ {
+		c.put(cacheKey{i}, cachedRow{}, 0, now)
+	}
+	c.get(cacheKey{0}, now)
+	c.put(cacheKey{2}, cachedRow{}, 0, now)
+	for i, want := range []bool{true, false, true} {
+		if _, ok := c.get(cacheKey{byte(i)}, now); ok != want {
+			t.Errorf("row %d: got cached %v, want %v", i, ok, want)
+		}
+	}
+}
+
+func TestCachePurgedOnSwap(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, 1)
+	s := newTestServing(t)
+	s.Launch()
+	if err := s.AddModel(&ModelConfig{Name: "test", Path: dir, Model: sumModel{},
+		Cache: &CacheConfig{Size: 10}}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := s.GetModel("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, version := range []uint64{1, 2} {
+		if version > 1 {
+			if err := s.load(m, writeModel(t, t.TempDir(), version)); err != nil {
+				t.Fatal(err)
+			}
+			if cached(m.cache) != 0 {
+				t.Fatal("the cache is not purged")
+			}
+		}
+		// the output of "0" is the version, the second request hits
+		for i := 0; i < 2; i++ {
+			out, err := s.Request("test", features("0"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := out.Data().([]float32)[0]; got != float32(version) {
+				t.Fatalf("version %d: got %v", version, got)
+			}
+		}
+		if cached(m.cache) != 1 {
+			t.Fatalf("version %d: got %d cached rows, want 1", version, cached(m.cache))
+		}
+	}
+}
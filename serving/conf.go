@@ -6,53 +6,289 @@
 package serving
 
 import (
+	"context"
+	"errors"
+
 	"github.com/yinyajun/go-serving/model"
 	"github.com/yinyajun/go-serving/params"
+	"github.com/yinyajun/go-serving/source"
+	"gorgonia.org/tensor"
+	"sync"
 	"sync/atomic"
 	"time"
 	"unsafe"
 )
 
 type ModelConfig struct {
-	Name  string      `validate:"required"`
-	Path  string      `validate:"required"`
-	Model model.Model `validate:"required"`
+	Name      string          `validate:"required"`
+	Path      string          `validate:"required_without=Source"`
+	Source    source.Source   // optional, model files in Path are watched by fsnotify if nil
+	Model     model.Model     `validate:"required"`
+	Batching  *BatchingConfig // optional, requests are predicted one by one if nil
+	Timeout   time.Duration   // optional, default timeout of requests
+	Warmup    string          // optional, default Path/DefaultWarmupFile if exists
+	Version   uint64          // optional, serves the latest version if 0
+	ModelName string          // optional, model name in the model files if not Name
+	// optional, polls Path every PollInterval instead of fsnotify, e.g. on NFS or
+	// kubernetes configmaps, model files must be named by version
+	PollInterval time.Duration
+	// optional, reverts a new version if it fails or shifts the outputs
+	Rollback *RollbackConfig
+	// optional, caches the outputs of repeated rows
+	Cache *CacheConfig
+	// optional, looks up embedding tables in a store with hot rows cached
+	Embeddings *EmbeddingConfig
+	// optional, looks up the fields of its stores instead of the model files, the
+	// stores are closed with the model
+	Stores *params.Composite
+}
+
+// resolve returns the config whose Source polls Path if PollInterval is set.
+func (c *ModelConfig) resolve() *ModelConfig {
+	if c.Source != nil || c.PollInterval <= 0 {
+		return c
+	}
+	res := *c
+	res.Source = source.NewPollingDir(c.Path, c.PollInterval)
+	return &res
 }
 
 type servingModel struct {
 	config    *ModelConfig
 	meta      unsafe.Pointer
 	startTime time.Time
+	batcher   *batcher
+	guard     *guard
+	cache     *outputCache
+	tiered    *params.TieredStore
+
+	requests lifecycle   // requests in flight, the params are released after them
+	replaced atomic.Bool // by UpdateModel, its requests are retried on the new model
+
+	mu      sync.Mutex
+	cancel  context.CancelFunc // stops subscribing the source
+	state   ModelState
+	lastErr error
 }
 
 func (s *servingModel) GetMeta() *params.Params {
 	return (*params.Params)(atomic.LoadPointer(&(s.meta)))
 }
 
+func (s *servingModel) State() ModelState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+func (s *servingModel) setState(state ModelState, err error) {
+	s.mu.Lock()
+	s.state, s.lastErr = state, err
+	s.mu.Unlock()
+}
+
+// loadFailed keeps serving the loaded version if any.
+func (s *servingModel) loadFailed(err error) {
+	s.mu.Lock()
+	if s.state != StateAvailable {
+		s.state = StateFailed
+	}
+	s.lastErr = err
+	s.mu.Unlock()
+}
+
+func (s *servingModel) Status() ModelStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	meta := s.GetMeta()
+	st := ModelStatus{Name: s.config.Name, Version: meta.Version(), State: s.state}
+	if deltas := meta.Deltas(); len(deltas) > 0 {
+		st.BaseVersion, st.Deltas = meta.BaseVersion(), deltas
+	}
+	if s.lastErr != nil {
+		st.Error = s.lastErr.Error()
+	}
+	return st
+}
+
+func (s *servingModel) close() {
+	s.stop()
+	s.setState(StateUnloading, nil)
+	if s.batcher != nil {
+		s.batcher.Close()
+	}
+}
+
+// stop stops loading new versions, the loaded one keeps serving.
+func (s *servingModel) stop() {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.mu.Unlock()
+}
+
+func (s *servingModel) predict(ctx context.Context, feats model.Features) (tensor.Tensor, error) {
+	return s.config.Model.Predict(ctx, s.params(s.GetMeta()), feats)
+}
+
+func (s *servingModel) Predict(ctx context.Context, feats model.Features) (tensor.Tensor, error) {
+	if !s.requests.enter() {
+		if s.replaced.Load() {
+			return nil, replacedError
+		}
+		return nil, NotAvailableError{name: s.config.Name, state: StateUnloading}
+	}
+	defer s.requests.leave()
+	if state := s.State(); state != StateAvailable {
+		return nil, NotAvailableError{name: s.config.Name, state: state}
+	}
+	if s.cache != nil {
+		gen := s.cache.generation()
+		return s.cache.predict(ctx, gen, s.GetMeta().Version(), feats, s.submit)
+	}
+	return s.submit(ctx, feats)
+}
+
+// submit predicts feats in a merged batch if batching.
+func (s *servingModel) submit(ctx context.Context, feats model.Features) (tensor.Tensor, error) {
+	if s.batcher != nil {
+		return s.batcher.Submit(ctx, feats)
+	}
+	return s.predict(ctx, feats)
+}
+
+// modelManager is safe for concurrent use, models can be registered while serving.
+// Models of different names can share a path, e.g. pinned to different versions.
 type modelManager struct {
-	paths map[string]*servingModel
+	mu    sync.RWMutex
+	paths map[string][]*servingModel
 	names map[string]*servingModel
 }
 
-func (f modelManager) Set(c *ModelConfig) error {
-	m := servingModel{config: c, meta: unsafe.Pointer(&params.Params{})}
-	if _, ok := f.paths[c.Path]; ok {
-		return DuplicatedError{c.Path}
+// release waits for the requests in flight of the closed model, then closes its
+// params, stores and tiered store. The stores are kept and the tiered store is
+// only purged if next shares them.
+func (s *servingModel) release(ctx context.Context, next *servingModel) error {
+	s.requests.close()
+	if err := s.requests.wait(ctx); err != nil {
+		return err
+	}
+	s.close()
+	s.GetMeta().Close()
+	var err error
+	if s.config.Stores != nil && (next == nil || next.config.Stores != s.config.Stores) {
+		err = s.config.Stores.Close()
+	}
+	if s.tiered == nil {
+		return err
+	}
+	if next != nil && next.config.Embeddings != nil && next.config.Embeddings.Store == s.config.Embeddings.Store {
+		s.tiered.Purge()
+		return err
+	}
+	return errors.Join(err, s.tiered.Close())
+}
+
+func newServingModel(c *ModelConfig) *servingModel {
+	return &servingModel{config: c, meta: unsafe.Pointer(&params.Params{}), state: StateLoading}
+}
+
+// watchedPath returns the path watched by fsnotify, models with a source have none.
+func (c *ModelConfig) watchedPath() (string, bool) {
+	return c.Path, c.Source == nil
+}
+
+// modelName returns the model name in the model files.
+func (c *ModelConfig) modelName() string {
+	if c.ModelName != "" {
+		return c.ModelName
 	}
+	return c.Name
+}
+
+func (f *modelManager) Set(m *servingModel) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := m.config
 	if _, ok := f.names[c.Name]; ok {
 		return DuplicatedError{c.Name}
 	}
-	f.paths[c.Path] = &m
-	f.names[c.Name] = &m
+	f.add(m)
+	f.names[c.Name] = m
 	return nil
 }
 
-func (f modelManager) GetModelByPath(path string) (*servingModel, bool) {
-	m, ok := f.paths[path]
-	return m, ok
+func (f *modelManager) add(m *servingModel) {
+	if p, ok := m.config.watchedPath(); ok {
+		f.paths[p] = append(f.paths[p], m)
+	}
+}
+
+func (f *modelManager) remove(m *servingModel) {
+	p, ok := m.config.watchedPath()
+	if !ok {
+		return
+	}
+	models := f.paths[p][:0]
+	for _, other := range f.paths[p] {
+		if other != m {
+			models = append(models, other)
+		}
+	}
+	if len(models) == 0 {
+		delete(f.paths, p)
+		return
+	}
+	f.paths[p] = models
+}
+
+// Replace replaces the model of the same name, it returns the old one.
+func (f *modelManager) Replace(m *servingModel) (*servingModel, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	c := m.config
+	old, ok := f.names[c.Name]
+	if !ok {
+		return nil, NotFoundError{name: c.Name, field: "models"}
+	}
+	f.remove(old)
+	f.add(m)
+	f.names[c.Name] = m
+	return old, nil
+}
+
+func (f *modelManager) Delete(name string) (*servingModel, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m, ok := f.names[name]
+	if !ok {
+		return nil, false
+	}
+	delete(f.names, name)
+	f.remove(m)
+	return m, true
+}
+
+func (f *modelManager) GetModelsByPath(path string) []*servingModel {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	return append([]*servingModel(nil), f.paths[path]...)
 }
 
-func (f modelManager) GetModelByName(name string) (*servingModel, bool) {
+func (f *modelManager) GetModelByName(name string) (*servingModel, bool) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	m, ok := f.names[name]
 	return m, ok
 }
+
+func (f *modelManager) All() []*servingModel {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+	res := make([]*servingModel, 0, len(f.names))
+	for _, m := range f.names {
+		res = append(res, m)
+	}
+	return res
+}
@@ -0,0 +1,285 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/17 10:15
+ */
+
+package serving
+
+import (
+	"crypto/sha256"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"time"
+
+	"github.com/yinyajun/go-serving/model"
+	"github.com/yinyajun/go-serving/params"
+	"github.com/yinyajun/go-serving/source"
+	"sigs.k8s.io/yaml"
+)
+
+// Config lists the served models, like tensorflow serving's model_config_file.
+//
+//	models:
+//	  - name: wide_deep
+//	    path: /tmp/data/wide_deep
+//	    spec_path: wide_deep.spec.json
+//	    version_policy: {specific: 1649232000}
+//	    timeout: 50ms
+//	    batching: {max_batch_size: 64, batch_timeout: 2ms}
+//	    rollback: {grace_period: 10m, max_error_rate: 0.05}
+//	    cache: {size: 100000, ttl: 10s}
+//	    embeddings: {dir: /data/wide_deep_rows, cache_rows: 1000000}
+//	    vocabulary_dir: /data/wide_deep_vocab
+type Config struct {
+	Models []ModelEntry `json:"models"`
+}
+
+// ModelEntry describes a model built from its spec, or a Go-defined one.
+type ModelEntry struct {
+	Name          string         `json:"name,omitempty"`
+	Path          string         `json:"path"`
+	Model         string         `json:"model,omitempty"`      // registered by model.Register
+	ModelName     string         `json:"model_name,omitempty"` // in the model files if not name
+	Spec          *model.Spec    `json:"spec,omitempty"`
+	SpecPath      string         `json:"spec_path,omitempty"`
+	VersionPolicy *VersionPolicy `json:"version_policy,omitempty"`
+	PollInterval  string         `json:"poll_interval,omitempty"` // polls a local path instead of fsnotify if set
+	Timeout       string         `json:"timeout,omitempty"`
+	Warmup        string         `json:"warmup,omitempty"`
+	Rollback      *struct {
+		GracePeriod    string  `json:"grace_period"`
+		MinRequests    int     `json:"min_requests,omitempty"`
+		MaxErrorRate   float64 `json:"max_error_rate,omitempty"`
+		MaxMeanShift   float64 `json:"max_mean_shift,omitempty"`
+		CountDeadlines bool    `json:"count_deadlines,omitempty"`
+	} `json:"rollback,omitempty"`
+	Cache *struct {
+		Size int    `json:"size"`
+		TTL  string `json:"ttl,omitempty"`
+	} `json:"cache,omitempty"`
+	VocabularyDir string `json:"vocabulary_dir,omitempty"` // of <field>.txt files, instead of the model files
+	Embeddings    *struct {
+		Dir       string `json:"dir"` // of row files written by params.WriteRows
+		CacheRows int    `json:"cache_rows,omitempty"`
+	} `json:"embeddings,omitempty"`
+	Batching *struct {
+		MaxBatchSize    int    `json:"max_batch_size"`
+		BatchTimeout    string `json:"batch_timeout,omitempty"`
+		NumBatchThreads int    `json:"num_batch_threads,omitempty"`
+		MaxEnqueued     int    `json:"max_enqueued,omitempty"`
+	} `json:"batching,omitempty"`
+
+	specDigest [sha256.Size]byte // of the spec_path file, a changed file reloads the model
+}
+
+// VersionPolicy serves the latest version by default, or a specific one.
+type VersionPolicy struct {
+	Specific uint64 `json:"specific,omitempty"`
+}
+
+// LoadConfig reads a yaml or json config, spec paths are relative to the config file.
+func LoadConfig(file string) (*Config, error) {
+	buf, err := os.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	c := new(Config)
+	if err := yaml.UnmarshalStrict(buf, c); err != nil {
+		return nil, ConfigError{file: file, err: err}
+	}
+	dir := filepath.Dir(file)
+	for i := range c.Models {
+		e := &c.Models[i]
+		if e.Name == "" {
+			return nil, ConfigError{file: file, err: errors.New("model name is required")}
+		}
+		if e.SpecPath != "" && !filepath.IsAbs(e.SpecPath) {
+			e.SpecPath = filepath.Join(dir, e.SpecPath)
+		}
+	}
+	return c, nil
+}
+
+// ModelConfig builds the config of the model. The opened stores are closed on error.
+func (e *ModelEntry) ModelConfig() (c *ModelConfig, err error) {
+	m, err := e.build()
+	if err != nil {
+		return nil, err
+	}
+	c = &ModelConfig{Name: e.Name, ModelName: e.ModelName, Path: e.Path, Model: m, Warmup: e.Warmup}
+	defer func() {
+		if err != nil {
+			closeStores(c)
+		}
+	}()
+	interval, err := parseDuration(e.PollInterval)
+	if err != nil {
+		return nil, err
+	}
+	if source.IsURL(e.Path) {
+		if c.Source, err = source.FromURL(e.Path, interval); err != nil {
+			return nil, err
+		}
+	} else {
+		c.PollInterval = interval
+	}
+	if e.VersionPolicy != nil {
+		c.Version = e.VersionPolicy.Specific
+	}
+	if c.Timeout, err = parseDuration(e.Timeout); err != nil {
+		return nil, err
+	}
+	if r := e.Rollback; r != nil {
+		c.Rollback = &RollbackConfig{MinRequests: r.MinRequests, MaxErrorRate: r.MaxErrorRate, MaxMeanShift: r.MaxMeanShift,
+			CountDeadlines: r.CountDeadlines}
+		if c.Rollback.GracePeriod, err = parseDuration(r.GracePeriod); err != nil {
+			return nil, err
+		}
+	}
+	if cc := e.Cache; cc != nil {
+		c.Cache = &CacheConfig{Size: cc.Size}
+		if c.Cache.TTL, err = parseDuration(cc.TTL); err != nil {
+			return nil, err
+		}
+	}
+	if b := e.Batching; b != nil {
+		c.Batching = &BatchingConfig{
+			MaxBatchSize:    b.MaxBatchSize,
+			NumBatchThreads: b.NumBatchThreads,
+			MaxEnqueued:     b.MaxEnqueued,
+		}
+		if c.Batching.BatchTimeout, err = parseDuration(b.BatchTimeout); err != nil {
+			return nil, err
+		}
+	}
+	if em := e.Embeddings; em != nil {
+		store, err := params.OpenDiskRowStore(em.Dir)
+		if err != nil {
+			return nil, err
+		}
+		c.Embeddings = &EmbeddingConfig{Store: store, CacheRows: em.CacheRows}
+	}
+	if e.VocabularyDir != "" {
+		v, err := params.OpenTextVocabularies(e.VocabularyDir)
+		if err != nil {
+			return nil, err
+		}
+		c.Stores = params.NewComposite().AddVocabulary(v, v.Fields()...)
+	}
+	return c, nil
+}
+
+// closeStores closes the stores opened by ModelConfig.
+func closeStores(c *ModelConfig) {
+	if c.Embeddings != nil {
+		if cl, ok := c.Embeddings.Store.(io.Closer); ok {
+			cl.Close()
+		}
+	}
+	if c.Stores != nil {
+		c.Stores.Close()
+	}
+}
+
+func (e *ModelEntry) build() (model.Model, error) {
+	if e.Model != "" {
+		return model.Registered(e.Model)
+	}
+	spec := e.Spec
+	if spec == nil {
+		if e.SpecPath == "" {
+			return nil, errors.New("model, spec or spec_path is required")
+		}
+		var err error
+		if spec, err = model.LoadSpec(e.SpecPath); err != nil {
+			return nil, err
+		}
+	}
+	return spec.Build()
+}
+
+func parseDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// ApplyConfig adds, updates and removes models to match c. Only the models added
+// by former configs are removed, the unchanged ones are kept as they are. A model
+// whose spec_path file changed is updated too.
+func (s *Serving) ApplyConfig(c *Config) error {
+	s.configLock.Lock()
+	defer s.configLock.Unlock()
+
+	var errs []error
+	applied := make(map[string]ModelEntry, len(c.Models))
+	for _, e := range c.Models {
+		if e.SpecPath != "" {
+			// an unreadable file keeps the zero digest and fails in applyEntry
+			if buf, err := os.ReadFile(e.SpecPath); err == nil {
+				e.specDigest = sha256.Sum256(buf)
+			}
+		}
+		if _, ok := applied[e.Name]; ok {
+			errs = append(errs, DuplicatedError{e.Name})
+			continue
+		}
+		if old, ok := s.applied[e.Name]; ok && reflect.DeepEqual(old, e) {
+			applied[e.Name] = e
+			continue
+		}
+		if err := s.applyEntry(e); err != nil {
+			s.logger.Error("model config failed", "model", e.Name, "err", err)
+			if old, ok := s.applied[e.Name]; ok {
+				applied[e.Name] = old // keeps serving the old one
+			}
+			errs = append(errs, err)
+			continue
+		}
+		applied[e.Name] = e
+	}
+	for name := range s.applied {
+		if _, ok := applied[name]; ok {
+			continue
+		}
+		if err := s.RemoveModel(name); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	s.applied = applied
+	return errors.Join(errs...)
+}
+
+func (s *Serving) applyEntry(e ModelEntry) error {
+	c, err := e.ModelConfig()
+	if err != nil {
+		return err
+	}
+	if _, ok := s.models.GetModelByName(e.Name); ok {
+		err = s.UpdateModel(c)
+	} else {
+		err = s.AddModel(c)
+	}
+	if err != nil {
+		// the stores are owned by the model once it's registered
+		if m, ok := s.models.GetModelByName(e.Name); !ok || m.config.Embeddings != c.Embeddings || m.config.Stores != c.Stores {
+			closeStores(c)
+		}
+	}
+	return err
+}
+
+// ReloadConfig loads and applies the config file.
+func (s *Serving) ReloadConfig(file string) error {
+	c, err := LoadConfig(file)
+	if err != nil {
+		s.logger.Error("config reload failed", "file", file, "err", err)
+		return err
+	}
+	s.logger.Info("config reloaded", "file", file, "models", len(c.Models))
+	return s.ApplyConfig(c)
+}
@@ -0,0 +1,104 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 11:05
+ */
+
+package serving
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yinyajun/go-serving/model"
+	"github.com/yinyajun/go-serving/params"
+	"gorgonia.org/tensor"
+)
+
+const testSpec = `{"name": "test", "type": "lr", "units": 2, "columns": [
+  {"type": "embedding", "dimension": 2, "combiner": "%s",
+   "categorical": {"type": "identity", "field": "x", "default": "0", "buckets": 2}}]}`
+
+func TestApplyConfigReloadsChangedSpec(t *testing.T) {
+	dir := t.TempDir()
+	spec := filepath.Join(dir, "test.spec.json")
+	writeSpec := func(combiner string) {
+		if err := os.WriteFile(spec, []byte(strings.Replace(testSpec, "%s", combiner, 1)), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s := newTestServing(t)
+	c := &Config{Models: []ModelEntry{{Name: "test", Path: dir, SpecPath: spec}}}
+	served := func() *servingModel {
+		if err := s.ApplyConfig(c); err != nil {
+			t.Fatal(err)
+		}
+		m, ok := s.models.GetModelByName("test")
+		if !ok {
+			t.Fatal("model not found")
+		}
+		return m
+	}
+
+	writeSpec("sum")
+	first := served()
+	if served() != first {
+		t.Error("unchanged spec reloaded the model")
+	}
+	writeSpec("mean")
+	if served() == first {
+		t.Error("changed spec didn't reload the model")
+	}
+}
+
+// mapped reports whether file is mapped into the memory of the process.
+func mapped(t *testing.T, file string) bool {
+	t.Helper()
+	maps, err := os.ReadFile("/proc/self/maps")
+	if err != nil {
+		t.Skip("no /proc/self/maps:", err)
+	}
+	return bytes.Contains(maps, []byte(file))
+}
+
+func TestApplyConfigClosesStoresOnError(t *testing.T) {
+	dir := t.TempDir()
+	f, err := os.Create(filepath.Join(dir, "x"+params.RowsExt))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := params.WriteRows(f, tensor.New(tensor.WithBacking([]float32{1, 2, 3, 4}), tensor.WithShape(2, 2))); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	// sanity check of mapped
+	store, err := params.OpenDiskRowStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !mapped(t, f.Name()) {
+		t.Skip("row files are not mapped")
+	}
+	store.Close()
+
+	spec := &model.Spec{Name: "test", Type: model.LRModelType, Units: 2, Columns: []*model.ColumnSpec{{
+		Type: model.EmbeddingColumnType, Dimension: 2, Combiner: "sum",
+		Categorical: &model.ColumnSpec{Type: model.IdentityColumnType, Field: "x", Default: "0", Buckets: 2},
+	}}}
+	e := ModelEntry{Name: "test", Spec: spec} // no path, AddModel fails
+	e.Embeddings = &struct {
+		Dir       string `json:"dir"`
+		CacheRows int    `json:"cache_rows,omitempty"`
+	}{Dir: dir}
+
+	s := newTestServing(t)
+	if err := s.ApplyConfig(&Config{Models: []ModelEntry{e}}); err == nil {
+		t.Fatal("expected an error")
+	}
+	if mapped(t, f.Name()) {
+		t.Error("rows of the failed config are still mapped")
+	}
+}
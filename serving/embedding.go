@@ -0,0 +1,28 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/23 16:10
+ */
+
+package serving
+
+import "github.com/yinyajun/go-serving/params"
+
+// EmbeddingConfig looks up the embedding tables of Store instead of the model
+// files, e.g. for tables too large for memory. Only CacheRows hot rows are kept
+// in memory, they are shared by the versions of the model.
+type EmbeddingConfig struct {
+	Store     params.RowStore `validate:"required"`
+	CacheRows int             `validate:"gte=0"` // no rows are cached if 0
+}
+
+// params returns meta assembled with the stores of the model if any.
+func (s *servingModel) params(meta *params.Params) params.Meta {
+	var res params.Meta = meta
+	if s.config.Stores != nil {
+		res = s.config.Stores.Meta(res)
+	}
+	if s.tiered != nil {
+		res = s.tiered.Meta(res)
+	}
+	return res
+}
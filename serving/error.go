@@ -11,7 +11,14 @@ import (
 )
 
 var (
-	RegisterError = errors.New("must register before serving launched")
+	// Deprecated: models can be registered while serving, it's never returned.
+	RegisterError  = errors.New("must register before serving launched")
+	QueueFullError = errors.New("batching queue is full")
+	BatchSizeError = errors.New("expected same batch size")
+	ShutdownError  = errors.New("serving is shut down")
+	ClosedError    = errors.New("model is closed")
+
+	replacedError = errors.New("model is replaced") // retried on the new model
 )
 
 type DuplicatedError struct {
@@ -47,6 +54,61 @@ func (e EmptyDirError) Error() string {
 	return fmt.Sprintf("No files in dir %s", e.dir)
 }
 
+type NotAvailableError struct {
+	name  string
+	state ModelState
+}
+
+func (e NotAvailableError) Error() string {
+	return fmt.Sprintf("%s is not available, state: %s", e.name, e.state)
+}
+
+type WarmupError struct {
+	file string
+	line int
+	err  error
+}
+
+func (e WarmupError) Error() string {
+	if e.line > 0 {
+		return fmt.Sprintf("Warmup failed at %s:%d: %v", e.file, e.line, e.err)
+	}
+	return fmt.Sprintf("Warmup failed at %s: %v", e.file, e.err)
+}
+
+func (e WarmupError) Unwrap() error {
+	return e.err
+}
+
+type ConfigError struct {
+	file string
+	err  error
+}
+
+func (e ConfigError) Error() string {
+	return fmt.Sprintf("Invalid config %s: %v", e.file, e.err)
+}
+
+func (e ConfigError) Unwrap() error {
+	return e.err
+}
+
+type RolledBackError struct {
+	version, to uint64
+}
+
+func (e RolledBackError) Error() string {
+	return fmt.Sprintf("Version %d is rolled back to %d", e.version, e.to)
+}
+
+type BadVersionError struct {
+	version uint64
+}
+
+func (e BadVersionError) Error() string {
+	return fmt.Sprintf("Version %d is marked bad by rollback", e.version)
+}
+
 type UnregisteredError struct {
 	name  string
 	field string
@@ -0,0 +1,107 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/14 10:30
+ */
+
+package serving
+
+import (
+	"net/http"
+	"sort"
+)
+
+type ModelState int32
+
+const (
+	StateLoading ModelState = iota
+	StateAvailable
+	StateFailed
+	StateUnloading
+)
+
+var stateNames = [...]string{"LOADING", "AVAILABLE", "FAILED", "UNLOADING"}
+
+func (s ModelState) String() string {
+	if int(s) < len(stateNames) {
+		return stateNames[s]
+	}
+	return "UNKNOWN"
+}
+
+func (s ModelState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+type ModelStatus struct {
+	Name    string     `json:"name"`
+	Version uint64     `json:"version"`
+	State   ModelState `json:"state"`
+	Error   string     `json:"error,omitempty"`
+	// the version of the full model file and the applied delta files, if any
+	BaseVersion uint64   `json:"base_version,omitempty"`
+	Deltas      []uint64 `json:"deltas,omitempty"`
+}
+
+// Statuses returns the status of every registered model, sorted by name.
+func (s *Serving) Statuses() []ModelStatus {
+	models := s.models.All()
+	res := make([]ModelStatus, 0, len(models))
+	for _, m := range models {
+		res = append(res, m.Status())
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Ready reports whether serving is launched and all models are available.
+func (s *Serving) Ready() bool {
+	s.lock.RLock()
+	launched := s.launched
+	s.lock.RUnlock()
+	if !launched {
+		return false
+	}
+	for _, m := range s.models.All() {
+		if m.State() != StateAvailable {
+			return false
+		}
+	}
+	return true
+}
+
+// Healthy reports whether no model has failed to load.
+func (s *Serving) Healthy() bool {
+	for _, m := range s.models.All() {
+		if m.State() == StateFailed {
+			return false
+		}
+	}
+	return true
+}
+
+// HealthHandler serves /healthz and /readyz. /healthz is the liveness of the
+// process, it's always ok, since restarting doesn't fix a model failed to load.
+// /readyz answers the model statuses, 503 if not ready.
+func (s *Serving) HealthHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, struct {
+			OK bool `json:"ok"`
+		}{true})
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		s.writeStatuses(w, s.Ready())
+	})
+	return mux
+}
+
+func (s *Serving) writeStatuses(w http.ResponseWriter, ok bool) {
+	code := http.StatusOK
+	if !ok {
+		code = http.StatusServiceUnavailable
+	}
+	writeJSON(w, code, struct {
+		OK     bool          `json:"ok"`
+		Models []ModelStatus `json:"models"`
+	}{ok, s.Statuses()})
+}
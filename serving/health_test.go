@@ -0,0 +1,68 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 18:10
+ */
+
+package serving
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type healthResponse struct {
+	OK     bool `json:"ok"`
+	Models []struct {
+		Name  string `json:"name"`
+		State string `json:"state"`
+	} `json:"models"`
+}
+
+func getHealth(t *testing.T, h http.Handler, path string) (int, healthResponse) {
+	t.Helper()
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+	var res healthResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return w.Code, res
+}
+
+func TestHealthHandler(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, 1)
+	s := newTestServing(t)
+	h := s.HealthHandler()
+	if err := s.AddModel(&ModelConfig{Name: "test", Path: dir, Model: sumModel{}}); err != nil {
+		t.Fatal(err)
+	}
+
+	check := func(stage string, ready int, states ...ModelState) {
+		t.Helper()
+		if code, res := getHealth(t, h, "/healthz"); code != http.StatusOK || !res.OK || res.Models != nil {
+			t.Errorf("%s: /healthz got %d %+v", stage, code, res)
+		}
+		code, res := getHealth(t, h, "/readyz")
+		if code != ready || res.OK != (ready == http.StatusOK) || len(res.Models) != len(states) {
+			t.Fatalf("%s: /readyz got %d %+v, want %d", stage, code, res, ready)
+		}
+		for i, state := range states {
+			if res.Models[i].State != state.String() {
+				t.Errorf("%s: model %s got %v, want %v", stage, res.Models[i].Name, res.Models[i].State, state)
+			}
+		}
+	}
+
+	check("not launched", http.StatusServiceUnavailable, StateLoading)
+	s.Launch()
+	check("launched", http.StatusOK, StateAvailable)
+	// the model name in the files mismatches
+	s.AddModel(&ModelConfig{Name: "other", Path: dir, Model: sumModel{}})
+	check("failed", http.StatusServiceUnavailable, StateFailed, StateAvailable)
+	if s.Healthy() {
+		t.Error("got healthy with a failed model")
+	}
+}
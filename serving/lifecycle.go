@@ -0,0 +1,126 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/16 09:48
+ */
+
+package serving
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+)
+
+type lifecycle struct {
+	mu        sync.RWMutex
+	closed    bool
+	inflight  sync.WaitGroup
+	shutdowns []func(context.Context) error
+}
+
+// enter tracks a request, it returns false once shutdown started.
+func (l *lifecycle) enter() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return false
+	}
+	l.inflight.Add(1)
+	return true
+}
+
+func (l *lifecycle) leave() {
+	l.inflight.Done()
+}
+
+// close stops accepting requests, it returns false if already closed.
+func (l *lifecycle) close() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closed {
+		return false
+	}
+	l.closed = true
+	return true
+}
+
+func (l *lifecycle) isClosed() bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	return l.closed
+}
+
+// wait waits for the in-flight requests, or returns when ctx is done.
+func (l *lifecycle) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		l.inflight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// OnShutdown registers f to stop a network server when Shutdown, f is called
+// at once if Shutdown already started.
+func (s *Serving) OnShutdown(f func(context.Context) error) {
+	s.lifecycle.mu.Lock()
+	if s.lifecycle.closed {
+		s.lifecycle.mu.Unlock()
+		f(context.Background())
+		return
+	}
+	s.lifecycle.shutdowns = append(s.lifecycle.shutdowns, f)
+	s.lifecycle.mu.Unlock()
+}
+
+// ListenAndServe serves handler on addr until Shutdown, then it returns http.ErrServerClosed.
+func (s *Serving) ListenAndServe(addr string, handler http.Handler) error {
+	srv := &http.Server{Addr: addr, Handler: handler}
+	// registered before serving, a server shut down before it starts returns at once
+	s.OnShutdown(srv.Shutdown)
+	return srv.ListenAndServe()
+}
+
+// Shutdown stops watching, rejects new requests, stops the network servers,
+// waits for in-flight requests and batches, then releases the loaded params.
+// It returns ctx.Err() if ctx is done before requests are drained.
+func (s *Serving) Shutdown(ctx context.Context) error {
+	if !s.lifecycle.close() {
+		return ShutdownError
+	}
+	var errs []error
+	s.lock.RLock()
+	watcher := s.watcher
+	s.lock.RUnlock()
+	if watcher != nil {
+		if err := watcher.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	// servers still starting may register theirs concurrently
+	s.lifecycle.mu.RLock()
+	shutdowns := append([]func(context.Context) error(nil), s.lifecycle.shutdowns...)
+	s.lifecycle.mu.RUnlock()
+	for _, f := range shutdowns {
+		if err := f(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if err := s.lifecycle.wait(ctx); err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for _, m := range s.models.All() {
+		m.close()
+		s.logger.Info("model unloaded", "model", m.config.Name, "version", m.GetMeta().Version())
+		if err := m.release(ctx, nil); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,294 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/26 18:20
+ */
+
+package serving
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yinyajun/go-serving/model"
+	"github.com/yinyajun/go-serving/params"
+	"gorgonia.org/tensor"
+)
+
+func TestShutdownRacesOnShutdown(t *testing.T) {
+	s := newTestServing(t)
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.OnShutdown(func(context.Context) error { return nil })
+		}()
+	}
+	if err := s.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	wg.Wait()
+}
+
+func TestShutdownJoinsErrors(t *testing.T) {
+	s := newTestServing(t)
+	first, second := errors.New("first"), errors.New("second")
+	s.OnShutdown(func(context.Context) error { return first })
+	s.OnShutdown(func(context.Context) error { return second })
+	err := s.Shutdown(context.Background())
+	if !errors.Is(err, first) || !errors.Is(err, second) {
+		t.Fatalf("got %v, want both errors", err)
+	}
+}
+
+func TestListenAndServeRacesShutdown(t *testing.T) {
+	for i := 0; i < 20; i++ {
+		s := newTestServing(t)
+		done := make(chan error, 1)
+		go func() {
+			done <- s.ListenAndServe("127.0.0.1:0", http.NotFoundHandler())
+		}()
+		if i%2 == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		if err := s.Shutdown(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		select {
+		case err := <-done:
+			if err != http.ErrServerClosed {
+				t.Fatalf("got %v, want %v", err, http.ErrServerClosed)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("the server is not stopped by Shutdown")
+		}
+	}
+}
+
+// blockingModel blocks the requests until unblocked.
+type blockingModel struct {
+	sumModel
+	started chan struct{}
+	unblock chan struct{}
+}
+
+func (b blockingModel) Predict(ctx context.Context, m params.Meta, feats model.Features) (tensor.Tensor, error) {
+	b.started <- struct{}{}
+	<-b.unblock
+	return b.sumModel.Predict(ctx, m, feats)
+}
+
+// closingStore counts its Close calls.
+type closingStore struct {
+	*params.MemRowStore
+	closed atomic.Int32
+}
+
+func (s *closingStore) Close() error {
+	s.closed.Add(1)
+	return nil
+}
+
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	for start := time.Now(); !cond(); time.Sleep(time.Millisecond) {
+		if time.Since(start) > 5*time.Second {
+			t.Fatal("timeout")
+		}
+	}
+}
+
+func TestRemoveModelReleasesAfterRequests(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, 1)
+	s := newTestServing(t)
+	s.Launch()
+	store := &closingStore{MemRowStore: params.NewMemRowStore(0)}
+	b := blockingModel{started: make(chan struct{}), unblock: make(chan struct{})}
+	if err := s.AddModel(&ModelConfig{Name: "test", Path: dir, Model: b,
+		Embeddings: &EmbeddingConfig{Store: store}}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := s.GetModel("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		out, err := s.Request("test", features("1"))
+		if err == nil && out.Data().([]float32)[0] != 2 {
+			t.Errorf("got %v, want [2]", out.Data())
+		}
+		done <- err
+	}()
+	<-b.started
+	if err := s.RemoveModel("test"); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if store.closed.Load() != 0 || len(m.GetMeta().TensorNames()) == 0 {
+		t.Fatal("params are released before the request in flight")
+	}
+	close(b.unblock)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	// the params are closed before the store
+	eventually(t, func() bool { return store.closed.Load() == 1 })
+	if len(m.GetMeta().TensorNames()) != 0 {
+		t.Fatal("params are not released")
+	}
+}
+
+// removalMetrics records the removed models.
+type removalMetrics struct {
+	nopMetrics
+	removed []string
+}
+
+func (r *removalMetrics) ObserveRemoval(model string) {
+	r.removed = append(r.removed, model)
+}
+
+func TestRemoveModelObservesMetrics(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, 1)
+	metrics := new(removalMetrics)
+	s := newTestServing(t)
+	s.metrics = metrics
+	if err := s.AddModel(&ModelConfig{Name: "test", Path: dir, Model: sumModel{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.UpdateModel(&ModelConfig{Name: "test", Path: dir, Model: sumModel{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveModel("test"); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(metrics.removed, []string{"test"}) {
+		t.Fatalf("got removed %v, want [test]", metrics.removed)
+	}
+}
+
+func TestUpdateModelReleasesOld(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, 1)
+	s := newTestServing(t)
+	s.Launch()
+	shared := &closingStore{MemRowStore: params.NewMemRowStore(0)}
+	add := func(store *closingStore) {
+		c := &ModelConfig{Name: "test", Path: dir, Model: sumModel{}, Embeddings: &EmbeddingConfig{Store: store}}
+		var err error
+		if _, ok := s.models.GetModelByName("test"); ok {
+			err = s.UpdateModel(c)
+		} else {
+			err = s.AddModel(c)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	add(shared)
+	add(shared)
+	// the first model only purges the store shared with the second one, which closes it
+	add(&closingStore{MemRowStore: params.NewMemRowStore(0)})
+	eventually(t, func() bool { return shared.closed.Load() > 0 })
+	time.Sleep(10 * time.Millisecond)
+	if n := shared.closed.Load(); n != 1 {
+		t.Fatalf("the store is closed %d times", n)
+	}
+	if _, err := s.Request("test", features("1")); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReleaseClosesStores(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, 1)
+	s := newTestServing(t)
+	s.Launch()
+	shared := &closingStore{MemRowStore: params.NewMemRowStore(0)}
+	other := &closingStore{MemRowStore: params.NewMemRowStore(0)}
+	composite := func(store *closingStore) *params.Composite {
+		return params.NewComposite().AddEmbedding(params.NewTieredStore(store, 0, nil), "y")
+	}
+	sharedStores := composite(shared)
+	add := func(stores *params.Composite) {
+		t.Helper()
+		c := &ModelConfig{Name: "test", Path: dir, Model: sumModel{}, Stores: stores}
+		var err error
+		if _, ok := s.models.GetModelByName("test"); ok {
+			err = s.UpdateModel(c)
+		} else {
+			err = s.AddModel(c)
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	add(sharedStores)
+	add(sharedStores)
+	add(composite(other))
+	// only the model updated with other stores closes the shared ones
+	eventually(t, func() bool { return shared.closed.Load() > 0 })
+	if err := s.RemoveModel("test"); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, func() bool { return other.closed.Load() > 0 })
+	time.Sleep(10 * time.Millisecond)
+	if n, m := shared.closed.Load(), other.closed.Load(); n != 1 || m != 1 {
+		t.Fatalf("the stores are closed %d and %d times", n, m)
+	}
+}
+
+func TestUpdateModelServesAcrossSwaps(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, 1)
+	s := newTestServing(t)
+	s.Launch()
+	c := func() *ModelConfig { return &ModelConfig{Name: "test", Path: dir, Model: sumModel{}} }
+	if err := s.AddModel(c()); err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errs := make(chan error, 32)
+	for i := 0; i < 32; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				if _, err := s.Request("test", features("1")); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	for i := 0; i < 200; i++ {
+		if err := s.UpdateModel(c()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("request during swaps: %v (%s)", err, ErrorType(err))
+	}
+}
@@ -0,0 +1,21 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/13 10:05
+ */
+
+package serving
+
+import "log/slog"
+
+// Logger is a leveled structured logger, args are alternating keys and
+// values as in log/slog, which *slog.Logger implements.
+type Logger interface {
+	Debug(msg string, args ...any)
+	Info(msg string, args ...any)
+	Warn(msg string, args ...any)
+	Error(msg string, args ...any)
+}
+
+func defaultLogger() Logger {
+	return slog.Default().With("component", "go-serving")
+}
@@ -0,0 +1,83 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/11 10:20
+ */
+
+package serving
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Metrics records serving events, the default one records nothing.
+// See package metrics for a prometheus implementation.
+type Metrics interface {
+	// ObserveRequest records a request of the model, err is nil if succeeded.
+	ObserveRequest(model string, latency time.Duration, err error)
+	// ObserveBatch records the size of a merged batch.
+	ObserveBatch(model string, size int)
+	// ObserveLoad records loading a version of the model, size is the
+	// memory size of the loaded params.
+	ObserveLoad(model string, version uint64, duration time.Duration, size int64, err error)
+	// ObserveRollback records rolling the model back from a version to the
+	// previous one, size is the memory size of its params.
+	ObserveRollback(model string, from, to uint64, size int64)
+	// ObserveShadow records the mean absolute difference between the outputs of
+	// the shadow model and the served one of the route.
+	ObserveShadow(route, shadow string, diff float64, err error)
+	// ObserveCache records the rows of a request hit and missed by the output cache.
+	ObserveCache(model string, hits, misses int)
+	// ObserveEmbeddingCache records the rows of a lookup hit and missed by the
+	// row cache of a tiered embedding table.
+	ObserveEmbeddingCache(model, table string, hits, misses int)
+	// ObserveRemoval records removing the model, its gauges, e.g. the version,
+	// shouldn't be reported any more.
+	ObserveRemoval(model string)
+}
+
+type nopMetrics struct{}
+
+func (nopMetrics) ObserveRequest(string, time.Duration, error)             {}
+func (nopMetrics) ObserveBatch(string, int)                                {}
+func (nopMetrics) ObserveLoad(string, uint64, time.Duration, int64, error) {}
+func (nopMetrics) ObserveRollback(string, uint64, uint64, int64)           {}
+func (nopMetrics) ObserveShadow(string, string, float64, error)            {}
+func (nopMetrics) ObserveCache(string, int, int)                           {}
+func (nopMetrics) ObserveEmbeddingCache(string, string, int, int)          {}
+func (nopMetrics) ObserveRemoval(string)                                   {}
+
+// ErrorType classifies err into a short label for metrics.
+func ErrorType(err error) string {
+	var (
+		notFound     NotFoundError
+		notMatch     NotMatchError
+		unregistered UnregisteredError
+		notAvailable NotAvailableError
+	)
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, context.DeadlineExceeded):
+		return "deadline_exceeded"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, QueueFullError):
+		return "queue_full"
+	case errors.Is(err, BatchSizeError):
+		return "batch_size"
+	case errors.Is(err, ShutdownError):
+		return "shutdown"
+	case errors.As(err, &notFound):
+		return "not_found"
+	case errors.As(err, &notMatch):
+		return "not_match"
+	case errors.As(err, &unregistered):
+		return "unregistered"
+	case errors.As(err, &notAvailable), errors.Is(err, ClosedError):
+		return "not_available"
+	default:
+		return "internal"
+	}
+}
@@ -0,0 +1,20 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/11 10:42
+ */
+
+package serving
+
+type Option func(*Serving)
+
+func WithMetrics(m Metrics) Option {
+	return func(s *Serving) {
+		s.metrics = m
+	}
+}
+
+func WithLogger(l Logger) Option {
+	return func(s *Serving) {
+		s.logger = l
+	}
+}
@@ -0,0 +1,30 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/10 11:05
+ */
+
+package serving
+
+import "time"
+
+type requestOptions struct {
+	timeout time.Duration
+	key     string
+}
+
+type RequestOption func(*requestOptions)
+
+// WithTimeout overrides the default timeout of the model, 0 means no timeout.
+func WithTimeout(timeout time.Duration) RequestOption {
+	return func(o *requestOptions) {
+		o.timeout = timeout
+	}
+}
+
+// WithRoutingKey routes the requests of the same key, e.g. a user id, to the
+// same split of a Route.
+func WithRoutingKey(key string) RequestOption {
+	return func(o *requestOptions) {
+		o.key = key
+	}
+}
@@ -0,0 +1,84 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/18 11:20
+ */
+
+package serving
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/yinyajun/go-serving/model"
+)
+
+type predictRequest struct {
+	Features model.Features `json:"features"`
+}
+
+type predictResponse struct {
+	ModelName    string         `json:"model_name"`
+	ModelVersion uint64         `json:"model_version"`
+	Outputs      model.Features `json:"outputs"`
+}
+
+// PredictHandler serves POST /v1/models/{name}:predict, the body is
+//
+//	{"features": {"F1": {"shape": [1, 3], "string_val": ["123", "124", "125"]}}}
+func (s *Serving) PredictHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/v1/models/")
+		name, ok := strings.CutSuffix(name, ":predict")
+		if !ok || name == "" {
+			writeError(w, http.StatusNotFound, NotFoundError{name: r.URL.Path, field: "paths"})
+			return
+		}
+		if r.Method != http.MethodPost {
+			writeError(w, http.StatusMethodNotAllowed, errors.New(r.Method+" not allowed"))
+			return
+		}
+		var req predictRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		version := uint64(0)
+		if m, err := s.GetModel(name); err == nil {
+			version = m.GetMeta().Version()
+		}
+		out, err := s.RequestContext(r.Context(), name, req.Features)
+		if err != nil {
+			writeError(w, HTTPStatus(err), err)
+			return
+		}
+		writeJSON(w, http.StatusOK, predictResponse{
+			ModelName:    name,
+			ModelVersion: version,
+			Outputs:      model.Features{"output": out},
+		})
+	})
+}
+
+// HTTPStatus maps err of a request to the http status code.
+func HTTPStatus(err error) int {
+	switch ErrorType(err) {
+	case "":
+		return http.StatusOK
+	case "not_found":
+		return http.StatusNotFound
+	case "deadline_exceeded":
+		return http.StatusGatewayTimeout
+	case "canceled":
+		return 499 // client closed request
+	case "queue_full":
+		return http.StatusTooManyRequests
+	case "not_available", "shutdown":
+		return http.StatusServiceUnavailable
+	case "batch_size", "not_match":
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
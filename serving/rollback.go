@@ -0,0 +1,186 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/20 10:40
+ */
+
+package serving
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+	"unsafe"
+
+	"github.com/yinyajun/go-serving/params"
+	"gorgonia.org/tensor"
+)
+
+// RollbackConfig reverts a newly swapped version to the previous one, if it fails
+// or shifts the outputs too much within the grace period.
+type RollbackConfig struct {
+	GracePeriod  time.Duration `validate:"required,gt=0"` // the previous version is kept during it
+	MinRequests  int           `validate:"gte=0"`         // requests observed before judging, default 100
+	MaxErrorRate float64       `validate:"gte=0,lte=1"`   // of internal errors and NaN outputs, 0 disables it
+	MaxMeanShift float64       `validate:"gte=0"`         // of the mean output from the previous version, 0 disables it
+	// counts the requests exceeding their deadlines as errors, e.g. if the
+	// deadlines are set by Timeout rather than by the clients
+	CountDeadlines bool
+}
+
+// stats of the outputs of a version.
+type stats struct {
+	version  uint64
+	requests int
+	errors   int
+	sum      float64
+	n        int
+}
+
+func (s *stats) mean() (float64, bool) {
+	if s.n == 0 {
+		return 0, false
+	}
+	return s.sum / float64(s.n), true
+}
+
+type guard struct {
+	conf RollbackConfig
+
+	mu       sync.Mutex
+	cur      stats
+	prev     *params.Params // kept until deadline
+	deadline time.Time
+	baseMean float64
+	hasBase  bool
+	bad      map[uint64]bool
+}
+
+func newGuard(conf RollbackConfig) *guard {
+	if conf.MinRequests == 0 {
+		conf.MinRequests = 100
+	}
+	return &guard{conf: conf, bad: make(map[uint64]bool)}
+}
+
+// swapped starts guarding the new version, the outputs of old are the baseline.
+func (g *guard) swapped(old, meta *params.Params) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if old.Version() == 0 {
+		g.cur = stats{version: meta.Version()}
+		return // nothing to roll back to
+	}
+	g.baseMean, g.hasBase = g.cur.mean()
+	g.cur = stats{version: meta.Version()}
+	g.prev = old
+	g.deadline = time.Now().Add(g.conf.GracePeriod)
+}
+
+// observe records a request predicted by version, it returns the previous
+// params if the version should be rolled back.
+func (g *guard) observe(version uint64, out tensor.Tensor, err error) *params.Params {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if version != g.cur.version {
+		return nil // swapped during the request
+	}
+	g.cur.requests++
+	switch ErrorType(err) {
+	case "":
+		if !g.record(out) {
+			g.cur.errors++
+		}
+	case "internal":
+		g.cur.errors++
+	case "deadline_exceeded":
+		if g.conf.CountDeadlines {
+			g.cur.errors++
+		}
+	}
+
+	if g.prev == nil {
+		return nil
+	}
+	if time.Now().After(g.deadline) {
+		g.prev = nil // releases the previous version
+		return nil
+	}
+	if g.cur.requests < g.conf.MinRequests {
+		return nil
+	}
+	rate := float64(g.cur.errors) / float64(g.cur.requests)
+	mean, ok := g.cur.mean()
+	if (g.conf.MaxErrorRate > 0 && rate > g.conf.MaxErrorRate) ||
+		(g.conf.MaxMeanShift > 0 && ok && g.hasBase && math.Abs(mean-g.baseMean) > g.conf.MaxMeanShift) {
+		prev := g.prev
+		g.prev = nil
+		g.bad[version] = true
+		g.cur = stats{version: prev.Version()}
+		return prev
+	}
+	return nil
+}
+
+// record adds the outputs to the stats, it returns false on NaN or Inf.
+func (g *guard) record(out tensor.Tensor) bool {
+	if out == nil {
+		return true
+	}
+	var sum float64
+	var n int
+	switch data := out.Data().(type) {
+	case []float32:
+		for _, v := range data {
+			sum += float64(v)
+		}
+		n = len(data)
+	case []float64:
+		for _, v := range data {
+			sum += v
+		}
+		n = len(data)
+	default:
+		return true
+	}
+	if math.IsNaN(sum) || math.IsInf(sum, 0) {
+		return false
+	}
+	g.cur.sum += sum
+	g.cur.n += n
+	return true
+}
+
+func (g *guard) isBad(version uint64) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.bad[version]
+}
+
+// observe guards the version after a request, and rolls it back if it's bad.
+func (s *Serving) observe(m *servingModel, version uint64, out tensor.Tensor, err error) {
+	if m.guard == nil {
+		return
+	}
+	prev := m.guard.observe(version, out, err)
+	if prev == nil {
+		return
+	}
+	cur := m.GetMeta()
+	if cur.Version() != version {
+		return
+	}
+	s.lock.Lock()
+	swapped := atomic.CompareAndSwapPointer(&(m.meta), unsafe.Pointer(cur), unsafe.Pointer(prev))
+	m.startTime = time.Now()
+	s.lock.Unlock()
+	if !swapped {
+		return
+	}
+	if m.cache != nil {
+		m.cache.purge()
+	}
+	s.metrics.ObserveRollback(m.config.Name, version, prev.Version(), prev.MemorySize())
+	m.setState(StateAvailable, RolledBackError{version: version, to: prev.Version()})
+	s.logger.Warn("model version rolled back", "model", m.config.Name, "from", version, "to", prev.Version())
+}
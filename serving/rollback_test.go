@@ -0,0 +1,73 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/26 19:10
+ */
+
+package serving
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"unsafe"
+
+	"github.com/yinyajun/go-serving/params"
+)
+
+func loadParams(t *testing.T, dir string, version uint64) *params.Params {
+	t.Helper()
+	meta := params.New(writeModel(t, dir, version))
+	if err := meta.Load(); err != nil {
+		t.Fatal(err)
+	}
+	return meta
+}
+
+func TestGuardDeadlines(t *testing.T) {
+	dir := t.TempDir()
+	v1, v2 := loadParams(t, dir, 1), loadParams(t, dir, 2)
+	for _, count := range []bool{false, true} {
+		g := newGuard(RollbackConfig{GracePeriod: time.Minute, MinRequests: 2, MaxErrorRate: 0.5, CountDeadlines: count})
+		g.swapped(v1, v2)
+		var prev *params.Params
+		for i := 0; i < 3 && prev == nil; i++ {
+			prev = g.observe(2, nil, context.DeadlineExceeded)
+		}
+		if (prev != nil) != count {
+			t.Errorf("CountDeadlines %v: rolled back %v", count, prev != nil)
+		}
+	}
+}
+
+type rollbackMetrics struct {
+	nopMetrics
+	model    string
+	from, to uint64
+	size     int64
+}
+
+func (r *rollbackMetrics) ObserveRollback(model string, from, to uint64, size int64) {
+	r.model, r.from, r.to, r.size = model, from, to, size
+}
+
+func TestRollbackObservesMetrics(t *testing.T) {
+	dir := t.TempDir()
+	v1, v2 := loadParams(t, dir, 1), loadParams(t, dir, 2)
+	metrics := new(rollbackMetrics)
+	s := newTestServing(t)
+	s.metrics = metrics
+
+	m := s.newModel(&ModelConfig{Name: "test", Path: dir, Model: sumModel{},
+		Rollback: &RollbackConfig{GracePeriod: time.Minute, MinRequests: 1, MaxErrorRate: 0.5}})
+	m.meta = unsafe.Pointer(v2)
+	m.guard.swapped(v1, v2)
+	s.observe(m, 2, nil, errors.New("internal"))
+
+	if m.GetMeta().Version() != 1 {
+		t.Fatalf("got version %d, want 1", m.GetMeta().Version())
+	}
+	if metrics.model != "test" || metrics.from != 2 || metrics.to != 1 || metrics.size != v1.MemorySize() {
+		t.Fatalf("got %+v", *metrics)
+	}
+}
@@ -0,0 +1,197 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/21 10:30
+ */
+
+package serving
+
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yinyajun/go-serving/model"
+	"gorgonia.org/tensor"
+)
+
+// Split routes Weight parts of the traffic to Model, e.g. a model pinned to a version.
+type Split struct {
+	Model  string `validate:"required"`
+	Weight int    `validate:"gte=0"`
+}
+
+// Route splits the requests of Name between models, and optionally shadows them.
+type Route struct {
+	Name   string  `validate:"required"`
+	Splits []Split `validate:"required,min=1,dive"`
+	// optional, predicted asynchronously after the served model, its outputs are
+	// only compared to the served ones
+	Shadow        string
+	ShadowTimeout time.Duration // default 1s
+}
+
+// Router is a routing layer above Serving, requests of names without routes
+// are passed through.
+type Router struct {
+	serving   *Serving
+	mu        sync.RWMutex
+	routes    map[string]*Route
+	shadowing chan struct{} // limits the concurrent shadow requests
+}
+
+func NewRouter(s *Serving) *Router {
+	return &Router{
+		serving:   s,
+		routes:    make(map[string]*Route),
+		shadowing: make(chan struct{}, 64),
+	}
+}
+
+// SetRoute adds or replaces the route of its name, the models of the route must
+// be registered.
+func (r *Router) SetRoute(rt *Route) error {
+	if err := valid.Struct(rt); err != nil {
+		return err
+	}
+	total := 0
+	for _, sp := range rt.Splits {
+		if _, err := r.serving.GetModel(sp.Model); err != nil {
+			return err
+		}
+		total += sp.Weight
+	}
+	if total == 0 {
+		return NotMatchError{expected: "positive total weight", provided: "0"}
+	}
+	if rt.Shadow != "" {
+		if _, err := r.serving.GetModel(rt.Shadow); err != nil {
+			return err
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.routes[rt.Name] = rt
+	return nil
+}
+
+func (r *Router) DeleteRoute(name string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	_, ok := r.routes[name]
+	delete(r.routes, name)
+	return ok
+}
+
+// Routes returns the routes sorted by name.
+func (r *Router) Routes() []*Route {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	res := make([]*Route, 0, len(r.routes))
+	for _, rt := range r.routes {
+		res = append(res, rt)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].Name < res[j].Name })
+	return res
+}
+
+// Request predicts feats by a split of the route of name, which is chosen by the
+// hash of the routing key if any, or randomly.
+func (r *Router) Request(ctx context.Context, name string, feats model.Features, opts ...RequestOption) (tensor.Tensor, error) {
+	r.mu.RLock()
+	rt, ok := r.routes[name]
+	r.mu.RUnlock()
+	if !ok {
+		return r.serving.RequestContext(ctx, name, feats, opts...)
+	}
+	var o requestOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	served := rt.pick(o.key)
+	out, err := r.serving.RequestContext(ctx, served, feats, opts...)
+	if rt.Shadow != "" && err == nil {
+		r.shadow(ctx, rt, served, feats, out)
+	}
+	return out, err
+}
+
+func (rt *Route) pick(key string) string {
+	total := 0
+	for _, sp := range rt.Splits {
+		total += sp.Weight
+	}
+	var n int
+	if key != "" {
+		h := fnv.New64a()
+		h.Write([]byte(key))
+		n = int(h.Sum64() % uint64(total))
+	} else {
+		n = rand.Intn(total)
+	}
+	for _, sp := range rt.Splits {
+		if n < sp.Weight {
+			return sp.Model
+		}
+		n -= sp.Weight
+	}
+	return rt.Splits[len(rt.Splits)-1].Model
+}
+
+// shadow predicts feats by the shadow model in background, it's skipped if too
+// many shadow requests are running. The caller owns feats and out once it
+// returns, so the background request works on copies.
+func (r *Router) shadow(ctx context.Context, rt *Route, served string, feats model.Features, out tensor.Tensor) {
+	select {
+	case r.shadowing <- struct{}{}:
+	default:
+		return
+	}
+	out = out.Clone().(tensor.Tensor)
+	copied := make(model.Features, len(feats))
+	for name, t := range feats {
+		copied[name] = t.Clone().(tensor.Tensor)
+	}
+	feats = copied
+	timeout := rt.ShadowTimeout
+	if timeout == 0 {
+		timeout = time.Second
+	}
+	// keeps the trace of the request, but not its cancellation
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	go func() {
+		defer func() { <-r.shadowing }()
+		defer cancel()
+		shadowOut, err := r.serving.RequestContext(ctx, rt.Shadow, feats)
+		var diff float64
+		if err == nil {
+			diff, err = meanAbsDiff(out, shadowOut)
+		}
+		r.serving.metrics.ObserveShadow(rt.Name, rt.Shadow, diff, err)
+		r.serving.logger.Debug("shadow request", "route", rt.Name, "model", served,
+			"shadow", rt.Shadow, "diff", diff, "err", err)
+	}()
+}
+
+func meanAbsDiff(a, b tensor.Tensor) (float64, error) {
+	if !a.Shape().Eq(b.Shape()) {
+		return 0, NotMatchError{expected: fmt.Sprint(a.Shape()), provided: fmt.Sprint(b.Shape())}
+	}
+	x, ok1 := a.Data().([]float32)
+	y, ok2 := b.Data().([]float32)
+	if !ok1 || !ok2 {
+		return 0, NotMatchError{expected: "float32 outputs", provided: fmt.Sprintf("%v and %v", a.Dtype(), b.Dtype())}
+	}
+	if len(x) == 0 {
+		return 0, nil
+	}
+	var sum float64
+	for i := range x {
+		sum += math.Abs(float64(x[i]) - float64(y[i]))
+	}
+	return sum / float64(len(x)), nil
+}
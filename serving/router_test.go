@@ -0,0 +1,172 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 15:20
+ */
+
+package serving
+
+import (
+	"context"
+	"errors"
+	"math"
+	"strconv"
+	"testing"
+)
+
+// newRouter serves model v of version v for each v, so its outputs of feature "0" are v.
+func newRouter(t *testing.T, versions ...uint64) (*Serving, *Router) {
+	t.Helper()
+	s := newTestServing(t)
+	s.Launch()
+	for _, v := range versions {
+		dir := t.TempDir()
+		writeModel(t, dir, v)
+		name := strconv.FormatUint(v, 10)
+		if err := s.AddModel(&ModelConfig{Name: name, ModelName: "test", Path: dir, Model: sumModel{}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, NewRouter(s)
+}
+
+// served returns the model which predicted the request of name.
+func served(t *testing.T, r *Router, name string, opts ...RequestOption) string {
+	t.Helper()
+	out, err := r.Request(context.Background(), name, features("0"), opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strconv.Itoa(int(out.Data().([]float32)[0]))
+}
+
+func TestSetRouteChecksModels(t *testing.T) {
+	_, r := newRouter(t, 1, 2)
+	cases := []struct {
+		name string
+		rt   *Route
+		err  error
+	}{
+		{"ok", &Route{Name: "r", Splits: []Split{{Model: "1", Weight: 1}}, Shadow: "2"}, nil},
+		{"unknown split", &Route{Name: "r", Splits: []Split{{Model: "1", Weight: 1}, {Model: "3", Weight: 1}}},
+			NotFoundError{name: "3", field: "models"}},
+		{"unknown shadow", &Route{Name: "r", Splits: []Split{{Model: "1", Weight: 1}}, Shadow: "3"},
+			NotFoundError{name: "3", field: "models"}},
+		{"zero weight", &Route{Name: "r", Splits: []Split{{Model: "1"}}},
+			NotMatchError{expected: "positive total weight", provided: "0"}},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if err := r.SetRoute(c.rt); err != c.err {
+				t.Fatalf("got %v, want %v", err, c.err)
+			}
+		})
+	}
+}
+
+func TestRouterSplitsByWeight(t *testing.T) {
+	_, r := newRouter(t, 1, 2)
+	if err := r.SetRoute(&Route{Name: "r", Splits: []Split{{Model: "1", Weight: 3}, {Model: "2", Weight: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	const n = 4000
+	counts := make(map[string]int)
+	for i := 0; i < n; i++ {
+		counts[served(t, r, "r")]++
+	}
+	// the stddev of the count of model 1 is about 27
+	if math.Abs(float64(counts["1"])-n*3/4) > 200 || counts["1"]+counts["2"] != n {
+		t.Fatalf("got %v, want about 3:1", counts)
+	}
+	// names without routes are passed through
+	if got := served(t, r, "2"); got != "2" {
+		t.Fatalf("got model %s, want 2", got)
+	}
+}
+
+func TestRouterStickyKeys(t *testing.T) {
+	_, r := newRouter(t, 1, 2)
+	if err := r.SetRoute(&Route{Name: "r", Splits: []Split{{Model: "1", Weight: 1}, {Model: "2", Weight: 1}}}); err != nil {
+		t.Fatal(err)
+	}
+	counts := make(map[string]int)
+	for i := 0; i < 100; i++ {
+		key := WithRoutingKey("user" + strconv.Itoa(i))
+		first := served(t, r, "r", key)
+		for j := 0; j < 5; j++ {
+			if got := served(t, r, "r", key); got != first {
+				t.Fatalf("key %d: got model %s, want %s", i, got, first)
+			}
+		}
+		counts[first]++
+	}
+	if counts["1"] == 0 || counts["2"] == 0 {
+		t.Fatalf("keys are not split: %v", counts)
+	}
+}
+
+// shadowMetrics sends the observed shadow requests.
+type shadowMetrics struct {
+	nopMetrics
+	observed chan shadowObservation
+}
+
+type shadowObservation struct {
+	route, shadow string
+	diff          float64
+	err           error
+}
+
+func (m shadowMetrics) ObserveShadow(route, shadow string, diff float64, err error) {
+	m.observed <- shadowObservation{route, shadow, diff, err}
+}
+
+func TestRouterShadows(t *testing.T) {
+	s, r := newRouter(t, 1)
+	metrics := shadowMetrics{observed: make(chan shadowObservation, 1)}
+	s.metrics = metrics
+	dir := t.TempDir()
+	writeModel(t, dir, 3)
+	b := blockingModel{started: make(chan struct{}), unblock: make(chan struct{})}
+	if err := s.AddModel(&ModelConfig{Name: "shadow", ModelName: "test", Path: dir, Model: b}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetRoute(&Route{Name: "r", Splits: []Split{{Model: "1", Weight: 1}}, Shadow: "shadow"}); err != nil {
+		t.Fatal(err)
+	}
+
+	feats := features("0")
+	out, err := r.Request(context.Background(), "r", feats)
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-b.started
+	// the caller reuses its inputs and outputs while the shadow request is running
+	feats["x"].Data().([]string)[0] = "1"
+	out.Data().([]float32)[0] = 100
+	close(b.unblock)
+
+	got := <-metrics.observed
+	if got.route != "r" || got.shadow != "shadow" || got.err != nil || got.diff != 2 {
+		t.Fatalf("got %+v, want diff 2", got)
+	}
+}
+
+func TestRouterSkipsShadowOnError(t *testing.T) {
+	s, r := newRouter(t, 1, 2)
+	metrics := shadowMetrics{observed: make(chan shadowObservation, 1)}
+	s.metrics = metrics
+	if err := r.SetRoute(&Route{Name: "r", Splits: []Split{{Model: "1", Weight: 1}}, Shadow: "2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.RemoveModel("1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Request(context.Background(), "r", features("0")); !errors.As(err, &NotFoundError{}) {
+		t.Fatalf("got %v, want NotFoundError", err)
+	}
+	select {
+	case got := <-metrics.observed:
+		t.Fatalf("shadowed a failed request: %+v", got)
+	default:
+	}
+}
@@ -6,9 +6,13 @@
 package serving
 
 import (
-	"log"
+	"context"
+	"errors"
+	"fmt"
 	"os"
 	"path"
+	"strconv"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -17,37 +21,192 @@ import (
 	"github.com/fsnotify/fsnotify"
 	"github.com/yinyajun/go-serving/model"
 	"github.com/yinyajun/go-serving/params"
+	"github.com/yinyajun/go-serving/source"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
 	"gorgonia.org/tensor"
 )
 
+var tracer = otel.Tracer("github.com/yinyajun/go-serving/serving")
+
+// sourceTimeout bounds listing the versions of a model source and loading one.
+const sourceTimeout = 10 * time.Minute
+
 type Serving struct {
 	launched bool
 	once     sync.Once
 	lock     sync.RWMutex
 	watcher  *fsnotify.Watcher
-	models   modelManager
+	models   *modelManager
+	metrics  Metrics
+	logger   Logger
+
+	lifecycle lifecycle
+
+	configLock sync.Mutex
+	applied    map[string]ModelEntry // models added by config
 }
 
-func New() *Serving {
+func New(opts ...Option) *Serving {
 	serving := &Serving{
-		models: modelManager{
-			paths: make(map[string]*servingModel),
+		models: &modelManager{
+			paths: make(map[string][]*servingModel),
 			names: make(map[string]*servingModel),
 		},
+		metrics: nopMetrics{},
+		logger:  defaultLogger(),
+	}
+	for _, opt := range opts {
+		opt(serving)
 	}
 	return serving
 }
 
+// Register registers a model and panics on error, see AddModel.
 func (s *Serving) Register(c *ModelConfig) {
-	if s.launched {
-		log.Panicln(RegisterError)
+	if err := s.AddModel(c); err != nil {
+		s.logger.Error("model register failed", "model", c.Name, "err", err)
+		panic(err)
+	}
+}
+
+// AddModel registers a model, it's loaded and watched at once if serving is launched.
+// A model failed to load is still registered, see ModelStatus.
+func (s *Serving) AddModel(c *ModelConfig) error {
+	if s.lifecycle.isClosed() {
+		return ShutdownError
 	}
 	if err := valid.Struct(c); err != nil {
-		log.Panicln(err)
+		return err
+	}
+	c = c.resolve()
+	m := s.newModel(c)
+	if err := s.models.Set(m); err != nil {
+		m.close()
+		return err
+	}
+	s.lock.RLock()
+	launched := s.launched
+	s.lock.RUnlock()
+	if !launched {
+		return nil
+	}
+	return s.start(m)
+}
+
+// UpdateModel replaces the config of a registered model. The latest version is loaded
+// with the new config before it's swapped in, the old config keeps serving on error.
+// Requests never fail by the swap, the old model is closed after its requests in flight.
+func (s *Serving) UpdateModel(c *ModelConfig) error {
+	if s.lifecycle.isClosed() {
+		return ShutdownError
+	}
+	if err := valid.Struct(c); err != nil {
+		return err
+	}
+	c = c.resolve()
+	old, ok := s.models.GetModelByName(c.Name)
+	if !ok {
+		return NotFoundError{name: c.Name, field: "models"}
+	}
+	m := s.newModel(c)
+	s.lock.RLock()
+	launched := s.launched
+	s.lock.RUnlock()
+	if launched {
+		var err error
+		if c.Source != nil {
+			var version uint64
+			if version, err = c.sourceVersion(); err == nil {
+				err = s.loadVersion(m, version)
+			}
+		} else {
+			var file string
+			if file, err = c.modelFile(); err == nil {
+				err = s.load(m, file)
+			}
+		}
+		if err != nil {
+			m.close()
+			return err
+		}
+	}
+	if _, err := s.models.Replace(m); err != nil {
+		m.close()
+		return err
+	}
+	// the old one serves its requests in flight, it's closed once they are done
+	old.replaced.Store(true)
+	old.stop()
+	s.release(old, m)
+	if launched {
+		oldPath, oldWatched := old.config.watchedPath()
+		newPath, newWatched := c.watchedPath()
+		if oldWatched && (!newWatched || oldPath != newPath) {
+			s.unwatch(oldPath)
+		}
+		if newWatched && (!oldWatched || oldPath != newPath) {
+			if err := s.watch(newPath); err != nil {
+				return err
+			}
+		}
+		if !newWatched {
+			s.subscribe(m)
+		}
+	}
+	s.logger.Info("model updated", "model", c.Name, "path", c.Path, "version", m.GetMeta().Version())
+	return nil
+}
+
+// RemoveModel unregisters a model, its enqueued requests are still predicted.
+func (s *Serving) RemoveModel(name string) error {
+	m, ok := s.models.Delete(name)
+	if !ok {
+		return NotFoundError{name: name, field: "models"}
+	}
+	if p, ok := m.config.watchedPath(); ok {
+		s.unwatch(p)
+	}
+	m.close()
+	s.metrics.ObserveRemoval(name)
+	s.logger.Info("model unloaded", "model", name, "version", m.GetMeta().Version())
+	s.release(m, nil)
+	return nil
+}
+
+// release closes the params of the closed model m, replaced by next if any,
+// once its requests in flight are done.
+func (s *Serving) release(m, next *servingModel) {
+	go func() {
+		if err := m.release(context.Background(), next); err != nil {
+			s.logger.Error("model release failed", "model", m.config.Name, "err", err)
+		}
+	}()
+}
+
+func (s *Serving) newModel(c *ModelConfig) *servingModel {
+	m := newServingModel(c)
+	if c.Rollback != nil {
+		m.guard = newGuard(*c.Rollback)
+	}
+	if c.Cache != nil {
+		m.cache = newOutputCache(*c.Cache, func(hits, misses int) {
+			s.metrics.ObserveCache(c.Name, hits, misses)
+		})
+	}
+	if c.Embeddings != nil {
+		m.tiered = params.NewTieredStore(c.Embeddings.Store, c.Embeddings.CacheRows, func(table string, hits, misses int) {
+			s.metrics.ObserveEmbeddingCache(c.Name, table, hits, misses)
+		})
 	}
-	if err := s.models.Set(c); err != nil {
-		log.Panicln(err)
+	if c.Batching != nil {
+		m.batcher = newBatcher(*c.Batching, m.predict, func(size int) {
+			s.metrics.ObserveBatch(c.Name, size)
+		})
 	}
+	return m
 }
 
 func (s *Serving) Launch() {
@@ -57,80 +216,348 @@ func (s *Serving) Launch() {
 }
 
 func (s *Serving) launch() {
-	// init names
-	for p, _ := range s.models.paths {
-		file, err := LatestModel(p)
-		if err != nil {
-			log.Panicln(err)
-		}
-		if err = s.UpdateMeta(file); err != nil {
-			log.Panicln(err)
-		}
-	}
 	// watch
 	watch, err := fsnotify.NewWatcher()
 	if err != nil {
-		log.Panicln(err)
+		s.logger.Error("watcher create failed", "err", err)
+		panic(err)
+	}
+	s.lock.Lock()
+	s.watcher = watch
+	s.launched = true
+	s.lock.Unlock()
+	// init names, models failed to load or watch are reported by Healthy
+	for _, m := range s.models.All() {
+		if err := s.start(m); err != nil {
+			m.loadFailed(err)
+			s.logger.Error("model watch failed", "model", m.config.Name, "err", err)
+		}
+	}
+}
+
+// start loads the served version of m and watches the new versions.
+func (s *Serving) start(m *servingModel) error {
+	if m.config.Source == nil {
+		s.loadLatest(m)
+		return s.watch(m.config.Path)
+	}
+	version, err := m.config.sourceVersion()
+	if err != nil {
+		s.logger.Error("model load failed", "model", m.config.Name, "source", m.config.Source.String(), "err", err)
+		m.loadFailed(err)
+	} else {
+		_ = s.loadVersion(m, version)
 	}
-	for p, _ := range s.models.paths {
-		err := watch.Add(p)
+	s.subscribe(m)
+	return nil
+}
+
+// subscribe loads the new versions of the model source until m is closed.
+func (s *Serving) subscribe(m *servingModel) {
+	ctx, cancel := context.WithCancel(context.Background())
+	m.mu.Lock()
+	if m.state == StateUnloading {
+		m.mu.Unlock()
+		cancel()
+		return
+	}
+	m.cancel = cancel
+	m.mu.Unlock()
+	c := m.config
+	go func() {
+		err := c.Source.Subscribe(ctx, func(ev source.Event) {
+			switch {
+			case ev.Err != nil:
+				s.logger.Error("watch error", "model", c.Name, "source", c.Source.String(), "err", ev.Err)
+			case c.Version == ev.Version, c.Version == 0 && ev.Version >= m.GetMeta().Version():
+				// loadVersion logs its failures
+				_ = s.loadVersion(m, ev.Version)
+			}
+		})
+		if err != nil && ctx.Err() == nil {
+			s.logger.Error("watch error", "model", c.Name, "source", c.Source.String(), "err", err)
+		}
+	}()
+}
+
+// loadVersion loads the version from the model source.
+func (s *Serving) loadVersion(m *servingModel, version uint64) error {
+	src := m.config.Source
+	name := fmt.Sprintf("%s@%d", src.String(), version)
+	return s.loadWith(m, name, func(meta *params.Params) error {
+		ctx, cancel := context.WithTimeout(context.Background(), sourceTimeout)
+		defer cancel()
+		f, err := src.Open(ctx, version)
 		if err != nil {
-			log.Panicln(err)
+			return err
 		}
+		defer f.Close()
+		return meta.LoadFrom(f, f.Size())
+	})
+}
+
+func (s *Serving) watch(dir string) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return s.watcher.Add(dir)
+}
+
+// unwatch stops watching dir if no models use it.
+func (s *Serving) unwatch(dir string) {
+	if len(s.models.GetModelsByPath(dir)) > 0 {
+		return
+	}
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	if s.watcher != nil {
+		_ = s.watcher.Remove(dir)
 	}
-	s.watcher = watch
-	s.launched = true
 }
 
+// loadLatest loads the served version in the model path, failures are logged.
+func (s *Serving) loadLatest(m *servingModel) {
+	file, err := m.config.modelFile()
+	if err != nil {
+		s.logger.Error("model load failed", "model", m.config.Name, "path", m.config.Path, "err", err)
+		m.loadFailed(err)
+		return
+	}
+	_ = s.load(m, file)
+}
+
+// Close is Shutdown without deadline.
 func (s *Serving) Close() error {
-	return s.watcher.Close()
+	return s.Shutdown(context.Background())
 }
 
+// Watch loads the new model files until Shutdown.
 func (s *Serving) Watch() {
+	s.lock.RLock()
+	watcher := s.watcher
+	s.lock.RUnlock()
 	for {
 		select {
-		case ev := <-s.watcher.Events:
+		case ev, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
 			if ev.Op&fsnotify.Create == fsnotify.Create {
-				time.Sleep(500 * time.Millisecond) // ensure file completed
-				err := s.UpdateMeta(ev.Name)
-				if err != nil {
-					log.Printf("Updated Failed: %s\n", err)
+				if info, err := os.Stat(ev.Name); err == nil && info.IsDir() {
+					continue // e.g. assets.extra
 				}
+				time.Sleep(500 * time.Millisecond) // ensure file completed
+				// UpdateMeta logs its failures
+				_ = s.UpdateMeta(ev.Name)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
 			}
-		case err := <-s.watcher.Errors:
-			log.Println("Watch error:", err)
+			s.logger.Error("watch error", "err", err)
 		}
 	}
 }
 
 func (s *Serving) UpdateMeta(file string) error {
-	meta := params.New(file)
-	if err := meta.Load(); err != nil {
+	dir := path.Dir(file)
+	models := s.models.GetModelsByPath(dir)
+	if len(models) == 0 {
+		err := UnregisteredError{name: dir, field: "paths"}
+		s.logger.Error("model load failed", "path", file, "err", err)
 		return err
 	}
-	dir := path.Dir(file)
-	m, ok := s.models.GetModelByPath(dir)
-	if !ok {
-		return UnregisteredError{name: dir, field: "paths"}
+	if path.Ext(file) == params.DeltaExt {
+		return s.updateDelta(models, file)
+	}
+	var errs []error
+	for _, m := range models {
+		if v := m.config.Version; v != 0 && fileVersion(file) != strconv.FormatUint(v, 10) {
+			continue // not the specific version
+		}
+		if err := s.load(m, file); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// updateDelta applies the delta file to the models of its base version.
+func (s *Serving) updateDelta(models []*servingModel, file string) error {
+	delta := params.New(file)
+	if err := delta.Load(); err != nil {
+		s.logger.Error("delta load failed", "path", file, "err", err)
+		return err
+	}
+	var errs []error
+	for _, m := range models {
+		if v := m.config.Version; v != 0 && v != delta.BaseVersion() {
+			continue // delta of another version
+		}
+		if err := s.applyDelta(m, file, delta); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// applyDelta swaps in the params of m updated by delta, copy-on-write.
+func (s *Serving) applyDelta(m *servingModel, file string, delta *params.Params) error {
+	return s.loadWith(m, file, func(meta *params.Params) error {
+		return meta.ApplyDelta(m.GetMeta(), delta)
+	})
+}
+
+// replayDeltas applies the delta files in the dir of m newer than its version,
+// e.g. after restarts.
+func (s *Serving) replayDeltas(m *servingModel) {
+	entries, err := os.ReadDir(m.config.Path)
+	if err != nil {
+		return
 	}
+	for _, e := range entries {
+		if e.IsDir() || path.Ext(e.Name()) != params.DeltaExt {
+			continue
+		}
+		v, err := strconv.ParseUint(fileVersion(e.Name()), 10, 64)
+		if err != nil || v <= m.GetMeta().Version() {
+			continue
+		}
+		file := path.Join(m.config.Path, e.Name())
+		delta := params.New(file)
+		if err := delta.Load(); err != nil {
+			s.logger.Error("delta load failed", "model", m.config.Name, "path", file, "err", err)
+			continue
+		}
+		if delta.BaseVersion() == m.GetMeta().BaseVersion() {
+			_ = s.applyDelta(m, file, delta)
+		}
+	}
+}
+
+// load loads file and the delta files of it.
+func (s *Serving) load(m *servingModel, file string) error {
+	if err := s.loadWith(m, file, (*params.Params).Load); err != nil {
+		return err
+	}
+	s.replayDeltas(m)
+	return nil
+}
+
+// loadWith loads file, warms it up and swaps it in as the version of m.
+func (s *Serving) loadWith(m *servingModel, file string, load func(*params.Params) error) (err error) {
 	conf := m.config
-	if conf.Name != meta.ModelName() {
-		return NotMatchError{expected: conf.Name, provided: meta.ModelName()}
+	start := time.Now()
+	meta := params.New(file)
+	defer func() {
+		s.metrics.ObserveLoad(conf.Name, meta.Version(), time.Since(start), meta.MemorySize(), err)
+		if err != nil {
+			m.loadFailed(err)
+			s.logger.Error("model load failed", "model", conf.Name, "path", file, "err", err)
+		}
+	}()
+	if err := load(meta); err != nil {
+		return err
+	}
+	if meta.IsDelta() {
+		return NotMatchError{expected: "model file", provided: "delta file"}
+	}
+	if conf.modelName() != meta.ModelName() {
+		return NotMatchError{expected: conf.modelName(), provided: meta.ModelName()}
+	}
+	if m.guard != nil && m.guard.isBad(meta.Version()) {
+		return BadVersionError{version: meta.Version()}
+	}
+	if conf.Version != 0 && conf.Version != meta.BaseVersion() {
+		return NotMatchError{expected: strconv.FormatUint(conf.Version, 10), provided: strconv.FormatUint(meta.BaseVersion(), 10)}
+	}
+	s.logger.Info("model loaded", "model", conf.Name, "version", meta.Version(), "path", file,
+		"format_version", meta.FormatVersion(), "duration", time.Since(start), "bytes", meta.MemorySize())
+
+	// reject the version before it serves any traffic
+	warmStart := time.Now()
+	n, err := warmup(context.Background(), conf, m.params(meta))
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		s.logger.Info("model warmed up", "model", conf.Name, "version", meta.Version(),
+			"requests", n, "duration", time.Since(warmStart))
 	}
 
+	if s.lifecycle.isClosed() {
+		return ShutdownError
+	}
 	s.lock.Lock()
-	atomic.StorePointer(&(m.meta), unsafe.Pointer(meta))
+	old := (*params.Params)(atomic.SwapPointer(&(m.meta), unsafe.Pointer(meta)))
 	m.startTime = time.Now()
 	s.lock.Unlock()
+	if m.guard != nil {
+		m.guard.swapped(old, meta)
+	}
+	if m.cache != nil {
+		m.cache.purge()
+	}
+	m.setState(StateAvailable, nil)
+	s.logger.Info("model version swapped", "model", conf.Name, "from", old.Version(), "to", meta.Version())
 	return nil
 }
 
 func (s *Serving) Request(name string, feats model.Features) (tensor.Tensor, error) {
+	return s.RequestContext(context.Background(), name, feats)
+}
+
+// RequestContext predicts feats by the model of name, it returns ctx.Err()
+// once ctx is done or the timeout of the request expires.
+func (s *Serving) RequestContext(ctx context.Context, name string, feats model.Features, opts ...RequestOption) (out tensor.Tensor, err error) {
+	if !s.lifecycle.enter() {
+		return nil, ShutdownError
+	}
+	defer s.lifecycle.leave()
 	m, err := s.GetModel(name)
 	if err != nil {
 		return nil, err
 	}
-	out, err := m.config.Model.Predict(m.GetMeta(), feats) // todo: check meta
+	start := time.Now()
+	ctx, span := tracer.Start(ctx, "Serving.Request", trace.WithAttributes(
+		attribute.String("model.name", name),
+		attribute.Int64("model.version", int64(m.GetMeta().Version())),
+		attribute.Int("batch.size", feats.BatchSize()),
+	))
+	defer func() {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		s.metrics.ObserveRequest(name, time.Since(start), err)
+		if err != nil {
+			s.logger.Warn("request failed", "model", name, "version", m.GetMeta().Version(),
+				"error_type", ErrorType(err), "err", err)
+		}
+	}()
+	o := requestOptions{timeout: m.config.Timeout}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, o.timeout)
+		defer cancel()
+	}
+	version := m.GetMeta().Version()
+	out, err = m.Predict(ctx, feats)
+	for errors.Is(err, replacedError) {
+		// replaced between GetModel and Predict, which never reached the old model
+		next, gerr := s.GetModel(name)
+		if gerr != nil {
+			err = gerr
+			break
+		}
+		m = next
+		version = m.GetMeta().Version()
+		out, err = m.Predict(ctx, feats)
+	}
+	s.observe(m, version, out, err)
 	if err != nil {
 		return nil, err
 	}
@@ -145,6 +572,44 @@ func (s *Serving) GetModel(name string) (*servingModel, error) {
 	return m, nil
 }
 
+// sourceVersion returns the served version in the model source.
+func (c *ModelConfig) sourceVersion() (uint64, error) {
+	if c.Version != 0 {
+		return c.Version, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), sourceTimeout)
+	defer cancel()
+	return source.Latest(ctx, c.Source)
+}
+
+// modelFile returns the file of the served version.
+func (c *ModelConfig) modelFile() (string, error) {
+	if c.Version == 0 {
+		return LatestModel(c.Path)
+	}
+	return VersionModel(c.Path, c.Version)
+}
+
+// fileVersion returns the file name without extension, e.g. 1649232000 of 1649232000.pb.
+func fileVersion(file string) string {
+	base := path.Base(file)
+	return strings.TrimSuffix(base, path.Ext(base))
+}
+
+// VersionModel returns the file of the version in dir, which is named by the version.
+func VersionModel(dir string, version uint64) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", err
+	}
+	for _, e := range entries {
+		if !e.IsDir() && path.Ext(e.Name()) != params.DeltaExt && fileVersion(e.Name()) == strconv.FormatUint(version, 10) {
+			return path.Join(dir, e.Name()), nil
+		}
+	}
+	return "", NotFoundError{name: strconv.FormatUint(version, 10), field: dir}
+}
+
 func LatestModel(dir string) (string, error) {
 	entries, err := os.ReadDir(dir)
 	var file string
@@ -152,7 +617,7 @@ func LatestModel(dir string) (string, error) {
 		return file, err
 	}
 	for _, e := range entries {
-		if e.IsDir() {
+		if e.IsDir() || path.Ext(e.Name()) == params.DeltaExt {
 			continue
 		}
 		if file == "" {
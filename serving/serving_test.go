@@ -0,0 +1,65 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/26 10:10
+ */
+
+package serving
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/yinyajun/go-serving/model"
+	"github.com/yinyajun/go-serving/params"
+	"github.com/yinyajun/go-serving/proto"
+	"gorgonia.org/tensor"
+)
+
+// sumModel outputs the sum of the embedding rows of feature x for each row.
+type sumModel struct{}
+
+func (sumModel) Predict(ctx context.Context, m params.Meta, feats model.Features) (tensor.Tensor, error) {
+	x := feats["x"].Data().([]string)
+	index := m.IndexLookup("x", "0", x...)
+	rows, err := m.EmbeddingLookup("x", index)
+	if err != nil {
+		return nil, err
+	}
+	data := rows.Data().([]float32)
+	dim := rows.Shape()[1]
+	out := make([]float32, len(x))
+	for i := range out {
+		for _, v := range data[i*dim : (i+1)*dim] {
+			out[i] += v
+		}
+	}
+	return tensor.New(tensor.WithBacking(out), tensor.WithShape(len(x), 1)), nil
+}
+
+// writeModel writes the model file of version into dir, the rows of x are filled by version.
+func writeModel(t testing.TB, dir string, version uint64) string {
+	t.Helper()
+	w := params.NewWriter("test", version)
+	w.AddDense("x", tensor.New(tensor.WithBacking([]float32{float32(version), 0, 1, float32(version)}), tensor.WithShape(2, 2)))
+	w.AddField(&proto.Field{Name: "x", Dim: 2, Records: map[string]int64{"0": 0, "1": 1}})
+	file := filepath.Join(dir, strconv.FormatUint(version, 10)+".pb")
+	if err := w.WriteFile(file); err != nil {
+		t.Fatal(err)
+	}
+	return file
+}
+
+func newTestServing(t testing.TB) *Serving {
+	t.Helper()
+	s := New(WithLogger(slog.New(slog.NewTextHandler(io.Discard, nil))))
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func features(x ...string) model.Features {
+	return model.Features{"x": tensor.New(tensor.WithBacking(x), tensor.WithShape(len(x)))}
+}
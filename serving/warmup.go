@@ -0,0 +1,89 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/15 14:02
+ */
+
+package serving
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"path"
+
+	"github.com/yinyajun/go-serving/model"
+	"github.com/yinyajun/go-serving/params"
+	"gorgonia.org/tensor"
+)
+
+// DefaultWarmupFile is relative to the model path, like tensorflow serving's
+// assets.extra/tf_serving_warmup_requests.
+const DefaultWarmupFile = "assets.extra/warmup.jsonl"
+
+func (c *ModelConfig) warmupFile() (string, bool) {
+	if c.Warmup != "" {
+		return c.Warmup, true
+	}
+	return path.Join(c.Path, DefaultWarmupFile), false
+}
+
+// warmup replays the recorded requests against meta, one feature map per line.
+// It returns the number of replayed requests.
+func warmup(ctx context.Context, c *ModelConfig, meta params.Meta) (int, error) {
+	file, required := c.warmupFile()
+	f, err := os.Open(file)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, WarmupError{file: file, err: err}
+	}
+	defer f.Close()
+
+	n := 0
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 64<<20)
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		var feats model.Features
+		if err := feats.UnmarshalJSON(scanner.Bytes()); err != nil {
+			return n, WarmupError{file: file, line: line, err: err}
+		}
+		out, err := c.Model.Predict(ctx, meta, feats)
+		if err != nil {
+			return n, WarmupError{file: file, line: line, err: err}
+		}
+		if err := checkFinite(out); err != nil {
+			return n, WarmupError{file: file, line: line, err: err}
+		}
+		n++
+	}
+	if err := scanner.Err(); err != nil {
+		return n, WarmupError{file: file, err: err}
+	}
+	return n, nil
+}
+
+func checkFinite(out tensor.Tensor) error {
+	switch data := out.Data().(type) {
+	case []float32:
+		for _, v := range data {
+			if math.IsNaN(float64(v)) || math.IsInf(float64(v), 0) {
+				return fmt.Errorf("output %v is not finite", v)
+			}
+		}
+	case []float64:
+		for _, v := range data {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("output %v is not finite", v)
+			}
+		}
+	}
+	return nil
+}
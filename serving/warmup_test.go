@@ -0,0 +1,160 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/27 21:40
+ */
+
+package serving
+
+import (
+	"context"
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/yinyajun/go-serving/model"
+	"github.com/yinyajun/go-serving/params"
+	"gorgonia.org/tensor"
+)
+
+// nanModel is sumModel whose outputs of the rows summed to 2 are NaN, e.g.
+// feature "0" of version 2.
+type nanModel struct {
+	sumModel
+}
+
+func (n nanModel) Predict(ctx context.Context, m params.Meta, feats model.Features) (tensor.Tensor, error) {
+	out, err := n.sumModel.Predict(ctx, m, feats)
+	if err != nil {
+		return nil, err
+	}
+	data := out.Data().([]float32)
+	for i, v := range data {
+		if v == 2 {
+			data[i] = float32(math.NaN())
+		}
+	}
+	return out, nil
+}
+
+func writeWarmup(t *testing.T, file string, lines ...string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		t.Fatal(err)
+	}
+	var data []byte
+	for _, line := range lines {
+		data = append(data, line+"\n"...)
+	}
+	if err := os.WriteFile(file, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWarmup(t *testing.T) {
+	dir := t.TempDir()
+	file := writeModel(t, dir, 1)
+	meta := params.New(file)
+	if err := meta.Load(); err != nil {
+		t.Fatal(err)
+	}
+	warmupFile := filepath.Join(t.TempDir(), "warmup.jsonl")
+	tests := []struct {
+		name  string
+		file  string // Warmup of the config, the default file in dir if empty
+		lines []string
+		n     int
+		line  int // of the WarmupError, -1 if no error
+	}{
+		{"missing default file", "", nil, 0, -1},
+		{"missing file", filepath.Join(dir, "missing.jsonl"), nil, 0, 0},
+		{"replayed", warmupFile, []string{
+			`{"x": {"shape": [2], "string_val": ["0", "0"]}}`,
+			``,
+			`{"x": {"shape": [1], "string_val": ["0"]}}`,
+		}, 2, -1},
+		// "1" of version 1 is summed to 2
+		{"nan", warmupFile, []string{
+			`{"x": {"shape": [1], "string_val": ["0"]}}`,
+			`{"x": {"shape": [2], "string_val": ["0", "1"]}}`,
+		}, 1, 2},
+		{"invalid json", warmupFile, []string{`{"x": `}, 0, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.lines != nil {
+				writeWarmup(t, tt.file, tt.lines...)
+			}
+			c := &ModelConfig{Name: "test", Path: dir, Warmup: tt.file, Model: nanModel{}}
+			n, err := warmup(context.Background(), c, meta)
+			if n != tt.n {
+				t.Errorf("got %d requests, want %d", n, tt.n)
+			}
+			var werr WarmupError
+			switch {
+			case tt.line < 0 && err != nil:
+				t.Fatalf("got %v", err)
+			case tt.line >= 0 && !errors.As(err, &werr):
+				t.Fatalf("got %v, want WarmupError", err)
+			case tt.line >= 0 && werr.line != tt.line:
+				t.Fatalf("got %v, want at line %d", err, tt.line)
+			}
+		})
+	}
+}
+
+func TestWarmupFailureNeverSwapsIn(t *testing.T) {
+	dir := t.TempDir()
+	writeModel(t, dir, 1)
+	writeWarmup(t, filepath.Join(dir, DefaultWarmupFile), `{"x": {"shape": [1], "string_val": ["0"]}}`)
+	s := newTestServing(t)
+	s.Launch()
+	if err := s.AddModel(&ModelConfig{Name: "test", Path: dir, Model: nanModel{}}); err != nil {
+		t.Fatal(err)
+	}
+	m, err := s.GetModel("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	errs := make(chan error, 1)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+			}
+			out, err := s.Request("test", features("0"))
+			if err == nil && out.Data().([]float32)[0] != 1 {
+				err = errors.New("served an output of version 2")
+			}
+			if err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	// version 2 outputs NaN for the warmup request
+	for i := 0; i < 10; i++ {
+		if err := s.load(m, writeModel(t, t.TempDir(), 2)); !errors.As(err, &WarmupError{}) {
+			t.Fatalf("got %v, want WarmupError", err)
+		}
+	}
+	close(stop)
+	wg.Wait()
+	select {
+	case err := <-errs:
+		t.Fatal(err)
+	default:
+	}
+	if st := m.Status(); st.Version != 1 || st.State != StateAvailable || st.Error == "" {
+		t.Fatalf("got %+v, want version 1 available with the error", st)
+	}
+}
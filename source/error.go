@@ -0,0 +1,33 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/19 10:24
+ */
+
+package source
+
+import "fmt"
+
+type EmptyError struct {
+	source string
+}
+
+func (e EmptyError) Error() string {
+	return fmt.Sprintf("No versions in %s", e.source)
+}
+
+type VersionNotFoundError struct {
+	source  string
+	version uint64
+}
+
+func (e VersionNotFoundError) Error() string {
+	return fmt.Sprintf("Version %d not found in %s", e.version, e.source)
+}
+
+type UnsupportedSchemeError struct {
+	scheme string
+}
+
+func (e UnsupportedSchemeError) Error() string {
+	return fmt.Sprintf("Unsupported source scheme: %s", e.scheme)
+}
@@ -0,0 +1,111 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/19 15:40
+ */
+
+package source
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultHTTPTimeout bounds each request of NewHTTP without a client,
+// including downloading a model file.
+var DefaultHTTPTimeout = 10 * time.Minute
+
+type httpStorage struct {
+	client *http.Client
+	base   string
+}
+
+// NewHTTP stores the model files under base url, whose versions are listed by the
+// links of its directory index (e.g. nginx autoindex), or by one file name per line.
+// A nil client times out after DefaultHTTPTimeout.
+func NewHTTP(base string, client *http.Client) Storage {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultHTTPTimeout}
+	}
+	return &httpStorage{client: client, base: strings.TrimSuffix(base, "/")}
+}
+
+var hrefPattern = regexp.MustCompile(`href="([^"?#]+)"`)
+
+func (h *httpStorage) Versions(ctx context.Context) ([]uint64, error) {
+	versions, _, err := h.list(ctx)
+	return versions, err
+}
+
+func (h *httpStorage) list(ctx context.Context) ([]uint64, map[uint64]string, error) {
+	body, err := h.get(ctx, h.base+"/")
+	if err != nil {
+		return nil, nil, err
+	}
+	var names []string
+	for _, m := range hrefPattern.FindAllSubmatch(body, -1) {
+		names = append(names, path.Base(string(m[1])))
+	}
+	if len(names) == 0 {
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		for scanner.Scan() {
+			if name := strings.TrimSpace(scanner.Text()); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	versions, files := versionsOf(names)
+	return versions, files, nil
+}
+
+// Open downloads the model file, it's loaded into memory as a whole anyway.
+func (h *httpStorage) Open(ctx context.Context, version uint64) (File, error) {
+	_, files, err := h.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	name, ok := files[version]
+	if !ok {
+		return nil, VersionNotFoundError{source: h.base, version: version}
+	}
+	body, err := h.get(ctx, h.base+"/"+name)
+	if err != nil {
+		return nil, err
+	}
+	return memFile{bytes.NewReader(body)}, nil
+}
+
+func (h *httpStorage) get(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+func (h *httpStorage) String() string {
+	return h.base
+}
+
+type memFile struct {
+	*bytes.Reader
+}
+
+func (memFile) Close() error {
+	return nil
+}
@@ -0,0 +1,141 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/19 11:02
+ */
+
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+type dir string
+
+// NewDir stores the model files in a local directory.
+func NewDir(path string) Storage {
+	return dir(path)
+}
+
+func (d dir) Versions(ctx context.Context) ([]uint64, error) {
+	versions, _, err := d.list()
+	return versions, err
+}
+
+func (d dir) list() ([]uint64, map[uint64]string, error) {
+	entries, err := os.ReadDir(string(d))
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, e := range entries {
+		if e.Type()&os.ModeSymlink != 0 {
+			// e.g. 100.pb -> ..data/100.pb of kubernetes configmaps, skips the broken
+			// ones and the directories like ..data
+			info, err := os.Stat(filepath.Join(string(d), e.Name()))
+			if err != nil || info.IsDir() {
+				continue
+			}
+		} else if e.IsDir() {
+			continue
+		}
+		names = append(names, e.Name())
+	}
+	versions, files := versionsOf(names)
+	return versions, files, nil
+}
+
+// Fingerprints returns the size and modification time of each version, following
+// symlinks, so that a version swapped by symlinks is found changed.
+func (d dir) Fingerprints(ctx context.Context) (map[uint64]string, error) {
+	_, files, err := d.list()
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[uint64]string, len(files))
+	for v, name := range files {
+		info, err := os.Stat(filepath.Join(string(d), name))
+		if err != nil {
+			continue // removed since listed
+		}
+		res[v] = fmt.Sprintf("%d-%d", info.Size(), info.ModTime().UnixNano())
+	}
+	return res, nil
+}
+
+func (d dir) Open(ctx context.Context, version uint64) (File, error) {
+	_, files, err := d.list()
+	if err != nil {
+		return nil, err
+	}
+	name, ok := files[version]
+	if !ok {
+		return nil, VersionNotFoundError{source: string(d), version: version}
+	}
+	f, err := os.Open(filepath.Join(string(d), name))
+	if err != nil {
+		return nil, err
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &localFile{File: f, size: stat.Size()}, nil
+}
+
+func (d dir) String() string {
+	return string(d)
+}
+
+type localFile struct {
+	*os.File
+	size int64
+}
+
+func (f *localFile) Size() int64 {
+	return f.size
+}
+
+type local struct {
+	dir
+}
+
+// NewLocal watches a local directory by fsnotify.
+func NewLocal(path string) Source {
+	return local{dir(path)}
+}
+
+func (l local) Subscribe(ctx context.Context, f func(Event)) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+	if err := watcher.Add(string(l.dir)); err != nil {
+		return err
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case ev := <-watcher.Events:
+			if ev.Op&fsnotify.Create != fsnotify.Create {
+				continue
+			}
+			v, ok := ParseVersion(ev.Name)
+			if !ok {
+				continue
+			}
+			time.Sleep(500 * time.Millisecond) // ensure file completed
+			f(Event{Version: v})
+		case err := <-watcher.Errors:
+			f(Event{Err: err})
+		}
+	}
+}
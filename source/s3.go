@@ -0,0 +1,103 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/19 14:15
+ */
+
+package source
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+type S3Config struct {
+	Endpoint        string // e.g. s3.amazonaws.com or localhost:9000 of minio
+	AccessKeyID     string
+	SecretAccessKey string
+	Secure          bool
+	Region          string
+	Bucket          string
+	Prefix          string // directory of the model files
+}
+
+type s3Storage struct {
+	client *minio.Client
+	conf   S3Config
+}
+
+// NewS3 stores the model files in an s3-compatible object store.
+func NewS3(conf S3Config) (Storage, error) {
+	client, err := minio.New(conf.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(conf.AccessKeyID, conf.SecretAccessKey, ""),
+		Secure: conf.Secure,
+		Region: conf.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	conf.Prefix = strings.Trim(conf.Prefix, "/")
+	return &s3Storage{client: client, conf: conf}, nil
+}
+
+func (s *s3Storage) Versions(ctx context.Context) ([]uint64, error) {
+	versions, _, err := s.list(ctx)
+	return versions, err
+}
+
+func (s *s3Storage) list(ctx context.Context) ([]uint64, map[uint64]string, error) {
+	prefix := s.conf.Prefix
+	if prefix != "" {
+		prefix += "/"
+	}
+	var keys []string
+	for obj := range s.client.ListObjects(ctx, s.conf.Bucket, minio.ListObjectsOptions{Prefix: prefix}) {
+		if obj.Err != nil {
+			return nil, nil, obj.Err
+		}
+		if strings.HasSuffix(obj.Key, "/") {
+			continue
+		}
+		keys = append(keys, obj.Key)
+	}
+	versions, files := versionsOf(keys)
+	return versions, files, nil
+}
+
+func (s *s3Storage) Open(ctx context.Context, version uint64) (File, error) {
+	_, files, err := s.list(ctx)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := files[version]
+	if !ok {
+		return nil, VersionNotFoundError{source: s.String(), version: version}
+	}
+	obj, err := s.client.GetObject(ctx, s.conf.Bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+	stat, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, err
+	}
+	return &s3File{Object: obj, size: stat.Size}, nil
+}
+
+func (s *s3Storage) String() string {
+	return "s3://" + path.Join(s.conf.Bucket, s.conf.Prefix)
+}
+
+// s3File reads the object by ranges.
+type s3File struct {
+	*minio.Object
+	size int64
+}
+
+func (f *s3File) Size() int64 {
+	return f.size
+}
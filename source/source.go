@@ -0,0 +1,185 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/19 10:20
+ */
+
+// Package source provides the versions of a model from local directories,
+// s3-compatible object stores or http servers.
+package source
+
+import (
+	"context"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yinyajun/go-serving/params"
+)
+
+// File is an opened model file of a version.
+type File interface {
+	io.ReaderAt
+	io.Closer
+	Size() int64
+}
+
+// Storage stores the model files, which are named by their versions, e.g. 1649232000.pb.
+type Storage interface {
+	// Versions lists the versions in ascending order.
+	Versions(ctx context.Context) ([]uint64, error)
+	Open(ctx context.Context, version uint64) (File, error)
+	String() string
+}
+
+// Event is a new version, or an error while watching.
+type Event struct {
+	Version uint64
+	Err     error
+}
+
+// Fingerprinter is an optional Storage which fingerprints the content of each version,
+// a polling Source reloads the version whose fingerprint changes.
+type Fingerprinter interface {
+	Fingerprints(ctx context.Context) (map[uint64]string, error)
+}
+
+type Source interface {
+	Storage
+	// Subscribe calls f on the new versions until ctx is done.
+	Subscribe(ctx context.Context, f func(Event)) error
+}
+
+// ParseVersion parses the version of the file name, e.g. 1649232000.pb.
+func ParseVersion(name string) (uint64, bool) {
+	base := path.Base(name)
+	v, err := strconv.ParseUint(strings.TrimSuffix(base, path.Ext(base)), 10, 64)
+	return v, err == nil
+}
+
+// versionsOf returns the versions of the names in ascending order, and the name of each version.
+func versionsOf(names []string) ([]uint64, map[uint64]string) {
+	files := make(map[uint64]string)
+	versions := make([]uint64, 0, len(names))
+	for _, name := range names {
+		if path.Ext(name) == params.DeltaExt {
+			continue // applied to the loaded version by serving
+		}
+		v, ok := ParseVersion(name)
+		if !ok {
+			continue
+		}
+		if _, ok := files[v]; !ok {
+			versions = append(versions, v)
+		}
+		files[v] = name
+	}
+	sort.Slice(versions, func(i, j int) bool { return versions[i] < versions[j] })
+	return versions, files
+}
+
+// Latest returns the latest version of st.
+func Latest(ctx context.Context, st Storage) (uint64, error) {
+	versions, err := st.Versions(ctx)
+	if err != nil {
+		return 0, err
+	}
+	if len(versions) == 0 {
+		return 0, EmptyError{source: st.String()}
+	}
+	return versions[len(versions)-1], nil
+}
+
+const (
+	// minRetryWait is the first wait to retry a failed poll, doubled on each
+	// failure up to the interval.
+	minRetryWait = time.Second
+	// pollTimeout bounds a poll of the storage.
+	pollTimeout = time.Minute
+)
+
+type polling struct {
+	Storage
+	interval time.Duration
+}
+
+// NewPolling watches st by diffing its versions every interval, and their
+// fingerprints if st is a Fingerprinter.
+func NewPolling(st Storage, interval time.Duration) Source {
+	return &polling{Storage: st, interval: interval}
+}
+
+// NewPollingDir watches a local directory by polling, for the network filesystems
+// and symlink swaps which fsnotify misses.
+func NewPollingDir(path string, interval time.Duration) Source {
+	return NewPolling(dir(path), interval)
+}
+
+func (p *polling) snapshot(ctx context.Context) (map[uint64]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, pollTimeout)
+	defer cancel()
+	if fp, ok := p.Storage.(Fingerprinter); ok {
+		return fp.Fingerprints(ctx)
+	}
+	versions, err := p.Versions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[uint64]string, len(versions))
+	for _, v := range versions {
+		res[v] = ""
+	}
+	return res, nil
+}
+
+// Subscribe diffs the snapshots of the storage. Failed polls are reported and
+// retried with backoff; if the first one fails, the latest version is reported
+// once the storage is available, since it may not be loaded.
+func (p *polling) Subscribe(ctx context.Context, f func(Event)) error {
+	var (
+		seen     map[uint64]string // nil until the first snapshot
+		failures int
+		wait     time.Duration
+	)
+	for {
+		if wait > 0 {
+			timer := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return ctx.Err()
+			case <-timer.C:
+			}
+		}
+		current, err := p.snapshot(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			f(Event{Err: err})
+			wait = min(minRetryWait<<min(failures, 16), p.interval)
+			failures++
+			continue
+		}
+		wait = p.interval
+
+		changed := make([]uint64, 0)
+		for v, fp := range current {
+			if old, ok := seen[v]; !ok || old != fp {
+				changed = append(changed, v)
+			}
+		}
+		sort.Slice(changed, func(i, j int) bool { return changed[i] < changed[j] })
+		switch {
+		case seen == nil && failures > 0 && len(changed) > 0:
+			f(Event{Version: changed[len(changed)-1]})
+		case seen != nil:
+			for _, v := range changed {
+				f(Event{Version: v})
+			}
+		}
+		seen, failures = current, 0
+	}
+}
@@ -0,0 +1,252 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/26 17:40
+ */
+
+package source
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeStorage fails the first failures listings.
+type fakeStorage struct {
+	mu       sync.Mutex
+	versions []uint64
+	failures int
+}
+
+func (f *fakeStorage) Versions(context.Context) ([]uint64, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.failures > 0 {
+		f.failures--
+		return nil, errors.New("unavailable")
+	}
+	return append([]uint64(nil), f.versions...), nil
+}
+
+func (f *fakeStorage) Open(context.Context, uint64) (File, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (f *fakeStorage) String() string { return "fake" }
+
+func (f *fakeStorage) add(v uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.versions = append(f.versions, v)
+}
+
+// subscribe collects the events of src until n versions are reported.
+func subscribe(t *testing.T, src Source, n int, during func()) (versions []uint64, errs int) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = src.Subscribe(ctx, func(ev Event) {
+			if ev.Err != nil {
+				errs++
+				return
+			}
+			versions = append(versions, ev.Version)
+			if len(versions) == n {
+				cancel()
+			}
+		})
+	}()
+	if during != nil {
+		during()
+	}
+	<-done
+	if len(versions) < n {
+		t.Fatalf("got versions %v, want %d", versions, n)
+	}
+	return versions, errs
+}
+
+func TestPollingRetriesFirstSnapshot(t *testing.T) {
+	st := &fakeStorage{versions: []uint64{1, 2}, failures: 3}
+	versions, errs := subscribe(t, NewPolling(st, 10*time.Millisecond), 1, nil)
+	if !reflect.DeepEqual(versions, []uint64{2}) || errs != 3 {
+		t.Fatalf("got versions %v, %d errors, want [2], 3 errors", versions, errs)
+	}
+}
+
+func TestPollingReportsNewVersions(t *testing.T) {
+	st := &fakeStorage{versions: []uint64{1}}
+	versions, errs := subscribe(t, NewPolling(st, 10*time.Millisecond), 2, func() {
+		time.Sleep(30 * time.Millisecond)
+		st.add(3)
+		st.add(2)
+	})
+	if !reflect.DeepEqual(versions, []uint64{2, 3}) || errs != 0 {
+		t.Fatalf("got versions %v, %d errors, want [2 3]", versions, errs)
+	}
+}
+
+func readAll(t *testing.T, st Storage, version uint64) string {
+	t.Helper()
+	f, err := st.Open(context.Background(), version)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	buf := make([]byte, f.Size())
+	if _, err := f.ReadAt(buf, 0); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	return string(buf)
+}
+
+var files = map[string]string{"1649232000.pb": "v1", "1649235600.pb": "v2", "1649235700.delta": "d"}
+
+func TestHTTPStorage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		name := strings.TrimPrefix(r.URL.Path, "/models/")
+		if name == "" {
+			for name := range files {
+				fmt.Fprintf(w, "<a href=\"%s\">%s</a>\n", name, name)
+			}
+			return
+		}
+		content, ok := files[name]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, content)
+	}))
+	defer srv.Close()
+
+	st := NewHTTP(srv.URL+"/models/", nil)
+	versions, err := st.Versions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []uint64{1649232000, 1649235600}) {
+		t.Fatalf("got %v", versions)
+	}
+	if got := readAll(t, st, 1649235600); got != "v2" {
+		t.Fatalf("got %q", got)
+	}
+	if _, err := st.Open(context.Background(), 1); !errors.As(err, &VersionNotFoundError{}) {
+		t.Fatalf("got %v, want VersionNotFoundError", err)
+	}
+}
+
+func TestHTTPStorageTimeout(t *testing.T) {
+	blocked := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-blocked
+	}))
+	defer srv.Close()
+	defer close(blocked)
+
+	timeout := DefaultHTTPTimeout
+	DefaultHTTPTimeout = 50 * time.Millisecond
+	defer func() { DefaultHTTPTimeout = timeout }()
+
+	st := NewHTTP(srv.URL, nil)
+	start := time.Now()
+	if _, err := st.Versions(context.Background()); err == nil {
+		t.Fatal("listing a hung server succeeds")
+	}
+	if d := time.Since(start); d > time.Second {
+		t.Fatalf("listing took %v", d)
+	}
+}
+
+// minioStandIn serves ListObjectsV2 and GetObject of a bucket like minio.
+func minioStandIn(bucket string, objects map[string]string) *httptest.Server {
+	modTime := time.Date(2022, 4, 19, 10, 0, 0, 0, time.UTC)
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := strings.CutPrefix(r.URL.Path, "/"+bucket+"/")
+		if !ok && r.URL.Path != "/"+bucket {
+			http.NotFound(w, r)
+			return
+		}
+		if key == "" {
+			prefix := r.URL.Query().Get("prefix")
+			var buf bytes.Buffer
+			fmt.Fprintf(&buf, `<ListBucketResult xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><Name>%s</Name><Prefix>%s</Prefix><MaxKeys>1000</MaxKeys><IsTruncated>false</IsTruncated>`, bucket, prefix)
+			for k, v := range objects {
+				if strings.HasPrefix(k, prefix) {
+					fmt.Fprintf(&buf, `<Contents><Key>%s</Key><Size>%d</Size><ETag>"%x"</ETag><LastModified>%s</LastModified></Contents>`,
+						k, len(v), v, modTime.Format(time.RFC3339))
+				}
+			}
+			buf.WriteString(`</ListBucketResult>`)
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write(buf.Bytes())
+			return
+		}
+		content, ok := objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprintf(w, `<Error><Code>NoSuchKey</Code><Key>%s</Key></Error>`, key)
+			return
+		}
+		w.Header().Set("ETag", fmt.Sprintf(`"%x"`, content))
+		http.ServeContent(w, r, key, modTime, strings.NewReader(content))
+	}))
+}
+
+func TestS3Storage(t *testing.T) {
+	objects := map[string]string{"other/1649232000.pb": "other"}
+	for name, content := range files {
+		objects["wide_deep/"+name] = content
+	}
+	srv := minioStandIn("models", objects)
+	defer srv.Close()
+
+	st, err := NewS3(S3Config{Endpoint: strings.TrimPrefix(srv.URL, "http://"), Region: "us-east-1",
+		AccessKeyID: "minio", SecretAccessKey: "minio123", Bucket: "models", Prefix: "/wide_deep/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	versions, err := st.Versions(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(versions, []uint64{1649232000, 1649235600}) {
+		t.Fatalf("got %v", versions)
+	}
+	if got := readAll(t, st, 1649232000); got != "v1" {
+		t.Fatalf("got %q", got)
+	}
+
+	// the source recovers once the object store is up
+	down := &fakeStorage{failures: 2}
+	src := NewPolling(&recovering{Storage: st, down: down}, 10*time.Millisecond)
+	got, errs := subscribe(t, src, 1, nil)
+	if !reflect.DeepEqual(got, []uint64{1649235600}) || errs != 2 {
+		t.Fatalf("got versions %v, %d errors", got, errs)
+	}
+}
+
+// recovering fails while down fails.
+type recovering struct {
+	Storage
+	down *fakeStorage
+}
+
+func (r *recovering) Versions(ctx context.Context) ([]uint64, error) {
+	if _, err := r.down.Versions(ctx); err != nil {
+		return nil, err
+	}
+	return r.Storage.Versions(ctx)
+}
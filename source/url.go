@@ -0,0 +1,67 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/19 16:30
+ */
+
+package source
+
+import (
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// DefaultPollInterval is the interval to list remote sources.
+const DefaultPollInterval = 30 * time.Second
+
+// FromURL returns the source of a model path:
+//
+//	/tmp/data/wide_deep                               local directory watched by fsnotify
+//	s3://bucket/wide_deep?endpoint=localhost:9000     s3, AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY from env,
+//	                                                  https unless insecure=true
+//	http://models.example.com/wide_deep/              http directory index
+//
+// Remote sources are polled every interval, DefaultPollInterval if 0.
+func FromURL(rawurl string, interval time.Duration) (Source, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+	switch u.Scheme {
+	case "", "file":
+		return NewLocal(u.Path), nil
+	case "s3":
+		q := u.Query()
+		endpoint := q.Get("endpoint")
+		if endpoint == "" {
+			endpoint = "s3.amazonaws.com"
+		}
+		st, err := NewS3(S3Config{
+			Endpoint:        endpoint,
+			AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+			SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+			Secure:          q.Get("insecure") != "true",
+			Region:          q.Get("region"),
+			Bucket:          u.Host,
+			Prefix:          strings.TrimPrefix(u.Path, "/"),
+		})
+		if err != nil {
+			return nil, err
+		}
+		return NewPolling(st, interval), nil
+	case "http", "https":
+		return NewPolling(NewHTTP(rawurl, nil), interval), nil
+	default:
+		return nil, UnsupportedSchemeError{scheme: u.Scheme}
+	}
+}
+
+// IsURL reports whether the model path has a scheme of remote sources.
+func IsURL(path string) bool {
+	u, err := url.Parse(path)
+	return err == nil && u.Scheme != "" && u.Scheme != "file"
+}
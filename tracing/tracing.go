@@ -0,0 +1,80 @@
+/*
+* @Author: Yajun
+* @Date:   2022/4/12 14:10
+ */
+
+// Package tracing sets up the opentelemetry tracer provider, spans of
+// serving, model, layer and column are dropped until Setup is called.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+)
+
+const (
+	StdoutExporter = "stdout"
+	OTLPExporter   = "otlp"
+)
+
+type Config struct {
+	ServiceName string  // default go-serving
+	Exporter    string  // stdout or otlp
+	Endpoint    string  // otlp grpc endpoint, default localhost:4317
+	Insecure    bool    // otlp without tls
+	SampleRatio float64 // ratio of traced requests, default 1
+}
+
+// Setup installs a global tracer provider exporting to the configured
+// exporter, the returned shutdown flushes and stops it.
+func Setup(ctx context.Context, c Config) (shutdown func(context.Context) error, err error) {
+	if c.ServiceName == "" {
+		c.ServiceName = "go-serving"
+	}
+	if c.SampleRatio == 0 {
+		c.SampleRatio = 1
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch c.Exporter {
+	case StdoutExporter:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout), stdouttrace.WithPrettyPrint())
+	case OTLPExporter:
+		opts := []otlptracegrpc.Option{}
+		if c.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(c.Endpoint))
+		}
+		if c.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("unknown exporter %q", c.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(),
+		resource.NewWithAttributes(semconv.SchemaURL, semconv.ServiceName(c.ServiceName)))
+	if err != nil {
+		return nil, err
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(c.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider.Shutdown, nil
+}